@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"euphio/internal/app"
+)
+
+var banCmd = &cobra.Command{
+	Use:   "ban",
+	Short: "Manage banned IP addresses",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		app.Boot(cfgFile, true)
+	},
+}
+
+var banReason string
+
+func init() {
+	banCmd.AddCommand(banAddCmd)
+	banCmd.AddCommand(banRemoveCmd)
+	banCmd.AddCommand(banListCmd)
+
+	banAddCmd.Flags().StringVarP(&banReason, "reason", "r", "", "why the address is being banned")
+}
+
+var banAddCmd = &cobra.Command{
+	Use:   "add [ip_or_cidr]",
+	Short: "Ban an IP address or CIDR range",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ban, err := app.Store.AddBan(args[0], banReason)
+		if err != nil {
+			log.Fatalf("Error adding ban: %v", err)
+		}
+		fmt.Printf("Banned %s.\n", ban.CIDR)
+	},
+}
+
+var banRemoveCmd = &cobra.Command{
+	Use:   "remove [ip_or_cidr]",
+	Short: "Remove a ban",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := app.Store.RemoveBan(args[0]); err != nil {
+			log.Fatalf("Error removing ban: %v", err)
+		}
+		fmt.Printf("Ban on %s removed.\n", args[0])
+	},
+}
+
+var banListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List banned addresses",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		bans, err := app.Store.ListBans()
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		if len(bans) == 0 {
+			fmt.Println("No addresses are banned.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CIDR\tReason\tBanned At")
+		for _, ban := range bans {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", ban.CIDR, ban.Reason, ban.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		w.Flush()
+	},
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"euphio/internal/assets"
+	"euphio/internal/network"
 )
 
 var initCmd = &cobra.Command{
@@ -88,6 +89,15 @@ func runInit(cmd *cobra.Command, args []string) {
 		fmt.Printf("Created directory: %s\n", path)
 	}
 
+	// Generate a self-signed certificate for Telnet over TLS
+	certFile := safeName + "/keys/telnet.crt"
+	keyFile := safeName + "/keys/telnet.key"
+	if err := network.GenerateSelfSignedCert(certFile, keyFile, []string{data.Hostname, "localhost", "127.0.0.1"}); err != nil {
+		fmt.Printf("Error generating TLS certificate: %v\n", err)
+	} else {
+		fmt.Printf("Created TLS certificate: %s\n", certFile)
+	}
+
 	// Copy views.yml
 	menusContent, err := assets.FS.ReadFile("config/views.yml")
 	if err != nil {
@@ -7,11 +7,14 @@ import (
 	"github.com/spf13/cobra"
 
 	"euphio/internal/app"
+	"euphio/internal/views"
 )
 
 var cfgFile string
 
 func main() {
+	app.Validate = views.Validate
+
 	configPath := os.Getenv("EUPHIO_CONFIG")
 	if configPath == "" {
 		configPath = "config.yml"
@@ -31,6 +34,7 @@ func main() {
 
 	rootCmd.AddCommand(serverCmd)
 	rootCmd.AddCommand(userCmd)
+	rootCmd.AddCommand(banCmd)
 	rootCmd.AddCommand(initCmd)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -1,18 +1,18 @@
 package main
 
 import (
-	"euphio/internal/ansi"
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
-	"sync"
+	"reflect"
 	"syscall"
+	"time"
 
+	"euphio/internal/ansi"
 	"euphio/internal/app"
 	"euphio/internal/network"
-	"euphio/internal/network/ssh"
-	"euphio/internal/network/telnet"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
@@ -33,163 +33,252 @@ func bootAppForServer(cmd *cobra.Command, args []string) {
 }
 
 func startServer(cmd *cobra.Command, args []string) {
-	ansi.RenderArt(os.Stdout, "boot", true)
+	ansi.RenderArt(os.Stdout, "boot", true, nil)
 
 	restartChan := make(chan struct{}, 1)
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM)
 
-	for {
-		var watcher *fsnotify.Watcher
-		if app.Config.HotReload {
-			// Setup Watcher
-			var err error
-			watcher, err = fsnotify.NewWatcher()
-			if err != nil {
-				app.Logger.Error("Failed to create watcher", "err", err)
-			} else {
-				// Watch all loaded config files
-				for _, file := range app.Config.LoadedFiles {
-					err = watcher.Add(file)
-
-					// Try to make path relative for cleaner logging
-					relPath := file
-					if cwd, err := os.Getwd(); err == nil {
-						if rel, err := filepath.Rel(cwd, file); err == nil {
-							relPath = rel
-						}
-					}
+	watcher := watchConfig(restartChan)
+	running := map[string]*runningListener{}
+	startListeners(running, enabledListeners())
 
-					if err != nil {
-						app.Logger.Error("Failed to watch config file", "file", relPath, "err", err)
-					} else {
-						app.Logger.Debug("Watching config file", "file", relPath)
-					}
-				}
+	for {
+		select {
+		case <-stopChan:
+			if watcher != nil {
+				watcher.Close()
+			}
+			shutdown(running, stopChan)
+			return
 
-				go func(w *fsnotify.Watcher) {
-					for {
-						select {
-						case event, ok := <-w.Events:
-							if !ok {
-								return
-							}
-							if event.Op&fsnotify.Write == fsnotify.Write {
-								// Check if hot reload is still enabled (in case it was disabled in the new config)
-								if !app.Config.HotReload {
-									continue
-								}
-
-								// Try to make path relative for cleaner logging
-								relPath := event.Name
-								if cwd, err := os.Getwd(); err == nil {
-									if rel, err := filepath.Rel(cwd, event.Name); err == nil {
-										relPath = rel
-									}
-								}
-
-								app.Logger.Info("Config file modified, rebooting app...", "file", relPath)
-								select {
-								case restartChan <- struct{}{}:
-								default:
-									// restart pending
-								}
-							}
-						case err, ok := <-w.Errors:
-							if !ok {
-								return
-							}
-							app.Logger.Error("Watcher error", "err", err)
-						}
-					}
-				}(watcher)
+		case <-restartChan:
+			// Callers stay online through a reload, and only the listeners whose config changed are restarted
+			if err := app.Reload(cfgFile); err != nil {
+				app.Logger.Error("Failed to reload config", "err", err)
+				// Carry on with the existing config, Reload didn't swap anything on failure
+				continue
+			}
+			if watcher != nil {
+				watcher.Close()
 			}
+			watcher = watchConfig(restartChan)
+			startListeners(running, enabledListeners())
 		}
+	}
+}
 
-		var wg sync.WaitGroup
-		var sshServer *ssh.Server
-		var telnetServer *telnet.Server
+// watchConfig watches the config files loaded, if hot reload is enabled, signalling restartChan when one is written.
+func watchConfig(restartChan chan<- struct{}) *fsnotify.Watcher {
+	if !app.Config().HotReload {
+		return nil
+	}
 
-		sshEnabled := app.Config.Listeners.SSH.Enabled
-		telnetEnabled := app.Config.Listeners.Telnet.Enabled
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		app.Logger.Error("Failed to create watcher", "err", err)
+		return nil
+	}
 
-		if !telnetEnabled && !sshEnabled {
-			app.Logger.Warn("No listeners enabled.")
-			// Wait for config change or stop
+	// Watch all loaded config files
+	for _, file := range app.Config().LoadedFiles {
+		if err := watcher.Add(file); err != nil {
+			app.Logger.Error("Failed to watch config file", "file", relPath(file), "err", err)
+		} else {
+			app.Logger.Debug("Watching config file", "file", relPath(file))
+		}
+	}
+
+	go func() {
+		for {
 			select {
-			case <-stopChan:
-				if watcher != nil {
-					watcher.Close()
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
 				}
-				return
-			case <-restartChan:
-				if watcher != nil {
-					watcher.Close()
+				if event.Op&fsnotify.Write == fsnotify.Write {
+					app.Logger.Info("Config file modified, reloading...", "file", relPath(event.Name))
+					select {
+					case restartChan <- struct{}{}:
+					default:
+						// restart pending
+					}
 				}
-				app.Boot(cfgFile, false)
-				continue
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				app.Logger.Error("Watcher error", "err", err)
 			}
 		}
+	}()
+	return watcher
+}
 
-		// Start SSH Server
-		if sshEnabled {
-			wg.Add(1)
-			sshServer = network.NewSSH()
-			go func() {
-				defer wg.Done()
-				if err := sshServer.ListenAndServe(); err != nil {
-					app.Logger.Error("SSH Server stopped", "err", err)
-				}
-			}()
+// relPath tries to make a path relative to the working directory, for cleaner logging.
+func relPath(path string) string {
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, path); err == nil {
+			return rel
 		}
+	}
+	return path
+}
 
-		// Start Telnet Server
-		if telnetEnabled {
-			wg.Add(1)
-			telnetServer = network.NewTelnet()
-			go func() {
-				defer wg.Done()
-				if err := telnetServer.ListenAndServe(); err != nil {
-					app.Logger.Error("Telnet Server stopped", "err", err)
-				}
-			}()
+// shutdown stops taking new callers, warns those online and gives them the grace period to log off, then hangs up on
+// anyone left. Another signal cuts the grace period short.
+func shutdown(running map[string]*runningListener, stopChan <-chan os.Signal) {
+	app.Logger.Info("Shutting down...")
+	for name, r := range running {
+		stopListener(name, r)
+	}
+
+	grace := time.Duration(app.Config().ShutdownGrace) * time.Second
+	if callers := len(app.Nodes.Active()); callers > 0 && grace > 0 {
+		app.Logger.Info("Waiting for callers to log off", "callers", callers, "grace", grace)
+		app.Nodes.Broadcast(fmt.Sprintf("\r\n\r\n*** The system is shutting down in %s. Please log off. ***\r\n", graceText(grace)))
+
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		go func() {
+			select {
+			case <-stopChan:
+				app.Logger.Info("Shutting down now")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		app.Nodes.Wait(ctx)
+		cancel()
+	}
+
+	if callers := len(app.Nodes.Active()); callers > 0 {
+		app.Logger.Info("Hanging up on callers", "callers", callers)
+		app.Nodes.Broadcast("\r\n\r\n*** The system is shutting down now. Goodbye! ***\r\n")
+		app.Nodes.HangupAll()
+
+		// Give sessions a moment to save what they need to
+		ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer cancel()
+		if !app.Nodes.Wait(ctx) {
+			app.Logger.Warn("Gave up waiting for sessions to end", "callers", len(app.Nodes.Active()))
 		}
+	}
+}
 
-		// Wait for stop or restart
+// How long to wait for sessions to end once they've been hung up on
+const drainTimeout = 10 * time.Second
+
+// graceText describes the grace period for callers, e.g. "2 minutes" or "30 seconds".
+func graceText(grace time.Duration) string {
+	n, unit := int(grace/time.Second), "second"
+	if grace%time.Minute == 0 {
+		n, unit = int(grace/time.Minute), "minute"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// listener is implemented by each of the network servers. Stopping one only stops it accepting connections, callers
+// already connected stay online.
+type listener interface {
+	ListenAndServe() error
+	Stop() error
+}
+
+// listenerSpec describes a listener enabled in the config.
+type listenerSpec struct {
+	config any // The part of the config it uses, it's restarted on reload if this changes
+	create func() listener
+}
+
+// runningListener is a listener that has been started.
+type runningListener struct {
+	listener
+	config any
+	done   chan struct{} // Closed once ListenAndServe returns
+}
+
+// enabledListeners describes each listener enabled in the config, keyed by a name for logging.
+func enabledListeners() map[string]listenerSpec {
+	cfg := app.Config().Listeners
+	listeners := map[string]listenerSpec{}
+	if cfg.SSH.Enabled {
+		listeners["SSH"] = listenerSpec{cfg.SSH, func() listener { return network.NewSSH() }}
+	}
+	if cfg.Telnet.Enabled {
+		listeners["Telnet"] = listenerSpec{cfg.Telnet, func() listener { return network.NewTelnet() }}
+	}
+	if cfg.WebSocket.Enabled {
+		listeners["WebSocket"] = listenerSpec{cfg.WebSocket, func() listener { return network.NewWebSocket() }}
+	}
+	if cfg.Telnet.TLSEnabled {
+		listeners["Telnet TLS"] = listenerSpec{cfg.Telnet, func() listener { return network.NewTelnetTLS() }}
+	}
+	if cfg.RLogin.Enabled {
+		listeners["RLogin"] = listenerSpec{cfg.RLogin, func() listener { return network.NewRLogin() }}
+	}
+	return listeners
+}
+
+// startListeners brings the running listeners in line with those wanted, from enabledListeners: starting those newly
+// enabled, stopping those disabled, and restarting those whose config has changed. The rest are left alone.
+func startListeners(running map[string]*runningListener, wanted map[string]listenerSpec) {
+	for name, r := range running {
 		select {
-		case <-stopChan:
-			app.Logger.Info("Shutting down...")
-			if sshServer != nil {
-				sshServer.Stop()
-			}
-			if telnetServer != nil {
-				telnetServer.Stop()
-			}
-			if watcher != nil {
-				watcher.Close()
-			}
-			return
+		case <-r.done:
+			// It stopped by itself (e.g. its port was taken), so give it another go
+			delete(running, name)
+			continue
+		default:
+		}
 
-		case <-restartChan:
-			if sshServer != nil {
-				sshServer.Stop()
-			}
-			if telnetServer != nil {
-				telnetServer.Stop()
-			}
-			if watcher != nil {
-				watcher.Close()
+		spec, ok := wanted[name]
+		if ok && reflect.DeepEqual(spec.config, r.config) {
+			continue
+		}
+		if ok {
+			app.Logger.Info("Restarting " + name + " server")
+		}
+		stopListener(name, r)
+		delete(running, name)
+	}
+
+	for name, spec := range wanted {
+		if _, ok := running[name]; ok {
+			continue
+		}
+		r := &runningListener{
+			listener: spec.create(),
+			config:   spec.config,
+			done:     make(chan struct{}),
+		}
+		running[name] = r
+		go func() {
+			defer close(r.done)
+			if err := r.ListenAndServe(); err != nil {
+				app.Logger.Error(name+" server stopped", "err", err)
 			}
+		}()
+	}
 
-			// Wait for servers to stop
-			wg.Wait()
+	if len(running) == 0 {
+		app.Logger.Warn("No listeners enabled.")
+	}
+}
 
-			// Reload Config
-			if err := app.Boot(cfgFile, false); err != nil {
-				app.Logger.Error("Failed to reload config", "err", err)
-				// We continue, which will restart servers with the *existing* config/store
-				// because Boot did not swap them on failure.
-			}
+// stopListener stops a listener, waiting until it has.
+func stopListener(name string, r *runningListener) {
+	for {
+		if err := r.Stop(); err != nil {
+			app.Logger.Error("Failed to stop "+name+" server", "err", err)
+		}
+		select {
+		case <-r.done:
+			return
+		case <-time.After(100 * time.Millisecond):
+			// It may not have been listening yet, so try again
 		}
 	}
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/nodes"
+)
+
+// fakeListener serves until it's stopped, counting how often it was started.
+type fakeListener struct {
+	starts *int
+	mu     *sync.Mutex
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func (l *fakeListener) ListenAndServe() error {
+	l.mu.Lock()
+	*l.starts++
+	l.mu.Unlock()
+	<-l.stop
+	return nil
+}
+
+func (l *fakeListener) Stop() error {
+	l.once.Do(func() { close(l.stop) })
+	return nil
+}
+
+// caller is a connection that keeps what's broadcast to it.
+type caller struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (c *caller) Send(msg string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, msg)
+	return nil
+}
+
+func (c *caller) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.sent...)
+}
+
+func (c *caller) RemoteAddr() net.Addr                { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (c *caller) GetTerminalInfo() nodes.TerminalInfo { return nodes.TerminalInfo{} }
+func (c *caller) IsUTF8() bool                        { return false }
+func (c *caller) GetWidth() int                       { return 80 }
+
+var _ = Describe("Server", func() {
+	var (
+		mu      sync.Mutex
+		starts  map[string]*int
+		running map[string]*runningListener
+	)
+
+	// spec describes a listener with the given config, counting its starts under its name.
+	spec := func(name string, cfg any) listenerSpec {
+		if starts[name] == nil {
+			starts[name] = new(int)
+		}
+		return listenerSpec{cfg, func() listener {
+			return &fakeListener{starts: starts[name], mu: &mu, stop: make(chan struct{})}
+		}}
+	}
+
+	started := func(name string) func() int {
+		return func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return *starts[name]
+		}
+	}
+
+	BeforeEach(func() {
+		starts = map[string]*int{}
+		running = map[string]*runningListener{}
+		app.SetConfig(&config.Config{})
+		app.Nodes = nodes.NewManager(2)
+	})
+
+	AfterEach(func() {
+		for name, r := range running {
+			stopListener(name, r)
+		}
+	})
+
+	Describe("startListeners", func() {
+		It("starts the listeners wanted", func() {
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23), "SSH": spec("SSH", 22)})
+			Expect(running).To(HaveLen(2))
+			Eventually(started("Telnet")).Should(Equal(1))
+			Eventually(started("SSH")).Should(Equal(1))
+		})
+
+		It("leaves alone listeners whose config hasn't changed, and restarts the rest", func() {
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23), "SSH": spec("SSH", 22)})
+			telnet := running["Telnet"]
+
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23), "SSH": spec("SSH", 2222)})
+			Expect(running["Telnet"]).To(BeIdenticalTo(telnet))
+			Eventually(started("SSH")).Should(Equal(2))
+			Consistently(started("Telnet")).Should(Equal(1))
+		})
+
+		It("stops listeners no longer wanted", func() {
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23), "SSH": spec("SSH", 22)})
+			ssh := running["SSH"]
+
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23)})
+			Expect(running).To(HaveKey("Telnet"))
+			Expect(running).NotTo(HaveKey("SSH"))
+			Expect(ssh.done).To(BeClosed())
+		})
+
+		It("starts again a listener that stopped by itself", func() {
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23)})
+			Eventually(started("Telnet")).Should(Equal(1))
+			running["Telnet"].Stop()
+			Eventually(running["Telnet"].done).Should(BeClosed())
+
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23)})
+			Eventually(started("Telnet")).Should(Equal(2))
+		})
+	})
+
+	Describe("shutdown", func() {
+		var (
+			stopChan chan os.Signal
+			node     *nodes.Node
+			conn     *caller
+			hungUp   chan struct{}
+		)
+
+		// online puts a caller on a node, who logs off when hung up on
+		online := func() {
+			var err error
+			node, err = app.Nodes.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+			conn = &caller{}
+			node.Conn = conn
+			hungUp = make(chan struct{})
+			id := node.ID
+			node.SetHangup(func() {
+				close(hungUp)
+				app.Nodes.Release(id)
+			})
+		}
+
+		// shutDown shuts down in the background, closing the channel returned once it's done.
+		shutDown := func() chan struct{} {
+			done := make(chan struct{})
+			go func() {
+				shutdown(running, stopChan)
+				close(done)
+			}()
+			return done
+		}
+
+		BeforeEach(func() {
+			stopChan = make(chan os.Signal, 1)
+			app.Config().ShutdownGrace = 2
+			startListeners(running, map[string]listenerSpec{"Telnet": spec("Telnet", 23)})
+		})
+
+		It("stops the listeners, and is done straight away with nobody online", func() {
+			telnet := running["Telnet"]
+			Eventually(shutDown()).Should(BeClosed())
+			Expect(telnet.done).To(BeClosed())
+		})
+
+		It("warns callers, and waits for them to log off", func() {
+			online()
+			done := shutDown()
+			Eventually(conn.messages).Should(ContainElement(ContainSubstring("shutting down in 2 seconds")))
+			Consistently(done).ShouldNot(BeClosed())
+
+			app.Nodes.Release(node.ID)
+			Eventually(done).Should(BeClosed())
+			Expect(hungUp).NotTo(BeClosed())
+		})
+
+		It("hangs up on callers still online after the grace period", func() {
+			app.Config().ShutdownGrace = 1
+			online()
+			done := shutDown()
+			Consistently(hungUp, 500*time.Millisecond).ShouldNot(BeClosed())
+			Eventually(hungUp, 2*time.Second).Should(BeClosed())
+			Eventually(done).Should(BeClosed())
+			Expect(conn.messages()).To(ContainElement(ContainSubstring("shutting down now")))
+		})
+
+		It("cuts the grace period short on another signal", func() {
+			online()
+			done := shutDown()
+			Eventually(conn.messages).Should(HaveLen(1))
+
+			stopChan <- os.Interrupt
+			Eventually(hungUp).Should(BeClosed())
+			Eventually(done).Should(BeClosed())
+		})
+	})
+})
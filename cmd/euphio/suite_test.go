@@ -0,0 +1,20 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+)
+
+func TestEuphio(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	RunSpecs(t, "Euphio Suite")
+}
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
+	gossh "golang.org/x/crypto/ssh"
 
 	"euphio/internal/app"
+	"euphio/internal/store"
 )
 
 var userCmd = &cobra.Command{
@@ -20,7 +24,10 @@ var userCmd = &cobra.Command{
 	},
 }
 
-var verbose bool
+var (
+	verbose   bool
+	lockoutIP string
+)
 
 func init() {
 	userCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose logging")
@@ -29,6 +36,30 @@ func init() {
 	userCmd.AddCommand(userPassCmd)
 	userCmd.AddCommand(userRemoveCmd)
 	userCmd.AddCommand(userRenameCmd)
+	userCmd.AddCommand(userDeleteCmd)
+	userCmd.AddCommand(userLockCmd)
+	userCmd.AddCommand(userUnlockCmd)
+	userCmd.AddCommand(userValidateCmd)
+	userCmd.AddCommand(userKeyCmd)
+	userCmd.AddCommand(userGroupCmd)
+	userCmd.AddCommand(userLevelCmd)
+	userCmd.AddCommand(userFlagsCmd)
+	userCmd.AddCommand(userFactorCmd)
+
+	userKeyCmd.AddCommand(userKeyAddCmd)
+	userKeyCmd.AddCommand(userKeyListCmd)
+	userKeyCmd.AddCommand(userKeyRemoveCmd)
+
+	userGroupCmd.AddCommand(userGroupAddCmd)
+	userGroupCmd.AddCommand(userGroupRemoveCmd)
+
+	userUnlockCmd.Flags().StringVar(&lockoutIP, "ip", "", "also clear failed logins from this IP address")
+
+	userLevelCmd.AddCommand(userLevelSetCmd)
+
+	userFlagsCmd.AddCommand(userFlagsSetCmd)
+
+	userFactorCmd.AddCommand(userFactorSetCmd)
 }
 
 var userCreateCmd = &cobra.Command{
@@ -98,6 +129,11 @@ var userInfoCmd = &cobra.Command{
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		fmt.Fprintf(w, "ID:\t%d\n", user.ID)
 		fmt.Fprintf(w, "Username:\t%s\n", user.Username)
+		fmt.Fprintf(w, "Status:\t%s\n", user.Status())
+		fmt.Fprintf(w, "Security Level:\t%d\n", user.SecurityLevel)
+		fmt.Fprintf(w, "Auth Factor:\t%d\n", user.AuthFactor)
+		fmt.Fprintf(w, "Groups:\t%s\n", strings.Join(user.GroupNames(), ", "))
+		fmt.Fprintf(w, "Flags:\t%s\n", user.Flags)
 		fmt.Fprintf(w, "Created At:\t%s\n", user.CreatedAt.Format("2006-01-02 15:04:05"))
 		w.Flush()
 	},
@@ -146,3 +182,246 @@ var userRenameCmd = &cobra.Command{
 		fmt.Printf("User '%s' renamed to '%s'.\n", oldName, newName)
 	},
 }
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "Mark a user as deleted, keeping their record and username",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+
+		if err := app.Store.DeleteUser(username); err != nil {
+			log.Fatalf("Error deleting user: %v", err)
+		}
+		fmt.Printf("User '%s' deleted.\n", username)
+	},
+}
+
+var userLockCmd = &cobra.Command{
+	Use:   "lock [username]",
+	Short: "Lock a user's account so they can't log in",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+
+		if err := app.Store.SetLocked(username, true); err != nil {
+			log.Fatalf("Error locking user: %v", err)
+		}
+		fmt.Printf("User '%s' locked.\n", username)
+	},
+}
+
+var userUnlockCmd = &cobra.Command{
+	Use:     "unlock [username]",
+	Aliases: []string{"clear-lockout"},
+	Short:   "Unlock a user's account, clearing their failed logins and any lockout they caused",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+
+		if err := app.Store.SetLocked(username, false); err != nil {
+			log.Fatalf("Error unlocking user: %v", err)
+		}
+		if err := app.Store.ClearLoginFailures(store.LoginFailureUser, username); err != nil {
+			log.Fatalf("Error clearing failed logins: %v", err)
+		}
+		fmt.Printf("User '%s' unlocked.\n", username)
+		if lockoutIP != "" {
+			if err := app.Store.ClearLoginFailures(store.LoginFailureIP, lockoutIP); err != nil {
+				log.Fatalf("Error clearing failed logins: %v", err)
+			}
+			fmt.Printf("Failed logins from %s cleared.\n", lockoutIP)
+		}
+	},
+}
+
+var userValidateCmd = &cobra.Command{
+	Use:   "validate [username]",
+	Short: "Mark a user's account as validated",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+
+		if err := app.Store.SetValidated(username, true); err != nil {
+			log.Fatalf("Error validating user: %v", err)
+		}
+		fmt.Printf("User '%s' validated.\n", username)
+	},
+}
+
+var userGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage a user's group membership",
+}
+
+var userGroupAddCmd = &cobra.Command{
+	Use:   "add [username] [group]",
+	Short: "Add a user to a group",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		group := args[1]
+
+		if err := app.Store.AddUserToGroup(username, group); err != nil {
+			log.Fatalf("Error adding user to group: %v", err)
+		}
+		fmt.Printf("User '%s' added to group '%s'.\n", username, group)
+	},
+}
+
+var userGroupRemoveCmd = &cobra.Command{
+	Use:   "remove [username] [group]",
+	Short: "Remove a user from a group",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		group := args[1]
+
+		if err := app.Store.RemoveUserFromGroup(username, group); err != nil {
+			log.Fatalf("Error removing user from group: %v", err)
+		}
+		fmt.Printf("User '%s' removed from group '%s'.\n", username, group)
+	},
+}
+
+var userLevelCmd = &cobra.Command{
+	Use:   "level",
+	Short: "Manage a user's security level",
+}
+
+var userLevelSetCmd = &cobra.Command{
+	Use:   "set [username] [level]",
+	Short: "Set a user's security level",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		level, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Fatalf("Invalid security level: %s", args[1])
+		}
+
+		if err := app.Store.SetSecurityLevel(username, level); err != nil {
+			log.Fatalf("Error setting security level: %v", err)
+		}
+		fmt.Printf("Security level for user '%s' set to %d.\n", username, level)
+	},
+}
+
+var userFactorCmd = &cobra.Command{
+	Use:   "factor",
+	Short: "Manage the authentication factors a user is required to log in with",
+}
+
+var userFactorSetCmd = &cobra.Command{
+	Use:   "set [username] [factor]",
+	Short: "Require 1 (a password or key) or 2 (a second factor too) authentication factors, see the AR ACS code",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		factor, err := strconv.Atoi(args[1])
+		if err != nil || factor < 1 || factor > 2 {
+			log.Fatalf("Invalid authentication factor: %s", args[1])
+		}
+
+		if err := app.Store.SetAuthFactor(username, factor); err != nil {
+			log.Fatalf("Error setting authentication factor: %v", err)
+		}
+		fmt.Printf("Authentication factor for user '%s' set to %d.\n", username, factor)
+	},
+}
+
+var userFlagsCmd = &cobra.Command{
+	Use:   "flags",
+	Short: "Manage a user's access flags",
+}
+
+var userFlagsSetCmd = &cobra.Command{
+	Use:   "set [username] [flags]",
+	Short: "Set a user's access flags (e.g. ACZ), replacing any existing flags",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+
+		if err := app.Store.SetFlags(username, args[1]); err != nil {
+			log.Fatalf("Error setting flags: %v", err)
+		}
+		fmt.Printf("Flags for user '%s' set to %s.\n", username, strings.ToUpper(args[1]))
+	},
+}
+
+var userKeyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage a user's SSH public keys",
+}
+
+var userKeyAddCmd = &cobra.Command{
+	Use:   "add [username] [public_key_or_file]",
+	Short: "Authorize an SSH public key for a user",
+	Long:  "Authorizes an SSH public key for a user. The key can be given in authorized_keys format or as a path to a .pub file.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		authorizedKey, err := readAuthorizedKey(args[1])
+		if err != nil {
+			log.Fatalf("Error reading public key file: %v", err)
+		}
+
+		key, err := app.Store.AddUserKey(username, authorizedKey)
+		if err != nil {
+			log.Fatalf("Error adding key: %v", err)
+		}
+		fmt.Printf("Key %s added for user '%s'.\n", key.Fingerprint, username)
+	},
+}
+
+// readAuthorizedKey returns the key given on the command line, which is either a key in authorized_keys format
+// (options and all) or the path to a file holding one, such as a .pub file.
+func readAuthorizedKey(arg string) (string, error) {
+	if _, _, _, _, err := gossh.ParseAuthorizedKey([]byte(arg)); err == nil {
+		return arg, nil
+	}
+	data, err := os.ReadFile(arg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+var userKeyListCmd = &cobra.Command{
+	Use:   "list [username]",
+	Short: "List a user's SSH public keys",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+		keys, err := app.Store.ListUserKeys(username)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		if len(keys) == 0 {
+			fmt.Printf("User '%s' has no keys.\n", username)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tFingerprint\tComment\tAdded At")
+		for _, key := range keys {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", key.ID, key.Fingerprint, key.Comment, key.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		w.Flush()
+	},
+}
+
+var userKeyRemoveCmd = &cobra.Command{
+	Use:   "remove [username] [id_or_fingerprint]",
+	Short: "Remove one of a user's SSH public keys",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		username := args[0]
+
+		if err := app.Store.RemoveUserKey(username, args[1]); err != nil {
+			log.Fatalf("Error removing key: %v", err)
+		}
+		fmt.Printf("Key removed from user '%s'.\n", username)
+	},
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/store"
+)
+
+var _ = Describe("user unlock", func() {
+	BeforeEach(func() {
+		db, err := store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		app.Store = db
+		Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+		Expect(app.Store.SetLocked("bob", true)).To(Succeed())
+		for kind, key := range map[string]string{store.LoginFailureUser: "bob", store.LoginFailureIP: "192.0.2.1"} {
+			_, err := app.Store.RecordLoginFailure(kind, key, time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app.Store.LockLogin(kind, key, time.Now().Add(time.Hour))).To(Succeed())
+		}
+		DeferCleanup(func() { lockoutIP = "" })
+	})
+
+	locked := func(kind, key string) bool {
+		failure, err := app.Store.FindLoginFailure(kind, key)
+		Expect(err).NotTo(HaveOccurred())
+		return failure.Locked(time.Now())
+	}
+
+	It("unlocks the account and clears the user's lockout, leaving addresses alone", func() {
+		userUnlockCmd.Run(userUnlockCmd, []string{"bob"})
+
+		user, err := app.Store.FindUserByUsername("bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.Locked).To(BeFalse())
+		Expect(locked(store.LoginFailureUser, "bob")).To(BeFalse())
+		Expect(locked(store.LoginFailureIP, "192.0.2.1")).To(BeTrue())
+	})
+
+	It("clears an address's lockout with --ip", func() {
+		lockoutIP = "192.0.2.1"
+		userUnlockCmd.Run(userUnlockCmd, []string{"bob"})
+		Expect(locked(store.LoginFailureIP, "192.0.2.1")).To(BeFalse())
+	})
+})
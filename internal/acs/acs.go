@@ -0,0 +1,225 @@
+package acs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"euphio/internal/nodes"
+)
+
+// Access Control Strings (ACS) are small expressions used throughout the view configuration to decide who can see or
+// do what. They're modeled on the ACS found in ENiGMA½ and the classic BBS packages that came before it.
+//
+// Supported codes:
+//
+//	LI               caller is logged in
+//	SL<level>        security level is at least <level>
+//	AR<factor>       authentication factors required of the user are at least <factor> (2 for a second factor)
+//	GM[group,...]    member of any of the groups
+//	FL[flag,...]     has any of the access flags
+//	NN<node>         connected on node <node> (or NN[1,2] for any of several nodes)
+//	TW<width>        terminal is at least <width> columns wide
+//	EC<encoding>     terminal encoding, 0 is CP437 and 1 is UTF-8
+//	TM[hh:mm-hh:mm]  current time is within any of the windows (windows can wrap past midnight)
+//	SV[name=value]   session variable has any of the values, e.g. a prompt's answer (SV[name] for any value but empty)
+//
+// Codes can be combined with & (and), | (or), ! (not) and parentheses. Codes next to each other are ANDed.
+
+// Context holds everything an expression can be checked against.
+type Context struct {
+	LoggedIn      bool
+	SecurityLevel int
+	AuthFactor    int
+	Groups        []string
+	Flags         string
+	Node          int
+	Width         int
+	UTF8          bool
+	Now           time.Time
+	Vars          map[string]string
+}
+
+// ContextFor builds a Context from the node and its connected user.
+func ContextFor(node *nodes.Node) Context {
+	ctx := Context{
+		Node: node.ID,
+		Now:  time.Now(),
+		Vars: node.Vars,
+	}
+	ctx.Width = node.Width()
+	ctx.UTF8 = node.IsUTF8()
+	if node.User != nil {
+		ctx.LoggedIn = true
+		ctx.SecurityLevel = node.User.SecurityLevel
+		ctx.AuthFactor = node.User.AuthFactor
+		ctx.Groups = node.User.GroupNames()
+		ctx.Flags = node.User.Flags
+	}
+	return ctx
+}
+
+var cache sync.Map // map[string]Expr
+
+// Check parses (or fetches from cache) the expression and evaluates it. An empty expression always passes.
+func Check(expr string, ctx Context) (bool, error) {
+	if strings.TrimSpace(expr) == "" {
+		return true, nil
+	}
+
+	if cached, ok := cache.Load(expr); ok {
+		return cached.(Expr).Eval(ctx), nil
+	}
+
+	parsed, err := Parse(expr)
+	if err != nil {
+		return false, err
+	}
+	cache.Store(expr, parsed)
+	return parsed.Eval(ctx), nil
+}
+
+type argKind int
+
+const (
+	argNone argKind = 0
+	argNum  argKind = 1 << iota
+	argList
+)
+
+type checker func(e codeExpr, ctx Context) bool
+
+type codeDef struct {
+	arg      argKind
+	check    checker
+	validate func(e codeExpr) error
+}
+
+var codes = map[string]codeDef{
+	"LI": {
+		arg: argNone,
+		check: func(e codeExpr, ctx Context) bool {
+			return ctx.LoggedIn
+		},
+	},
+	"SL": {
+		arg: argNum,
+		check: func(e codeExpr, ctx Context) bool {
+			return ctx.SecurityLevel >= e.num
+		},
+	},
+	"AR": {
+		arg: argNum,
+		check: func(e codeExpr, ctx Context) bool {
+			return ctx.AuthFactor >= e.num
+		},
+	},
+	"GM": {
+		arg: argList,
+		check: func(e codeExpr, ctx Context) bool {
+			for _, group := range e.list {
+				if slices.ContainsFunc(ctx.Groups, func(g string) bool { return strings.EqualFold(g, group) }) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	"FL": {
+		arg: argList,
+		check: func(e codeExpr, ctx Context) bool {
+			// Flags are kept in upper case, but can be written either way
+			for _, flag := range e.list {
+				if strings.Contains(strings.ToUpper(ctx.Flags), strings.ToUpper(flag)) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	"NN": {
+		arg: argNum | argList,
+		check: func(e codeExpr, ctx Context) bool {
+			if e.hasNum {
+				return ctx.Node == e.num
+			}
+			return slices.Contains(e.list, strconv.Itoa(ctx.Node))
+		},
+	},
+	"TW": {
+		arg: argNum,
+		check: func(e codeExpr, ctx Context) bool {
+			return ctx.Width >= e.num
+		},
+	},
+	"EC": {
+		arg: argNum,
+		check: func(e codeExpr, ctx Context) bool {
+			return ctx.UTF8 == (e.num == 1)
+		},
+		validate: func(e codeExpr) error {
+			if e.num != 0 && e.num != 1 {
+				return fmt.Errorf("acs: invalid encoding EC%d, use EC0 for CP437 or EC1 for UTF-8", e.num)
+			}
+			return nil
+		},
+	},
+	"TM": {
+		arg: argList,
+		check: func(e codeExpr, ctx Context) bool {
+			now := ctx.Now.Hour()*60 + ctx.Now.Minute()
+			for _, window := range e.list {
+				start, end, _ := parseWindow(window)
+				if start <= end && now >= start && now < end {
+					return true
+				}
+				// The window wraps past midnight, e.g. 22:00-06:00
+				if start > end && (now >= start || now < end) {
+					return true
+				}
+			}
+			return false
+		},
+		validate: func(e codeExpr) error {
+			for _, window := range e.list {
+				if _, _, err := parseWindow(window); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	"SV": {
+		arg: argList,
+		check: func(e codeExpr, ctx Context) bool {
+			for _, item := range e.list {
+				name, value, hasValue := strings.Cut(item, "=")
+				got := ctx.Vars[strings.TrimSpace(name)]
+				if hasValue && strings.EqualFold(got, strings.TrimSpace(value)) || !hasValue && got != "" {
+					return true
+				}
+			}
+			return false
+		},
+	},
+}
+
+// parseWindow parses a "hh:mm-hh:mm" window into minutes since midnight.
+func parseWindow(window string) (int, int, error) {
+	from, to, ok := strings.Cut(window, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("acs: invalid time window %q", window)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, fmt.Errorf("acs: invalid time window %q", window)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, fmt.Errorf("acs: invalid time window %q", window)
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
@@ -0,0 +1,132 @@
+package acs_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/acs"
+)
+
+var _ = Describe("ACS", func() {
+	var ctx acs.Context
+
+	BeforeEach(func() {
+		ctx = acs.Context{
+			LoggedIn:      true,
+			SecurityLevel: 20,
+			AuthFactor:    1,
+			Groups:        []string{"users", "demo"},
+			Flags:         "AC",
+			Node:          2,
+			Width:         80,
+			UTF8:          true,
+			Now:           time.Date(2024, 1, 1, 23, 30, 0, 0, time.UTC),
+			Vars:          map[string]string{"newscan": "yes", "empty": ""},
+		}
+	})
+
+	check := func(expr string) bool {
+		ok, err := acs.Check(expr, ctx)
+		Expect(err).NotTo(HaveOccurred())
+		return ok
+	}
+
+	Describe("codes", func() {
+		It("passes an empty expression", func() {
+			Expect(check("")).To(BeTrue())
+		})
+
+		It("checks login status", func() {
+			Expect(check("LI")).To(BeTrue())
+			ctx.LoggedIn = false
+			Expect(check("LI")).To(BeFalse())
+		})
+
+		It("checks security level", func() {
+			Expect(check("SL20")).To(BeTrue())
+			Expect(check("SL21")).To(BeFalse())
+		})
+
+		It("checks the authentication factors required", func() {
+			Expect(check("AR1")).To(BeTrue())
+			Expect(check("AR2")).To(BeFalse())
+			ctx.AuthFactor = 2
+			Expect(check("AR2")).To(BeTrue())
+		})
+
+		It("checks group membership", func() {
+			Expect(check("GM[demo]")).To(BeTrue())
+			Expect(check("GM[sysops, users]")).To(BeTrue())
+			Expect(check("GM[sysops]")).To(BeFalse())
+		})
+
+		It("checks flags", func() {
+			Expect(check("FL[C]")).To(BeTrue())
+			Expect(check("FL[B]")).To(BeFalse())
+			Expect(check("FL[c]")).To(BeTrue())
+			Expect(check("FL[b]")).To(BeFalse())
+		})
+
+		It("checks the node", func() {
+			Expect(check("NN2")).To(BeTrue())
+			Expect(check("NN[1,3]")).To(BeFalse())
+		})
+
+		It("checks terminal width and encoding", func() {
+			Expect(check("TW80")).To(BeTrue())
+			Expect(check("TW132")).To(BeFalse())
+			Expect(check("EC1")).To(BeTrue())
+			Expect(check("EC0")).To(BeFalse())
+		})
+
+		It("checks time windows, including ones that wrap past midnight", func() {
+			Expect(check("TM[22:00-06:00]")).To(BeTrue())
+			Expect(check("TM[09:00-17:00]")).To(BeFalse())
+			Expect(check("TM[09:00-17:00,23:00-23:59]")).To(BeTrue())
+		})
+
+		It("checks session variables", func() {
+			Expect(check("SV[newscan=yes]")).To(BeTrue())
+			Expect(check("SV[newscan=Yes]")).To(BeTrue())
+			Expect(check("SV[newscan=no]")).To(BeFalse())
+			Expect(check("SV[newscan=no, newscan=yes]")).To(BeTrue())
+			Expect(check("SV[newscan]")).To(BeTrue())
+			Expect(check("SV[empty]")).To(BeFalse())
+			Expect(check("SV[missing]")).To(BeFalse())
+		})
+	})
+
+	Describe("operators", func() {
+		It("ANDs codes next to each other", func() {
+			Expect(check("SL20GM[demo]")).To(BeTrue())
+			Expect(check("SL20 GM[sysops]")).To(BeFalse())
+			Expect(check("SL20&GM[sysops]")).To(BeFalse())
+		})
+
+		It("supports OR, NOT and grouping", func() {
+			Expect(check("SL99|GM[demo]")).To(BeTrue())
+			Expect(check("!GM[demo]")).To(BeFalse())
+			Expect(check("!(SL99|NN1)")).To(BeTrue())
+			Expect(check("(SL99|NN2)&!TW132")).To(BeTrue())
+		})
+	})
+
+	Describe("errors", func() {
+		DescribeTable("rejects invalid expressions",
+			func(expr string) {
+				_, err := acs.Check(expr, ctx)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("unknown code", "XX1"),
+			Entry("missing argument", "SL"),
+			Entry("unexpected argument", "LI1"),
+			Entry("unclosed list", "GM[demo"),
+			Entry("unclosed group", "(SL1|LI"),
+			Entry("dangling operator", "SL1|"),
+			Entry("invalid time window", "TM[late]"),
+			Entry("unknown encoding", "EC2"),
+		)
+	})
+})
@@ -0,0 +1,224 @@
+package acs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a parsed ACS expression.
+type Expr interface {
+	Eval(ctx Context) bool
+}
+
+type notExpr struct {
+	expr Expr
+}
+
+func (e notExpr) Eval(ctx Context) bool {
+	return !e.expr.Eval(ctx)
+}
+
+type andExpr struct {
+	left, right Expr
+}
+
+func (e andExpr) Eval(ctx Context) bool {
+	return e.left.Eval(ctx) && e.right.Eval(ctx)
+}
+
+type orExpr struct {
+	left, right Expr
+}
+
+func (e orExpr) Eval(ctx Context) bool {
+	return e.left.Eval(ctx) || e.right.Eval(ctx)
+}
+
+// codeExpr is a single two letter code with its optional argument, e.g. SL20 or GM[users,sysops].
+type codeExpr struct {
+	code    string
+	num     int
+	hasNum  bool
+	list    []string
+	checker checker
+}
+
+func (e codeExpr) Eval(ctx Context) bool {
+	return e.checker(e, ctx)
+}
+
+// Parse parses an ACS expression.
+//
+// Grammar:
+//
+//	expr    := and ( "|" and )*
+//	and     := unary ( "&"? unary )*
+//	unary   := "!" unary | primary
+//	primary := "(" expr ")" | CODE [ NUMBER | "[" list "]" ]
+//
+// Codes placed next to each other are implicitly ANDed, so "SL20GM[users]" is the same as "SL20&GM[users]".
+func Parse(s string) (Expr, error) {
+	p := &parser{input: []rune(s)}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, fmt.Errorf("acs: unexpected %q at position %d in %q", p.peek(), p.pos, s)
+	}
+	return expr, nil
+}
+
+type parser struct {
+	input []rune
+	pos   int
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.peek() != '|' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		switch r := p.peek(); {
+		case r == '&':
+			p.pos++
+		case r == '!' || r == '(' || unicode.IsUpper(r):
+			// Implicit AND
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	p.skipSpace()
+	if p.peek() == '!' {
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, fmt.Errorf("acs: unexpected end of expression")
+	}
+
+	if p.peek() == '(' {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("acs: missing closing parenthesis at position %d", p.pos)
+		}
+		p.pos++
+		return expr, nil
+	}
+
+	if p.pos+2 > len(p.input) || !unicode.IsUpper(p.input[p.pos]) || !unicode.IsUpper(p.input[p.pos+1]) {
+		return nil, fmt.Errorf("acs: expected a code at position %d", p.pos)
+	}
+	e := codeExpr{code: string(p.input[p.pos : p.pos+2])}
+	p.pos += 2
+
+	def, ok := codes[e.code]
+	if !ok {
+		return nil, fmt.Errorf("acs: unknown code %q", e.code)
+	}
+	e.checker = def.check
+
+	switch {
+	case p.peek() == '[':
+		end := p.pos + 1
+		for end < len(p.input) && p.input[end] != ']' {
+			end++
+		}
+		if end >= len(p.input) {
+			return nil, fmt.Errorf("acs: missing closing bracket for %s", e.code)
+		}
+		for _, item := range strings.Split(string(p.input[p.pos+1:end]), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				e.list = append(e.list, item)
+			}
+		}
+		p.pos = end + 1
+	case unicode.IsDigit(p.peek()):
+		start := p.pos
+		for !p.eof() && unicode.IsDigit(p.peek()) {
+			p.pos++
+		}
+		e.num, _ = strconv.Atoi(string(p.input[start:p.pos]))
+		e.hasNum = true
+	}
+
+	if def.arg&argNum == 0 && e.hasNum {
+		return nil, fmt.Errorf("acs: %s does not take a number", e.code)
+	}
+	if def.arg&argList == 0 && e.list != nil {
+		return nil, fmt.Errorf("acs: %s does not take a list", e.code)
+	}
+	if def.arg != argNone && !e.hasNum && e.list == nil {
+		return nil, fmt.Errorf("acs: %s requires an argument", e.code)
+	}
+	if def.validate != nil {
+		if err := def.validate(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
@@ -0,0 +1,13 @@
+package acs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestACS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ACS Suite")
+}
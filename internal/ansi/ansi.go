@@ -17,10 +17,11 @@ const (
 	ClearScreen = "\x1b[2J\x1b[H"
 )
 
-// RenderArt loads an ANSI/art file (with overrides), processes it (SAUCE, CP437, Templates), and writes it to the
-// writer.
+// RenderArt loads an ANSI/art file (with overrides), processes it (SAUCE, CP437, Templates, MCI codes), and writes it
+// to the writer, returning where its MCI codes were (see StripMCI).
 // It handles file lookup, extension resolution (.utf8ans, .ans, .asc), and fallback to embedded assets.
-func RenderArt(w io.Writer, artName string, isUTF8 bool) error {
+// vars are made available to the template as .Custom (see CallerVars).
+func RenderArt(w io.Writer, artName string, isUTF8 bool, vars map[string]interface{}) (MCICodes, error) {
 	// Determine possible file extensions
 	extensions := []string{}
 	if isUTF8 {
@@ -31,16 +32,16 @@ func RenderArt(w io.Writer, artName string, isUTF8 bool) error {
 	// Load the art file
 	data, ext, err := LoadArt(artName, extensions)
 	if err != nil {
-		return fmt.Errorf("art not found: %s (checked extensions: %v)", artName, extensions)
+		return nil, fmt.Errorf("art not found: %s (checked extensions: %v)", artName, extensions)
 	}
 
 	// Remove SAUCE record
 	cleanData := StripSauce(data)
 
 	// Render templates first, as they might contain CP437 characters or UTF-8 depending on the source
-	renderedData, err := RenderTemplate(cleanData, nil)
+	renderedData, err := RenderTemplate(cleanData, vars)
 	if err != nil {
-		return fmt.Errorf("failed to render template for %s: %w", artName, err)
+		return nil, fmt.Errorf("failed to render template for %s: %w", artName, err)
 	}
 
 	var s string
@@ -59,13 +60,11 @@ func RenderArt(w io.Writer, artName string, isUTF8 bool) error {
 	s = strings.ReplaceAll(s, "\r\n", "\n")
 	s = strings.ReplaceAll(s, "\n", "\r\n")
 
-	finalBytes := []byte(s)
-	if ext == ".utf8ans" {
-	}
+	s, codes := StripMCI(s, isUTF8)
 
-	finalBytes = append(finalBytes, []byte(ResetSeq)...)
+	finalBytes := append([]byte(s), []byte(ResetSeq)...)
 	_, err = w.Write(finalBytes)
-	return err
+	return codes, err
 }
 
 // LoadArt attempts to find and load an art file.
@@ -73,9 +72,9 @@ func RenderArt(w io.Writer, artName string, isUTF8 bool) error {
 // Returns data, extension, error.
 func LoadArt(name string, exts []string) ([]byte, string, error) {
 	// Try configured art path (overrides)
-	if app.Config != nil && app.Config.Paths.Ansi != "" {
+	if cfg := app.Config(); cfg != nil && cfg.Paths.Ansi != "" {
 		for _, ext := range exts {
-			fullPath := filepath.Join(app.Config.Paths.Ansi, name+ext)
+			fullPath := filepath.Join(cfg.Paths.Ansi, name+ext)
 			data, err := os.ReadFile(fullPath)
 			if err == nil {
 				app.Logger.Debug("Loaded art from disk", "path", fullPath)
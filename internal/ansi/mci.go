@@ -0,0 +1,216 @@
+package ansi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Columns art is drawn for. Anything printed past the last column wraps onto the next line.
+const artWidth = 80
+
+// Position is a place on screen, counting from 1 at the top left.
+type Position struct {
+	Row int
+	Col int
+}
+
+// MoveTo returns the sequence that moves the cursor to the position.
+func (p Position) MoveTo() string {
+	return fmt.Sprintf("\x1b[%d;%dH", p.Row, p.Col)
+}
+
+// MCICodes maps the MCI codes found in art (e.g. "VM1") to where they were.
+type MCICodes map[string]Position
+
+// StripMCI finds the MCI codes in art and blanks them out. Codes are a percent sign, two capital letters and a
+// number, like %VM1, and mark where a view draws something: a menu, a label or an edit field. Each is replaced with
+// as many spaces, so the rest of the art stays put, and its position is noted.
+//
+// Pipe colour codes, like |07 or |14 (see PipeColors), are turned into colour sequences on the way, and take up no
+// room.
+//
+// Positions are only meaningful if the art is drawn from the top left of the screen (e.g. after clearing it). The
+// cursor is followed through the usual ANSI art sequences; if isUTF8 is false each byte is taken as a column, as for
+// CP437 art.
+func StripMCI(art string, isUTF8 bool) (string, MCICodes) {
+	var sb strings.Builder
+	codes := MCICodes{}
+	cur := Position{Row: 1, Col: 1}
+	saved := cur
+
+	for i := 0; i < len(art); {
+		c := art[i]
+		switch {
+		case c == 0x1b:
+			n := sequenceLength(art[i:])
+			cur, saved = followSequence(art[i:i+n], cur, saved)
+			sb.WriteString(art[i : i+n])
+			i += n
+			continue
+
+		case c == '|':
+			if seq, n := pipeAt(art[i:]); n > 0 {
+				sb.WriteString(seq)
+				i += n
+				continue
+			}
+
+		case c == '%':
+			if code, n := mciAt(art[i:]); n > 0 {
+				if cur.Col > artWidth {
+					cur = Position{Row: cur.Row + 1, Col: 1}
+				}
+				codes[code] = cur
+				sb.WriteString(strings.Repeat(" ", n))
+				cur.Col += n
+				i += n
+				continue
+			}
+
+		case c == '\r':
+			cur.Col = 1
+		case c == '\n':
+			cur.Row++
+		case c == '\b':
+			cur.Col = max(cur.Col-1, 1)
+		case c == '\t':
+			cur.Col = min((cur.Col-1)/8*8+9, artWidth)
+		case c < 0x20:
+			// Other control characters don't move the cursor
+		default:
+			if cur.Col > artWidth {
+				cur = Position{Row: cur.Row + 1, Col: 1}
+			}
+			cur.Col++
+		}
+
+		n := 1
+		if isUTF8 && c >= utf8.RuneSelf {
+			_, n = utf8.DecodeRuneInString(art[i:])
+		}
+		sb.WriteString(art[i : i+n])
+		i += n
+	}
+	return sb.String(), codes
+}
+
+// mciAt returns the MCI code at the start of s, without the percent sign, and how long it is with it.
+func mciAt(s string) (string, int) {
+	if len(s) < 4 || !isUpper(s[1]) || !isUpper(s[2]) || !isDigit(s[3]) {
+		return "", 0
+	}
+	n := 4
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return s[1:n], n
+}
+
+// SGR parameters for the pipe colour codes: |00 to |15 set the foreground, in the PC palette order, and |16 to |23
+// the background.
+var pipeColors = [...]string{
+	"22;30", "22;34", "22;32", "22;36", "22;31", "22;35", "22;33", "22;37",
+	"1;30", "1;34", "1;32", "1;36", "1;31", "1;35", "1;33", "1;37",
+	"40", "44", "42", "46", "41", "45", "43", "47",
+}
+
+// PipeColors turns the pipe colour codes in s, as used by Renegade, Mystic and ENiGMA, into colour sequences. |00
+// to |15 set the foreground colour, and |16 to |23 the background. Anything else after a pipe is left alone.
+func PipeColors(s string) string {
+	if !strings.Contains(s, "|") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if seq, n := pipeAt(s[i:]); n > 0 {
+			sb.WriteString(seq)
+			i += n
+			continue
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String()
+}
+
+// pipeAt returns the colour sequence for the pipe colour code at the start of s, and how long the code is.
+func pipeAt(s string) (string, int) {
+	if len(s) < 3 || s[0] != '|' || !isDigit(s[1]) || !isDigit(s[2]) {
+		return "", 0
+	}
+	code := int(s[1]-'0')*10 + int(s[2]-'0')
+	if code >= len(pipeColors) {
+		return "", 0
+	}
+	return "\x1b[" + pipeColors[code] + "m", 3
+}
+
+// sequenceLength returns the length of the escape sequence at the start of s.
+func sequenceLength(s string) int {
+	if len(s) < 2 || s[1] != '[' {
+		return min(len(s), 2)
+	}
+	for i := 2; i < len(s); i++ {
+		if s[i] >= 0x40 && s[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// followSequence works out where an escape sequence leaves the cursor, and the saved position.
+func followSequence(seq string, cur, saved Position) (Position, Position) {
+	if len(seq) < 3 || seq[1] != '[' {
+		switch seq {
+		case "\x1b7":
+			saved = cur
+		case "\x1b8":
+			cur = saved
+		}
+		return cur, saved
+	}
+
+	final := seq[len(seq)-1]
+	params := strings.Split(seq[2:len(seq)-1], ";")
+	param := func(i, def int) int {
+		if i < len(params) {
+			if n, err := strconv.Atoi(params[i]); err == nil && n > 0 {
+				return n
+			}
+		}
+		return def
+	}
+
+	switch final {
+	case 'A':
+		cur.Row = max(cur.Row-param(0, 1), 1)
+	case 'B':
+		cur.Row += param(0, 1)
+	case 'C':
+		cur.Col = min(cur.Col+param(0, 1), artWidth)
+	case 'D':
+		cur.Col = max(min(cur.Col, artWidth)-param(0, 1), 1)
+	case 'H', 'f':
+		cur = Position{Row: param(0, 1), Col: param(1, 1)}
+	case 'J':
+		// ANSI.SYS homes the cursor when clearing the screen, and art is drawn for it
+		if param(0, 0) == 2 {
+			cur = Position{Row: 1, Col: 1}
+		}
+	case 's':
+		saved = cur
+	case 'u':
+		cur = saved
+	}
+	return cur, saved
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
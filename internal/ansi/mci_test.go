@@ -0,0 +1,74 @@
+package ansi_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/ansi"
+)
+
+var _ = Describe("StripMCI", func() {
+	It("blanks out codes, noting where they were", func() {
+		art, codes := ansi.StripMCI("Menu:\r\n  %VM1 and %TL12\r\n", true)
+		Expect(art).To(Equal("Menu:\r\n       and      \r\n"))
+		Expect(codes).To(Equal(ansi.MCICodes{
+			"VM1":  {Row: 2, Col: 3},
+			"TL12": {Row: 2, Col: 12},
+		}))
+	})
+
+	It("leaves anything that isn't a code alone", func() {
+		art, codes := ansi.StripMCI("100% %vm1 %V1 %", true)
+		Expect(art).To(Equal("100% %vm1 %V1 %"))
+		Expect(codes).To(BeEmpty())
+	})
+
+	It("follows cursor movement", func() {
+		_, codes := ansi.StripMCI("\x1b[2J\x1b[5;10H%ET1\x1b[2B\x1b[3D%ET2\x1b[s\x1b[H%ET3\x1b[u%ET4", true)
+		Expect(codes).To(Equal(ansi.MCICodes{
+			"ET1": {Row: 5, Col: 10},
+			"ET2": {Row: 7, Col: 11},
+			"ET3": {Row: 1, Col: 1},
+			"ET4": {Row: 7, Col: 15},
+		}))
+	})
+
+	It("skips over colour sequences", func() {
+		_, codes := ansi.StripMCI("\x1b[1;33;44mab\x1b[0m%VM1", true)
+		Expect(codes).To(HaveKeyWithValue("VM1", ansi.Position{Row: 1, Col: 3}))
+	})
+
+	It("wraps at 80 columns", func() {
+		line := ""
+		for range 80 {
+			line += "x"
+		}
+		_, codes := ansi.StripMCI(line+"%VM1", true)
+		Expect(codes).To(HaveKeyWithValue("VM1", ansi.Position{Row: 2, Col: 1}))
+	})
+
+	It("counts UTF-8 characters as one column, and bytes as one otherwise", func() {
+		_, codes := ansi.StripMCI("░▒%VM1", true)
+		Expect(codes).To(HaveKeyWithValue("VM1", ansi.Position{Row: 1, Col: 3}))
+
+		_, codes = ansi.StripMCI("\xb0\xb1%VM1", false)
+		Expect(codes).To(HaveKeyWithValue("VM1", ansi.Position{Row: 1, Col: 3}))
+	})
+
+	It("turns pipe colour codes into colour sequences, taking up no room", func() {
+		art, codes := ansi.StripMCI("|14|17Hi|07 %VM1 |99|7", true)
+		Expect(art).To(Equal("\x1b[1;33m\x1b[44mHi\x1b[22;37m      |99|7"))
+		Expect(codes).To(HaveKeyWithValue("VM1", ansi.Position{Row: 1, Col: 4}))
+	})
+})
+
+var _ = Describe("PipeColors", func() {
+	It("sets the foreground and background colours", func() {
+		Expect(ansi.PipeColors("|00a|08b|15c|16d|23e")).
+			To(Equal("\x1b[22;30ma\x1b[1;30mb\x1b[1;37mc\x1b[40md\x1b[47me"))
+	})
+
+	It("leaves anything else after a pipe alone", func() {
+		Expect(ansi.PipeColors("a | b |24 |1x ||")).To(Equal("a | b |24 |1x ||"))
+	})
+})
@@ -0,0 +1,13 @@
+package ansi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAnsi(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ANSI Suite")
+}
@@ -2,9 +2,12 @@ package ansi
 
 import (
 	"bytes"
+	"maps"
 	"text/template"
+	"time"
 
 	"euphio/internal/app"
+	"euphio/internal/nodes"
 
 	"github.com/Masterminds/sprig/v3"
 )
@@ -24,16 +27,37 @@ type TemplateData struct {
 // NewTemplateData creates a TemplateData struct populated with global config values.
 func NewTemplateData() *TemplateData {
 	return &TemplateData{
-		BoardName:       app.Config.General.BoardName,
-		PrettyBoardName: app.Config.General.PrettyBoardName,
-		Description:     app.Config.General.Description,
-		Hostname:        app.Config.General.Hostname,
-		Website:         app.Config.General.Website,
+		BoardName:       app.Config().General.BoardName,
+		PrettyBoardName: app.Config().General.PrettyBoardName,
+		Description:     app.Config().General.Description,
+		Hostname:        app.Config().General.Hostname,
+		Website:         app.Config().General.Website,
 		Version:         app.Version,
 		Custom:          make(map[string]interface{}),
 	}
 }
 
+// CallerVars returns what templates know about the caller on a node, for use as .Custom:
+//
+//	Node:     the node number
+//	Username: the caller's username, once logged in
+//	TimeLeft: minutes left online today once logged in, or "Unlimited"
+//	Vars:     the session variables, e.g. {{ .Custom.Vars.newscan }} for the answer to a prompt
+func CallerVars(node *nodes.Node) map[string]interface{} {
+	vars := map[string]interface{}{
+		"Node": node.ID,
+		"Vars": maps.Clone(node.Vars),
+	}
+	if user := node.User; user != nil {
+		vars["Username"] = user.Username
+		vars["TimeLeft"] = "Unlimited"
+		if daily := app.Config().Limits.ForLevel(user.SecurityLevel).Daily(); daily > 0 {
+			vars["TimeLeft"] = int(max(user.TimeLeft(daily, time.Now()), 0) / time.Minute)
+		}
+	}
+	return vars
+}
+
 // RenderTemplate parses and executes the given data as a Go template.
 // It automatically injects global configuration values.
 // You can provide additional custom data via the 'extra' map.
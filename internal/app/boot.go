@@ -1,11 +1,14 @@
 package app
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"euphio/internal/config"
 	"euphio/internal/logger"
@@ -14,44 +17,54 @@ import (
 )
 
 var (
-	Version = "v0.1.000" // Default version, can be overwritten by build flags
-	Config  *config.Config
-	Store   *store.Store
-	Logger  *slog.Logger
-	Nodes   *nodes.Manager
+	Version   = "v0.1.000" // Default version, can be overwritten by build flags
+	StartedAt = time.Now()
+	Store     *store.Store
+	Logger    *slog.Logger
+	Nodes     *nodes.Manager
+
+	// Validate checks a config before it's used, for mistakes config.Load can't see (e.g. an ACS that doesn't parse).
+	// It's set by main, as the checks belong to packages that import this one.
+	Validate func(cfg *config.Config) error
+
+	current    atomic.Pointer[config.Config]
+	logHandler *logger.SwapHandler // Behind Logger, so reloading can replace it
 )
 
-func Boot(configPath string, quiet bool) error {
-	if configPath == "" {
-		configPath = "config/example.yml"
-	}
+// Config returns the configuration in use. Reloading replaces it rather than changing it, so callers can hold on to
+// what's returned for a consistent view, e.g. for the rest of a request.
+func Config() *config.Config {
+	return current.Load()
+}
 
-	// Load the configuration
-	newConfig, err := config.Load(configPath)
+// SetConfig replaces the configuration in use.
+func SetConfig(cfg *config.Config) {
+	current.Store(cfg)
+}
+
+// The config loaded if no path is given
+const defaultConfigPath = "config/example.yml"
+
+func Boot(configPath string, quiet bool) error {
+	configPath = cmp.Or(configPath, defaultConfigPath)
+	newConfig, err := loadConfig(configPath)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("configuration file not found: %s\n\nPlease ensure you have a valid configuration file.\nYou can specify one using the -c flag or EUPHIO_CONFIG environment variable.\nExample: euphio -c config/myconfig.yml\n\nYou can get a basic configuration setup by initializing the current path.\nExample: euphio init", configPath)
-		}
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return err
 	}
 
 	// If all successful, swap globals and cleanup.
-	Config = newConfig
+	SetConfig(newConfig)
 
-	Nodes = nodes.NewManager(Config.MaxNodes)
+	Nodes = nodes.NewManager(newConfig.MaxNodes)
 
 	// Setup Logger
-	Logger = logger.Setup(Config.Loggers, quiet)
+	logHandler = logger.NewSwap(logger.Setup(newConfig.Loggers, quiet).Handler())
+	Logger = slog.New(logHandler)
 
 	// Prepare the data store
-	dir := Config.Paths.Data
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return fmt.Errorf("failed to create data path: %w", err)
-	}
-
-	newStore, err := store.New(filepath.Clean(filepath.Join(dir, "data.sqlite3")), quiet)
+	newStore, err := openStore(newConfig.Paths.Data, quiet)
 	if err != nil {
-		return fmt.Errorf("failed to connect to the database: %w", err)
+		return err
 	}
 
 	if Store != nil {
@@ -68,4 +81,65 @@ func Boot(configPath string, quiet bool) error {
 	return nil
 }
 
-// initConfig and initStore are removed as they are now integrated into Boot for safety.
+// Reload loads the configuration again for a running server, without disturbing callers who are online. The new
+// config replaces the old one, so sessions see changes to views, prompts and the like as they move around, and the
+// loggers are set up afresh. Nodes and the data store are kept, so changes to maxNodes and the data path take effect
+// on restart. On failure, nothing is changed.
+func Reload(configPath string) error {
+	configPath = cmp.Or(configPath, defaultConfigPath)
+	newConfig, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	old := Config()
+	if newConfig.MaxNodes != old.MaxNodes {
+		Logger.Warn("Changes to maxNodes take effect on restart", "maxNodes", Nodes.Max())
+	}
+	if newConfig.Paths.Data != old.Paths.Data {
+		Logger.Warn("Changes to the data path take effect on restart", "data", old.Paths.Data)
+	}
+
+	SetConfig(newConfig)
+	logHandler.Swap(logger.Setup(newConfig.Loggers, false).Handler())
+
+	Logger.Info("Reloaded configuration", "file", configPath)
+	return nil
+}
+
+// loadConfig loads and validates the configuration.
+func loadConfig(configPath string) (*config.Config, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("configuration file not found: %s\n\nPlease ensure you have a valid configuration file.\nYou can specify one using the -c flag or EUPHIO_CONFIG environment variable.\nExample: euphio -c config/myconfig.yml\n\nYou can get a basic configuration setup by initializing the current path.\nExample: euphio init", configPath)
+		}
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// openStore opens the data store in the data directory, creating them if need be.
+func openStore(dir string, quiet bool) (*store.Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data path: %w", err)
+	}
+	db, err := store.New(filepath.Clean(filepath.Join(dir, "data.sqlite3")), quiet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	return db, nil
+}
+
+func validate(cfg *config.Config) error {
+	if Validate == nil {
+		return nil
+	}
+	if err := Validate(cfg); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	return nil
+}
@@ -4,5 +4,8 @@ import (
 	"embed"
 )
 
-//go:embed *.yml config/*
+// The browser terminal is vendored into web/xterm, see vendor-xterm.sh
+//go:generate sh vendor-xterm.sh
+
+//go:embed *.yml config/* web/*
 var FS embed.FS
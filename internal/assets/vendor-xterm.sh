@@ -0,0 +1,25 @@
+#!/bin/sh
+# Vendors the xterm.js browser terminal and its fit addon into web/xterm, for the WebSocket client page to load from
+# the embedded assets rather than a CDN. Run with `go generate ./internal/assets`, and commit what it fetches.
+set -eu
+
+XTERM_VERSION=5.5.0
+FIT_VERSION=0.10.0
+
+dir=$(cd "$(dirname "$0")" && pwd)/web/xterm
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+
+fetch() {
+	mkdir "$tmp/$1"
+	curl -fsSL "https://registry.npmjs.org/@xterm/$1/-/$1-$2.tgz" | tar -xz -C "$tmp/$1" --strip-components=1
+}
+
+fetch xterm "$XTERM_VERSION"
+fetch addon-fit "$FIT_VERSION"
+
+mkdir -p "$dir"
+cp "$tmp/xterm/lib/xterm.js" "$tmp/xterm/css/xterm.css" "$dir/"
+cp "$tmp/addon-fit/lib/addon-fit.js" "$dir/"
+cp "$tmp/xterm/LICENSE" "$dir/LICENSE"
+echo "xterm $XTERM_VERSION, addon-fit $FIT_VERSION" >"$dir/VERSION"
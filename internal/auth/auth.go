@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/store"
+)
+
+var (
+	ErrLockedOut = errors.New("too many failed logins")
+	ErrTooSoon   = errors.New("login attempted too soon after a failure")
+	ErrNoTime    = errors.New("no time left today")
+)
+
+// Login authenticates a caller, slowing down and eventually locking out repeated failures for the username or the
+// remote address. Attempts made before the back-off from earlier failures has passed are refused with ErrTooSoon,
+// without checking the password, and users with no time left today with ErrNoTime. This should be used by anything
+// accepting a password from a caller.
+func Login(username, password string, addr net.Addr) (*store.User, error) {
+	if err := CheckLocked(username, addr); err != nil {
+		return nil, err
+	}
+	if wait := backoff(username, addr); wait > 0 {
+		app.Logger.Debug("Login attempt during back-off", "user", username, "addr", hostOf(addr), "wait", wait)
+		return nil, ErrTooSoon
+	}
+
+	user, err := app.Store.Authenticate(username, password)
+	if err != nil {
+		// Only track usernames that exist, so scanners guessing names don't fill the store
+		if errors.Is(err, store.ErrInvalidPassword) {
+			recordFailure(store.LoginFailureUser, username)
+		}
+		if !errors.Is(err, store.ErrAccountLocked) {
+			recordFailure(store.LoginFailureIP, hostOf(addr))
+		}
+		return nil, err
+	}
+
+	clearFailures(store.LoginFailureUser, username)
+	clearFailures(store.LoginFailureIP, hostOf(addr))
+	if err := CheckTimeLeft(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// CheckTimeLeft returns ErrNoTime if the user has used up their time online for today. Anything logging a user in
+// without Login should check this too.
+func CheckTimeLeft(user *store.User) error {
+	daily := app.Config().Limits.ForLevel(user.SecurityLevel).Daily()
+	if daily > 0 && user.TimeLeft(daily, time.Now()) <= 0 {
+		return ErrNoTime
+	}
+	return nil
+}
+
+// CheckLocked returns ErrLockedOut if logins for the username or the remote address are currently locked out.
+func CheckLocked(username string, addr net.Addr) error {
+	now := time.Now()
+	for kind, key := range keysFor(username, addr) {
+		failure, err := app.Store.FindLoginFailure(kind, key)
+		if err != nil {
+			app.Logger.Error("Failed to check login lockout", "kind", kind, "key", key, "err", err)
+			continue
+		}
+		if failure.Locked(now) {
+			app.Logger.Debug("Login attempt while locked out", "kind", kind, "key", key, "until", failure.LockedUntil)
+			return ErrLockedOut
+		}
+	}
+	return nil
+}
+
+// backoff returns how much longer the back-off from previous failures has to run. Each failure doubles the delay,
+// which makes guessing passwords impractically slow without locking legitimate users out.
+func backoff(username string, addr net.Addr) time.Duration {
+	cfg := app.Config().Security
+	if cfg.LoginBackoff <= 0 {
+		return 0
+	}
+
+	var wait time.Duration
+	for kind, key := range keysFor(username, addr) {
+		failure, err := app.Store.FindLoginFailure(kind, key)
+		if err != nil || failure.Count == 0 {
+			continue
+		}
+
+		delay := time.Duration(cfg.LoginBackoff) * time.Millisecond << min(failure.Count-1, 16)
+		if maxDelay := time.Duration(cfg.LoginBackoffMax) * time.Millisecond; maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+		wait = max(wait, time.Until(failure.LastFailed.Add(delay)))
+	}
+	return wait
+}
+
+func recordFailure(kind, key string) {
+	cfg := app.Config().Security
+	failure, err := app.Store.RecordLoginFailure(kind, key, lockoutDuration())
+	if err != nil {
+		app.Logger.Error("Failed to record login failure", "kind", kind, "key", key, "err", err)
+		return
+	}
+
+	if cfg.MaxLoginFailures > 0 && failure.Count >= cfg.MaxLoginFailures {
+		until := time.Now().Add(lockoutDuration())
+		if err := app.Store.LockLogin(kind, key, until); err != nil {
+			app.Logger.Error("Failed to lock out logins", "kind", kind, "key", key, "err", err)
+			return
+		}
+		app.Logger.Warn("Logins locked out", "kind", kind, "key", key, "failures", failure.Count, "until", until)
+	}
+}
+
+func clearFailures(kind, key string) {
+	if err := app.Store.ClearLoginFailures(kind, key); err != nil {
+		app.Logger.Error("Failed to clear login failures", "kind", kind, "key", key, "err", err)
+	}
+}
+
+func lockoutDuration() time.Duration {
+	if app.Config().Security.LockoutMinutes > 0 {
+		return time.Duration(app.Config().Security.LockoutMinutes) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+func keysFor(username string, addr net.Addr) map[string]string {
+	return map[string]string{
+		store.LoginFailureUser: username,
+		store.LoginFailureIP:   hostOf(addr),
+	}
+}
+
+// hostOf returns the IP portion of an address, so all connections from the same host are tracked together.
+func hostOf(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
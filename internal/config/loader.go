@@ -4,22 +4,27 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	LoadedFiles []string          `yaml:"-"` // Track all files loaded for this config
-	Include     []string          `yaml:"include"`
-	Debug       bool              `yaml:"debug"`
-	MaxNodes    int               `yaml:"maxNodes"`
-	HotReload   bool              `yaml:"hotReload"`
-	General     GeneralConfig     `yaml:"general"`
-	Paths       PathsConfig       `yaml:"paths"`
-	Loggers     []LoggerConfig    `yaml:"loggers"`
-	Listeners   ListenersConfig   `yaml:"listeners"`
-	Views       map[string]View   `yaml:"views"`
-	Prompts     map[string]Prompt `yaml:"prompts"`
+	LoadedFiles   []string          `yaml:"-"` // Track all files loaded for this config
+	Include       []string          `yaml:"include"`
+	Debug         bool              `yaml:"debug"`
+	MaxNodes      int               `yaml:"maxNodes"`
+	HotReload     bool              `yaml:"hotReload"`
+	ShutdownGrace int               `yaml:"shutdownGrace"` // Seconds callers are given to log off when shutting down
+	General       GeneralConfig     `yaml:"general"`
+	Paths         PathsConfig       `yaml:"paths"`
+	Loggers       []LoggerConfig    `yaml:"loggers"`
+	Listeners     ListenersConfig   `yaml:"listeners"`
+	Security      SecurityConfig    `yaml:"security"`
+	Terminal      TerminalConfig    `yaml:"terminal"`
+	Limits        LimitsConfig      `yaml:"limits"`
+	Views         map[string]View   `yaml:"views"`
+	Prompts       map[string]Prompt `yaml:"prompts"`
 }
 
 type GeneralConfig struct {
@@ -28,6 +33,7 @@ type GeneralConfig struct {
 	Description     string `yaml:"description"`
 	Hostname        string `yaml:"hostname"`
 	Website         string `yaml:"website"`
+	Colors          string `yaml:"colors"` // Colours the board's art uses, for listing sites: 16 (default), 256 or truecolor
 }
 
 type PathsConfig struct {
@@ -46,43 +52,161 @@ type LoggerConfig struct {
 }
 
 type ListenersConfig struct {
-	Telnet TelnetConfig `yaml:"telnet"`
-	SSH    SSHConfig    `yaml:"ssh"`
+	Telnet    TelnetConfig    `yaml:"telnet"`
+	SSH       SSHConfig       `yaml:"ssh"`
+	WebSocket WebSocketConfig `yaml:"websocket"`
+	RLogin    RLoginConfig    `yaml:"rlogin"`
 }
 
 type TelnetConfig struct {
-	Enabled     bool   `yaml:"enabled"`
-	Port        int    `yaml:"port"`
-	InitialView string `yaml:"initialView"`
+	Enabled       bool                `yaml:"enabled"`
+	Port          int                 `yaml:"port"`
+	InitialView   string              `yaml:"initialView"`
+	TLSEnabled    bool                `yaml:"tlsEnabled"` // Also listen for Telnet over TLS (telnets)
+	TLSPort       int                 `yaml:"tlsPort"`
+	CertFile      string              `yaml:"certFile"` // PEM encoded certificate chain
+	KeyFile       string              `yaml:"keyFile"`  // PEM encoded private key
+	Linemode      bool                `yaml:"linemode"` // Offer RFC 1184 LINEMODE so capable clients edit lines locally
+	ProxyProtocol ProxyProtocolConfig `yaml:"proxyProtocol"`
 }
 
 type SSHConfig struct {
-	Enabled     bool   `yaml:"enabled"`
-	Port        int    `yaml:"port"`
-	InitialView string `yaml:"initialView"`
-	KeyFile     string `yaml:"keyFile"`
+	Enabled       bool                `yaml:"enabled"`
+	Port          int                 `yaml:"port"`
+	InitialView   string              `yaml:"initialView"`
+	KeyFile       string              `yaml:"keyFile"`
+	ProxyProtocol ProxyProtocolConfig `yaml:"proxyProtocol"`
+}
+
+type WebSocketConfig struct {
+	Enabled       bool                `yaml:"enabled"`
+	Port          int                 `yaml:"port"`
+	InitialView   string              `yaml:"initialView"`
+	Protocol      string              `yaml:"protocol"`  // Used when the client doesn't ask for a subprotocol, "raw" (default) or "telnet"
+	ServePage     bool                `yaml:"servePage"` // Serve a browser based terminal client
+	ProxyProtocol ProxyProtocolConfig `yaml:"proxyProtocol"`
+}
+
+type RLoginConfig struct {
+	Enabled       bool                `yaml:"enabled"`
+	Port          int                 `yaml:"port"`
+	InitialView   string              `yaml:"initialView"`
+	TrustedHosts  []string            `yaml:"trustedHosts"` // CIDRs allowed to log callers in by username alone
+	SharedSecret  string              `yaml:"sharedSecret"` // Callers sending this in place of a password are logged in by username alone
+	SwapUsers     bool                `yaml:"swapUsers"`    // Read the username from the client user field and password from the server user field
+	ProxyProtocol ProxyProtocolConfig `yaml:"proxyProtocol"`
+}
+
+// ProxyProtocolConfig enables reading PROXY protocol headers on a listener, for running behind a load balancer.
+type ProxyProtocolConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	TrustedProxies []string `yaml:"trustedProxies"` // CIDRs allowed to send headers, if empty every connection must send one
+}
+
+type TerminalConfig struct {
+	Probe         bool `yaml:"probe"`         // Query the terminal for its size, UTF-8 support and CTerm version
+	ProbeTimeout  int  `yaml:"probeTimeout"`  // Milliseconds to wait for the terminal to answer
+	EscapeTimeout int  `yaml:"escapeTimeout"` // Milliseconds to wait after ESC before taking it as the Escape key
+}
+
+type SecurityConfig struct {
+	MaxLoginFailures int `yaml:"maxLoginFailures"` // Failures (per username or IP) before locking out, 0 disables
+	LockoutMinutes   int `yaml:"lockoutMinutes"`   // How long a lockout lasts
+	LoginBackoff     int `yaml:"loginBackoff"`     // Base delay in milliseconds, doubled with each failure
+	LoginBackoffMax  int `yaml:"loginBackoffMax"`  // Maximum delay in milliseconds
+
+	Allow                []string `yaml:"allow"`                // CIDRs that bypass bans and connection limits
+	Deny                 []string `yaml:"deny"`                 // CIDRs that are always refused (see also `euphio ban`)
+	MaxConnectionsPerIP  int      `yaml:"maxConnectionsPerIP"`  // Concurrent connections from one IP, 0 for no limit
+	MaxConnectsPerMinute int      `yaml:"maxConnectsPerMinute"` // New connections from one IP per minute, 0 for no limit
+}
+
+type LimitsConfig struct {
+	IdleTimeout int          `yaml:"idleTimeout"` // Minutes without a key press before hanging up, 0 for no limit
+	IdleWarning int          `yaml:"idleWarning"` // Seconds before hanging up to warn the caller
+	Levels      []LevelLimit `yaml:"levels"`      // Time limits by security level
+}
+
+type LevelLimit struct {
+	Level         int `yaml:"level"`         // Applies from this security level up to the next entry's
+	MinutesPerDay int `yaml:"minutesPerDay"` // Time online allowed each day, 0 for no limit
+	TimeBank      int `yaml:"timeBank"`      // Most minutes that can be saved in the time bank, 0 for none
+}
+
+// ForLevel returns the limits for a security level, from the entry with the highest level at or below it. There are
+// no limits if there's no such entry.
+func (c LimitsConfig) ForLevel(level int) LevelLimit {
+	var limit LevelLimit
+	found := false
+	for _, l := range c.Levels {
+		if l.Level <= level && (!found || l.Level > limit.Level) {
+			limit = l
+			found = true
+		}
+	}
+	return limit
+}
+
+// Daily returns the time allowed online each day, or 0 if there's no limit.
+func (l LevelLimit) Daily() time.Duration {
+	return time.Duration(l.MinutesPerDay) * time.Minute
 }
 
 type View struct {
 	Type        string                 `yaml:"type"`
+	ACS         string                 `yaml:"acs,omitempty"`    // Required to enter the view
 	Module      string                 `yaml:"module,omitempty"` // Name of the module to use
 	Ansi        string                 `yaml:"ansi,omitempty"`
+	Labels      map[string]string      `yaml:"labels,omitempty"` // Text drawn at MCI codes in the art, keyed by code (e.g. TL1)
 	HideCursor  bool                   `yaml:"hideCursor,omitempty"`
 	ClearScreen bool                   `yaml:"clearScreen,omitempty"`
 	Options     map[string]interface{} `yaml:"options,omitempty"`
-	Actions     map[string]string      `yaml:"actions,omitempty"`
-	Next        *NextView              `yaml:"next,omitempty"`
+	Actions     map[string]Action      `yaml:"actions,omitempty"`
+	Next        NextViews              `yaml:"next,omitempty"`
 	Prompt      string                 `yaml:"prompt,omitempty"`
 }
 
 type Prompt struct {
-	Ansi     string `yaml:"ansi"`
-	LineFeed bool   `yaml:"lineFeed"`
+	Type      string `yaml:"type"` // pause (the default), yesNo, choice, text, password or number
+	Ansi      string `yaml:"ansi"`
+	LineFeed  bool   `yaml:"lineFeed"`
+	Text      string `yaml:"text,omitempty"`      // Shown after the art, a template like art
+	Var       string `yaml:"var,omitempty"`       // Session variable the answer is stored in, or secret for passwords
+	Default   string `yaml:"default,omitempty"`   // Answer taken if the caller just presses Enter
+	Choices   string `yaml:"choices,omitempty"`   // Keys a choice prompt accepts, e.g. "ARQ"
+	MaxLength int    `yaml:"maxLength,omitempty"` // Longest answer for text and password prompts
+	Min       *int   `yaml:"min,omitempty"`       // Range for number prompts
+	Max       *int   `yaml:"max,omitempty"`
+}
+
+type Action struct {
+	View string `yaml:"view"`
+	ACS  string `yaml:"acs"`
+}
+
+// UnmarshalYAML implements custom unmarshaling for Action to handle both string and object formats
+func (a *Action) UnmarshalYAML(value *yaml.Node) error {
+	// Case 1: "1": "viewName"
+	if value.Kind == yaml.ScalarNode {
+		a.View = value.Value
+		return nil
+	}
+
+	// Case 2: "1": { view: "viewName", acs: "SL20" }
+	type plain Action
+	var tmp plain
+	if err := value.Decode(&tmp); err != nil {
+		return err
+	}
+
+	*a = Action(tmp)
+	return nil
 }
 
 type NextView struct {
 	View  string `yaml:"view"`
 	Delay int    `yaml:"delay"` // Delay in milliseconds
+	ACS   string `yaml:"acs"`   // Required for this branch to be taken
 }
 
 // UnmarshalYAML implements custom unmarshaling for NextView to handle both string and object formats
@@ -101,8 +225,31 @@ func (n *NextView) UnmarshalYAML(value *yaml.Node) error {
 		return err
 	}
 
-	n.View = tmp.View
-	n.Delay = tmp.Delay
+	*n = NextView(tmp)
+	return nil
+}
+
+// NextViews is a list of branches, the first one whose ACS passes is taken.
+type NextViews []NextView
+
+// UnmarshalYAML implements custom unmarshaling for NextViews so a single view can be given without a list
+func (n *NextViews) UnmarshalYAML(value *yaml.Node) error {
+	// Case 1: next: [ "viewName", { view: "viewName", acs: "GM[demo]" } ]
+	if value.Kind == yaml.SequenceNode {
+		var views []NextView
+		if err := value.Decode(&views); err != nil {
+			return err
+		}
+		*n = views
+		return nil
+	}
+
+	// Case 2: next: "viewName" or next: { view: "viewName", delay: 1000 }
+	var view NextView
+	if err := value.Decode(&view); err != nil {
+		return err
+	}
+	*n = NextViews{view}
 	return nil
 }
 
@@ -0,0 +1,243 @@
+package keys
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	esc = 0x1b
+
+	// Longest escape sequence we'll wait for. Anything longer is garbage, so the ESC is taken as a key of its own.
+	maxSequence = 32
+)
+
+// Decoder turns what the caller's terminal sends into key presses. It understands the escape sequences sent by
+// ANSI/VT100, xterm, Linux console and SyncTERM terminals, and the different ways clients send Enter: CR (SSH),
+// CR LF or CR NUL (Telnet), or a bare LF.
+//
+// Bytes that aren't valid UTF-8 are passed through as the character with that code, so callers with CP437 terminals
+// still get their keys.
+//
+// A lone ESC can't be told apart from the start of a sequence until more input arrives, so it's held along with any
+// incomplete sequence or character. If nothing follows within a short time, Flush it.
+type Decoder struct {
+	pending []byte
+	lastCR  bool
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{}
+}
+
+// Feed decodes input into keys, holding on to anything incomplete at the end.
+func (d *Decoder) Feed(input []byte) []Key {
+	data := append(d.pending, input...)
+	d.pending = nil
+
+	var keys []Key
+	for len(data) > 0 {
+		key, n, ok := d.decode(data)
+		if n == 0 {
+			d.pending = data
+			break
+		}
+		data = data[n:]
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Pending returns true if input is being held, waiting for the rest of an escape sequence or character.
+func (d *Decoder) Pending() bool {
+	return len(d.pending) > 0
+}
+
+// Flush gives up waiting for the rest of whatever is being held, and decodes it as it stands: a lone ESC becomes the
+// Escape key.
+func (d *Decoder) Flush() []Key {
+	var keys []Key
+	for len(d.pending) > 0 {
+		data := d.pending
+		d.pending = nil
+		if data[0] == esc {
+			keys = append(keys, Key{Code: Escape})
+		} else {
+			keys = append(keys, Char(rune(data[0])))
+		}
+		keys = append(keys, d.Feed(data[1:])...)
+	}
+	return keys
+}
+
+// decode reads a key from the start of data, returning how many bytes it used and whether there was a key to report
+// (line ending pairs and unknown sequences are swallowed). It uses nothing if data is incomplete.
+func (d *Decoder) decode(data []byte) (Key, int, bool) {
+	b := data[0]
+
+	// Enter is CR, CR LF, CR NUL or LF
+	lastCR := d.lastCR
+	d.lastCR = b == '\r'
+	switch b {
+	case '\r':
+		return Key{Code: Enter}, 1, true
+	case '\n':
+		return Key{Code: Enter}, 1, !lastCR
+	case 0:
+		return Key{}, 1, false
+	}
+
+	switch {
+	case b == esc:
+		return decodeEscape(data)
+	case b == 0x08 || b == 0x7f:
+		// Terminals disagree on which of these backspace sends. SyncTERM sends DEL for the delete key, but most
+		// terminals send it for backspace, so that's what it's taken as.
+		return Key{Code: Backspace}, 1, true
+	case b == '\t':
+		return Key{Code: Tab}, 1, true
+	case b < 0x20:
+		return Control(rune('@' + b)), 1, true
+	case b < utf8.RuneSelf:
+		return Char(rune(b)), 1, true
+	}
+
+	if !utf8.FullRune(data) {
+		return Key{}, 0, false
+	}
+	r, size := utf8.DecodeRune(data)
+	if r == utf8.RuneError && size == 1 {
+		return Char(rune(b)), 1, true
+	}
+	return Char(r), size, true
+}
+
+// decodeEscape reads an escape sequence from the start of data. ESC followed by anything other than a CSI or SS3
+// sequence is the Escape key, with what follows decoded separately.
+func decodeEscape(data []byte) (Key, int, bool) {
+	if len(data) < 2 {
+		return Key{}, 0, false
+	}
+
+	switch data[1] {
+	case 'O':
+		if len(data) < 3 {
+			return Key{}, 0, false
+		}
+		key, ok := ss3Keys[data[2]]
+		return key, 3, ok
+
+	case '[':
+		// Linux console function keys: ESC [ [ A to ESC [ [ E
+		if len(data) >= 3 && data[2] == '[' {
+			if len(data) < 4 {
+				return Key{}, 0, false
+			}
+			if data[3] >= 'A' && data[3] <= 'E' {
+				return Key{Code: F1 + Code(data[3]-'A')}, 4, true
+			}
+			return Key{}, 4, false
+		}
+
+		// Parameter and intermediate bytes, then the final byte
+		for i := 2; i < len(data) && i < maxSequence; i++ {
+			c := data[i]
+			if c >= 0x40 && c <= 0x7e {
+				key, ok := csiKey(string(data[2:i]), c)
+				return key, i + 1, ok
+			}
+			if c < 0x20 || c > 0x3f {
+				// Not a valid sequence
+				return Key{Code: Escape}, 1, true
+			}
+		}
+		if len(data) >= maxSequence {
+			return Key{Code: Escape}, 1, true
+		}
+		return Key{}, 0, false
+	}
+
+	return Key{Code: Escape}, 1, true
+}
+
+// SS3 sequences: ESC O <final>
+var ss3Keys = map[byte]Key{
+	'A': {Code: Up},
+	'B': {Code: Down},
+	'C': {Code: Right},
+	'D': {Code: Left},
+	'H': {Code: Home},
+	'F': {Code: End},
+	'M': {Code: Enter}, // Keypad enter
+	'P': {Code: F1},
+	'Q': {Code: F2},
+	'R': {Code: F3},
+	'S': {Code: F4},
+	't': {Code: F5}, // SyncTERM
+}
+
+// CSI sequences ending in a letter: ESC [ <params> <final>. Modifiers in the parameters (e.g. xterm's ESC [ 1;5A for
+// ctrl-up) are ignored.
+var csiFinalKeys = map[byte]Key{
+	'A': {Code: Up},
+	'B': {Code: Down},
+	'C': {Code: Right},
+	'D': {Code: Left},
+	'H': {Code: Home},
+	'F': {Code: End},
+	'Z': {Code: BackTab},
+	'P': {Code: F1},
+	'Q': {Code: F2},
+	'S': {Code: F4},
+	'K': {Code: End},      // SyncTERM
+	'@': {Code: Insert},   // SyncTERM
+	'V': {Code: PageUp},   // SyncTERM
+	'U': {Code: PageDown}, // SyncTERM
+}
+
+// CSI sequences ending in a tilde (VT220 style): ESC [ <code> ~
+var csiTildeKeys = map[int]Key{
+	1:  {Code: Home},
+	2:  {Code: Insert},
+	3:  {Code: Delete},
+	4:  {Code: End},
+	5:  {Code: PageUp},
+	6:  {Code: PageDown},
+	7:  {Code: Home},
+	8:  {Code: End},
+	11: {Code: F1},
+	12: {Code: F2},
+	13: {Code: F3},
+	14: {Code: F4},
+	15: {Code: F5},
+	17: {Code: F6},
+	18: {Code: F7},
+	19: {Code: F8},
+	20: {Code: F9},
+	21: {Code: F10},
+	23: {Code: F11},
+	24: {Code: F12},
+}
+
+func csiKey(params string, final byte) (Key, bool) {
+	if final == '~' {
+		code, _, _ := strings.Cut(params, ";")
+		n, err := strconv.Atoi(code)
+		if err != nil {
+			return Key{}, false
+		}
+		key, ok := csiTildeKeys[n]
+		return key, ok
+	}
+
+	// F3 would be ESC [ R, but that's a cursor position report when it has parameters
+	if final == 'R' {
+		return Key{Code: F3}, params == ""
+	}
+
+	key, ok := csiFinalKeys[final]
+	return key, ok
+}
@@ -0,0 +1,103 @@
+package keys
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code identifies what kind of key was pressed.
+type Code int
+
+const (
+	Rune Code = iota // A printable character, see Key.Rune
+	Ctrl             // A control character, Key.Rune holds the letter (e.g. 'U' for ^U)
+	Enter
+	Tab
+	BackTab
+	Backspace
+	Delete
+	Escape
+	Up
+	Down
+	Right
+	Left
+	Home
+	End
+	Insert
+	PageUp
+	PageDown
+	F1
+	F2
+	F3
+	F4
+	F5
+	F6
+	F7
+	F8
+	F9
+	F10
+	F11
+	F12
+)
+
+var codeNames = map[Code]string{
+	Enter:     "Enter",
+	Tab:       "Tab",
+	BackTab:   "BackTab",
+	Backspace: "Backspace",
+	Delete:    "Delete",
+	Escape:    "Escape",
+	Up:        "Up",
+	Down:      "Down",
+	Right:     "Right",
+	Left:      "Left",
+	Home:      "Home",
+	End:       "End",
+	Insert:    "Insert",
+	PageUp:    "PageUp",
+	PageDown:  "PageDown",
+}
+
+// Key is a single key press.
+type Key struct {
+	Code Code
+	Rune rune
+}
+
+// Char returns the key for a printable character.
+func Char(r rune) Key {
+	return Key{Code: Rune, Rune: r}
+}
+
+// Control returns the key for a control character, e.g. Control('U') for ^U.
+func Control(letter rune) Key {
+	return Key{Code: Ctrl, Rune: letter}
+}
+
+// String returns the character for printable keys, ^ and the letter for control keys, and the name of anything else
+// (e.g. "Enter", "Up" or "F1"). Views use it to match keys to actions.
+func (k Key) String() string {
+	switch {
+	case k.Code == Rune:
+		return string(k.Rune)
+	case k.Code == Ctrl:
+		return "^" + string(k.Rune)
+	case k.Code >= F1 && k.Code <= F12:
+		return fmt.Sprintf("F%d", k.Code-F1+1)
+	}
+	if name, ok := codeNames[k.Code]; ok {
+		return name
+	}
+	return fmt.Sprintf("Key(%d)", k.Code)
+}
+
+// Text returns the printable characters typed, ignoring every other key.
+func Text(keys []Key) string {
+	var sb strings.Builder
+	for _, k := range keys {
+		if k.Code == Rune {
+			sb.WriteRune(k.Rune)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,122 @@
+package keys_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/keys"
+)
+
+var _ = Describe("Decoder", func() {
+	var decoder *keys.Decoder
+
+	BeforeEach(func() {
+		decoder = keys.NewDecoder()
+	})
+
+	feed := func(input string) []keys.Key {
+		return decoder.Feed([]byte(input))
+	}
+
+	key := func(code keys.Code) keys.Key {
+		return keys.Key{Code: code}
+	}
+
+	It("decodes characters, including UTF-8", func() {
+		Expect(feed("aé")).To(Equal([]keys.Key{keys.Char('a'), keys.Char('é')}))
+	})
+
+	It("passes through bytes that aren't UTF-8", func() {
+		Expect(feed("\x82x")).To(Equal([]keys.Key{keys.Char(0x82), keys.Char('x')}))
+	})
+
+	It("waits for the rest of a UTF-8 character", func() {
+		Expect(feed("\xc3")).To(BeEmpty())
+		Expect(decoder.Pending()).To(BeTrue())
+		Expect(feed("\xa9")).To(Equal([]keys.Key{keys.Char('é')}))
+	})
+
+	DescribeTable("line endings",
+		func(input string, enters int) {
+			Expect(feed(input)).To(HaveLen(enters))
+		},
+		Entry("CR", "\r", 1),
+		Entry("CR LF", "\r\n", 1),
+		Entry("CR NUL", "\r\x00", 1),
+		Entry("LF", "\n", 1),
+		Entry("LF LF", "\n\n", 2),
+		Entry("CR CR", "\r\r", 2),
+	)
+
+	It("pairs a CR and LF split across reads", func() {
+		Expect(feed("\r")).To(Equal([]keys.Key{key(keys.Enter)}))
+		Expect(feed("\n")).To(BeEmpty())
+	})
+
+	It("decodes control characters", func() {
+		Expect(feed("\x15\x08\x7f\t")).To(Equal([]keys.Key{
+			keys.Control('U'), key(keys.Backspace), key(keys.Backspace), key(keys.Tab),
+		}))
+	})
+
+	DescribeTable("escape sequences",
+		func(input string, code keys.Code) {
+			Expect(feed(input)).To(Equal([]keys.Key{key(code)}))
+		},
+		Entry("ANSI up", "\x1b[A", keys.Up),
+		Entry("VT100 application left", "\x1bOD", keys.Left),
+		Entry("xterm ctrl-right", "\x1b[1;5C", keys.Right),
+		Entry("xterm home", "\x1b[H", keys.Home),
+		Entry("VT220 end", "\x1b[4~", keys.End),
+		Entry("VT220 delete", "\x1b[3~", keys.Delete),
+		Entry("VT220 page down", "\x1b[6~", keys.PageDown),
+		Entry("VT100 F1", "\x1bOP", keys.F1),
+		Entry("xterm F5", "\x1b[15~", keys.F5),
+		Entry("xterm F12", "\x1b[24~", keys.F12),
+		Entry("Linux console F3", "\x1b[[C", keys.F3),
+		Entry("SyncTERM end", "\x1b[K", keys.End),
+		Entry("SyncTERM insert", "\x1b[@", keys.Insert),
+		Entry("SyncTERM page up", "\x1b[V", keys.PageUp),
+		Entry("shift-tab", "\x1b[Z", keys.BackTab),
+	)
+
+	It("swallows unknown sequences and cursor position reports", func() {
+		Expect(feed("\x1b[12;40Rx\x1b[99~")).To(Equal([]keys.Key{keys.Char('x')}))
+	})
+
+	It("waits for the rest of a sequence split across reads", func() {
+		Expect(feed("a\x1b")).To(Equal([]keys.Key{keys.Char('a')}))
+		Expect(feed("[")).To(BeEmpty())
+		Expect(feed("B")).To(Equal([]keys.Key{key(keys.Down)}))
+		Expect(decoder.Pending()).To(BeFalse())
+	})
+
+	It("flushes a lone ESC as the Escape key", func() {
+		Expect(feed("\x1b")).To(BeEmpty())
+		Expect(decoder.Pending()).To(BeTrue())
+		Expect(decoder.Flush()).To(Equal([]keys.Key{key(keys.Escape)}))
+		Expect(decoder.Pending()).To(BeFalse())
+	})
+
+	It("flushes an incomplete sequence as the keys typed", func() {
+		feed("\x1b[")
+		Expect(decoder.Flush()).To(Equal([]keys.Key{key(keys.Escape), keys.Char('[')}))
+	})
+
+	It("takes ESC followed by something else as the Escape key", func() {
+		Expect(feed("\x1bq")).To(Equal([]keys.Key{key(keys.Escape), keys.Char('q')}))
+	})
+})
+
+var _ = Describe("Key", func() {
+	It("names keys", func() {
+		Expect(keys.Char('Q').String()).To(Equal("Q"))
+		Expect(keys.Control('C').String()).To(Equal("^C"))
+		Expect(keys.Key{Code: keys.Enter}.String()).To(Equal("Enter"))
+		Expect(keys.Key{Code: keys.F10}.String()).To(Equal("F10"))
+	})
+
+	It("returns the text typed", func() {
+		Expect(keys.Text([]keys.Key{keys.Char('h'), {Code: keys.Left}, keys.Char('i')})).To(Equal("hi"))
+	})
+})
@@ -0,0 +1,227 @@
+package lineedit
+
+import (
+	"io"
+	"strings"
+
+	"euphio/internal/keys"
+)
+
+// Options control how a line is edited.
+type Options struct {
+	MaxLength int      // Longest line that can be typed, 0 for no limit
+	Masked    bool     // Echo the mask character instead of what's typed (e.g. for passwords)
+	Mask      rune     // Defaults to '*'
+	History   *History // Lines to recall with the up and down arrows, nil for none. Never used when masked.
+	Inline    bool     // Leave the cursor where it is at Enter, rather than starting a new line (e.g. for form fields)
+}
+
+// Editor is a line discipline: it echoes what the caller types, handles the usual editing keys and history, and hands
+// back whole lines. Supported keys are backspace and delete, ^U (erase line), ^W (erase word), ^A/^E and Home/End,
+// the left and right arrows to move within the line, and the up and down arrows to recall history.
+type Editor struct {
+	opts Options
+
+	buf []rune
+	pos int // Cursor position within buf
+
+	histPos int    // Position while browsing history, History.Len() when not browsing
+	saved   []rune // The line being typed before browsing history
+
+	clientEcho bool
+}
+
+func New(opts Options) *Editor {
+	if opts.Mask == 0 {
+		opts.Mask = '*'
+	}
+	e := &Editor{opts: opts}
+	e.resetHistory()
+	return e
+}
+
+// SetMasked switches masking on or off, e.g. when moving on to a password.
+func (e *Editor) SetMasked(masked bool) {
+	e.opts.Masked = masked
+}
+
+// SetClientEcho tells the editor the client is echoing (and editing) lines itself, as with Telnet LINEMODE, so
+// nothing should be echoed back.
+func (e *Editor) SetClientEcho(clientEcho bool) {
+	e.clientEcho = clientEcho
+}
+
+// Line returns what has been typed so far.
+func (e *Editor) Line() string {
+	return string(e.buf)
+}
+
+// SetLine replaces what has been typed so far, e.g. to pre-fill a default.
+func (e *Editor) SetLine(w io.Writer, line string) {
+	e.replace(w, []rune(line))
+}
+
+// Feed processes typed keys, echoing them to w. Once a line is completed it's returned along with any keys that follow
+// it, which should be fed again once the line has been dealt with.
+func (e *Editor) Feed(w io.Writer, input []keys.Key) (line string, done bool, rest []keys.Key) {
+	for i, k := range input {
+		switch k {
+		case keys.Control('U'):
+			e.replace(w, nil)
+			continue
+		case keys.Control('W'):
+			e.eraseWord(w)
+			continue
+		case keys.Control('A'):
+			e.moveTo(w, 0)
+			continue
+		case keys.Control('E'):
+			e.moveTo(w, len(e.buf))
+			continue
+		}
+
+		switch k.Code {
+		case keys.Enter:
+			line = string(e.buf)
+			e.buf, e.pos = nil, 0
+			if !e.opts.Inline {
+				e.echo(w, "\r\n")
+			}
+			if e.opts.History != nil && !e.opts.Masked {
+				e.opts.History.Add(line)
+			}
+			e.resetHistory()
+			return line, true, input[i+1:]
+		case keys.Rune:
+			e.insert(w, k.Rune)
+		case keys.Backspace:
+			e.backspace(w)
+		case keys.Delete:
+			if e.pos < len(e.buf) {
+				e.moveTo(w, e.pos+1)
+				e.backspace(w)
+			}
+		case keys.Up:
+			e.recall(w, -1)
+		case keys.Down:
+			e.recall(w, 1)
+		case keys.Left:
+			e.moveTo(w, e.pos-1)
+		case keys.Right:
+			e.moveTo(w, e.pos+1)
+		case keys.Home:
+			e.moveTo(w, 0)
+		case keys.End:
+			e.moveTo(w, len(e.buf))
+		}
+	}
+	return "", false, nil
+}
+
+func (e *Editor) insert(w io.Writer, r rune) {
+	if e.opts.MaxLength > 0 && len(e.buf) >= e.opts.MaxLength {
+		return
+	}
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.pos+1:], e.buf[e.pos:])
+	e.buf[e.pos] = r
+	e.pos++
+
+	tail := e.display(e.buf[e.pos:])
+	e.echo(w, e.display([]rune{r})+tail+strings.Repeat("\b", len([]rune(tail))))
+}
+
+func (e *Editor) backspace(w io.Writer) {
+	if e.pos == 0 {
+		return
+	}
+	e.buf = append(e.buf[:e.pos-1], e.buf[e.pos:]...)
+	e.pos--
+
+	tail := e.display(e.buf[e.pos:])
+	e.echo(w, "\b"+tail+" "+strings.Repeat("\b", len([]rune(tail))+1))
+}
+
+// eraseWord erases back to the start of the word before the cursor, along with any spaces after it.
+func (e *Editor) eraseWord(w io.Writer) {
+	start := e.pos
+	for start > 0 && e.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	for e.pos > start {
+		e.backspace(w)
+	}
+}
+
+func (e *Editor) moveTo(w io.Writer, pos int) {
+	pos = max(0, min(pos, len(e.buf)))
+	if pos < e.pos {
+		e.echo(w, strings.Repeat("\b", e.pos-pos))
+	} else if pos > e.pos {
+		e.echo(w, e.display(e.buf[e.pos:pos]))
+	}
+	e.pos = pos
+}
+
+// replace erases the line and types in another.
+func (e *Editor) replace(w io.Writer, line []rune) {
+	if e.opts.MaxLength > 0 && len(line) > e.opts.MaxLength {
+		line = line[:e.opts.MaxLength]
+	}
+	e.moveTo(w, len(e.buf))
+	n := len(e.buf)
+	e.echo(w, strings.Repeat("\b \b", n))
+
+	e.buf = append([]rune(nil), line...)
+	e.pos = len(e.buf)
+	e.echo(w, e.display(e.buf))
+}
+
+// recall moves through the history, keeping hold of the line being typed so it can be returned to.
+func (e *Editor) recall(w io.Writer, step int) {
+	h := e.opts.History
+	if h == nil || e.opts.Masked {
+		return
+	}
+
+	pos := e.histPos + step
+	if pos < 0 || pos > h.Len() {
+		return
+	}
+	if e.histPos == h.Len() {
+		e.saved = append([]rune(nil), e.buf...)
+	}
+	e.histPos = pos
+
+	if pos == h.Len() {
+		e.replace(w, e.saved)
+	} else {
+		e.replace(w, []rune(h.At(pos)))
+	}
+}
+
+func (e *Editor) resetHistory() {
+	e.saved = nil
+	e.histPos = 0
+	if e.opts.History != nil {
+		e.histPos = e.opts.History.Len()
+	}
+}
+
+// display returns what's shown for the runes, taking masking into account.
+func (e *Editor) display(runes []rune) string {
+	if e.opts.Masked {
+		return strings.Repeat(string(e.opts.Mask), len(runes))
+	}
+	return string(runes)
+}
+
+func (e *Editor) echo(w io.Writer, s string) {
+	if e.clientEcho || s == "" {
+		return
+	}
+	io.WriteString(w, s)
+}
@@ -0,0 +1,31 @@
+package lineedit
+
+// History holds previously entered lines, oldest first.
+type History struct {
+	lines []string
+	max   int
+}
+
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// Add records a line. Blank lines and repeats of the previous line aren't recorded.
+func (h *History) Add(line string) {
+	if line == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == line) {
+		return
+	}
+	h.lines = append(h.lines, line)
+	if h.max > 0 && len(h.lines) > h.max {
+		h.lines = h.lines[len(h.lines)-h.max:]
+	}
+}
+
+func (h *History) Len() int {
+	return len(h.lines)
+}
+
+// At returns the line at the index, 0 being the oldest.
+func (h *History) At(i int) string {
+	return h.lines[i]
+}
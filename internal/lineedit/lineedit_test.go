@@ -0,0 +1,179 @@
+package lineedit_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
+)
+
+var _ = Describe("Editor", func() {
+	var (
+		out     *bytes.Buffer
+		editor  *lineedit.Editor
+		history *lineedit.History
+		decoder *keys.Decoder
+	)
+
+	BeforeEach(func() {
+		out = &bytes.Buffer{}
+		decoder = keys.NewDecoder()
+		history = lineedit.NewHistory(10)
+		editor = lineedit.New(lineedit.Options{MaxLength: 10, History: history})
+	})
+
+	// feed decodes the input as the session would, returning what's left as text
+	feed := func(input string) (string, bool, string) {
+		line, done, rest := editor.Feed(out, decoder.Feed([]byte(input)))
+		return line, done, keys.Text(rest)
+	}
+
+	It("echoes input and returns the line on enter", func() {
+		line, done, rest := feed("hello\r\n")
+		Expect(done).To(BeTrue())
+		Expect(line).To(Equal("hello"))
+		Expect(rest).To(BeEmpty())
+		Expect(out.String()).To(Equal("hello\r\n"))
+	})
+
+	It("stays on the line at enter when inline", func() {
+		editor = lineedit.New(lineedit.Options{Inline: true})
+		line, done, _ := feed("hello\r\n")
+		Expect(done).To(BeTrue())
+		Expect(line).To(Equal("hello"))
+		Expect(out.String()).To(Equal("hello"))
+	})
+
+	It("keeps partial lines between feeds", func() {
+		_, done, _ := feed("hel")
+		Expect(done).To(BeFalse())
+		Expect(editor.Line()).To(Equal("hel"))
+
+		line, done, _ := feed("lo\r")
+		Expect(done).To(BeTrue())
+		Expect(line).To(Equal("hello"))
+	})
+
+	It("treats CR LF as one line ending", func() {
+		_, _, rest := feed("one\r\ntwo\r\n")
+		Expect(rest).To(Equal("two"))
+	})
+
+	It("returns the input after the line", func() {
+		line, _, rest := feed("who\r\nhelp\r\n")
+		Expect(line).To(Equal("who"))
+		Expect(rest).To(Equal("help"))
+	})
+
+	It("handles backspace and DEL", func() {
+		line, _, _ := feed("abcd\b\x7fe\r")
+		Expect(line).To(Equal("abe"))
+		Expect(out.String()).To(Equal("abcd\b \b\b \be\r\n"))
+	})
+
+	It("erases the line with ^U", func() {
+		line, _, _ := feed("abc\x15xy\r")
+		Expect(line).To(Equal("xy"))
+	})
+
+	It("erases the previous word with ^W", func() {
+		line, _, _ := feed("say hi  \x17there\r")
+		Expect(line).To(Equal("say there"))
+	})
+
+	It("enforces the maximum length", func() {
+		line, _, _ := feed("0123456789abc\r")
+		Expect(line).To(Equal("0123456789"))
+	})
+
+	It("ignores other keys", func() {
+		line, _, _ := feed("a\x07\t\x1b[Pb\r")
+		Expect(line).To(Equal("ab"))
+	})
+
+	It("moves within the line with the arrow keys and inserts at the cursor", func() {
+		line, _, _ := feed("ac\x1b[Db\x1b[C!\r")
+		Expect(line).To(Equal("abc!"))
+	})
+
+	It("handles Home, End and Delete", func() {
+		line, _, _ := feed("bc\x1b[Ha\x1b[Fd\x1b[H\x1b[3~\r")
+		Expect(line).To(Equal("bcd"))
+	})
+
+	Describe("history", func() {
+		BeforeEach(func() {
+			feed("first\r")
+			feed("second\r")
+		})
+
+		It("records lines", func() {
+			Expect(history.Len()).To(Equal(2))
+			Expect(history.At(0)).To(Equal("first"))
+		})
+
+		It("recalls lines with the arrow keys", func() {
+			line, _, _ := feed("\x1b[A\x1b[A\r")
+			Expect(line).To(Equal("first"))
+
+			line, _, _ = feed("\x1bOA\r")
+			Expect(line).To(Equal("first"))
+		})
+
+		It("returns to the line being typed", func() {
+			line, _, _ := feed("new\x1b[A\x1b[B\r")
+			Expect(line).To(Equal("new"))
+		})
+
+		It("doesn't record blank or repeated lines", func() {
+			feed("\r")
+			feed("second\r")
+			Expect(history.Len()).To(Equal(2))
+		})
+
+		It("drops the oldest lines", func() {
+			h := lineedit.NewHistory(2)
+			h.Add("a")
+			h.Add("b")
+			h.Add("c")
+			Expect(h.Len()).To(Equal(2))
+			Expect(h.At(0)).To(Equal("b"))
+		})
+	})
+
+	Describe("masked", func() {
+		BeforeEach(func() {
+			editor.SetMasked(true)
+		})
+
+		It("echoes the mask", func() {
+			line, _, _ := feed("pw\b!\r")
+			Expect(line).To(Equal("p!"))
+			Expect(out.String()).To(Equal("**\b \b*\r\n"))
+		})
+
+		It("keeps lines out of the history", func() {
+			feed("secret\r")
+			Expect(history.Len()).To(BeZero())
+			line, _, _ := feed("\x1b[A\r")
+			Expect(line).To(BeEmpty())
+		})
+	})
+
+	It("doesn't echo when the client does", func() {
+		editor.SetClientEcho(true)
+		line, _, _ := feed("abc\r\n")
+		Expect(line).To(Equal("abc"))
+		Expect(out.String()).To(BeEmpty())
+	})
+
+	It("pre-fills a line", func() {
+		editor.SetLine(out, "bob")
+		line, _, _ := feed("by\r")
+		Expect(line).To(Equal("bobby"))
+		Expect(out.String()).To(Equal("bobby\r\n"))
+	})
+})
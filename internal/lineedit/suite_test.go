@@ -0,0 +1,13 @@
+package lineedit_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLineEdit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Line Editor Suite")
+}
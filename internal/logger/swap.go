@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// SwapHandler passes records on to a handler that can be replaced while loggers are using it, e.g. when the config is
+// reloaded. Loggers derived with With and WithGroup follow the replacement too.
+type SwapHandler struct {
+	current *atomic.Pointer[slog.Handler]
+	derive  []func(slog.Handler) slog.Handler // Applied to the current handler, for derived loggers
+}
+
+func NewSwap(handler slog.Handler) *SwapHandler {
+	h := &SwapHandler{current: &atomic.Pointer[slog.Handler]{}}
+	h.Swap(handler)
+	return h
+}
+
+// Swap replaces the handler, for this handler and every one derived from it.
+func (h *SwapHandler) Swap(handler slog.Handler) {
+	h.current.Store(&handler)
+}
+
+func (h *SwapHandler) handler() slog.Handler {
+	handler := *h.current.Load()
+	for _, derive := range h.derive {
+		handler = derive(handler)
+	}
+	return handler
+}
+
+func (h *SwapHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return (*h.current.Load()).Enabled(ctx, level)
+}
+
+func (h *SwapHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h *SwapHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *SwapHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *SwapHandler) with(derive func(slog.Handler) slog.Handler) *SwapHandler {
+	return &SwapHandler{
+		current: h.current,
+		derive:  append(append([]func(slog.Handler) slog.Handler{}, h.derive...), derive),
+	}
+}
@@ -0,0 +1,29 @@
+package logger_test
+
+import (
+	"bytes"
+	"log/slog"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/logger"
+)
+
+var _ = Describe("SwapHandler", func() {
+	It("sends records to the handler swapped in, from derived loggers too", func() {
+		var before, after bytes.Buffer
+		handler := logger.NewSwap(slog.NewTextHandler(&before, nil))
+		log := slog.New(handler)
+		nodeLog := log.With("node", 1).WithGroup("telnet")
+
+		log.Info("first")
+		handler.Swap(slog.NewTextHandler(&after, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		log.Info("hidden")
+		nodeLog.Warn("second", "opt", "ECHO")
+
+		Expect(before.String()).To(ContainSubstring("msg=first"))
+		Expect(after.String()).NotTo(ContainSubstring("hidden"))
+		Expect(after.String()).To(ContainSubstring("msg=second node=1 telnet.opt=ECHO"))
+	})
+})
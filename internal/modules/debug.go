@@ -23,8 +23,14 @@ func (m *DebugModule) HandleCommand(w io.Writer, node *nodes.Node, cmd string, a
 		io.WriteString(w, "Debug commands: help, info, time, whoami, yell <msg>, box, tui\r\n")
 		return true, nil
 	case "info":
-		info := node.Conn.GetTerminalInfo()
+		info := node.TerminalInfo()
 		fmt.Fprintf(w, "Terminal: %s (%dx%d)\r\n", info.Type, info.Width, info.Height)
+		if info.Probed {
+			fmt.Fprintf(w, "ANSI: %t, UTF-8: %t\r\n", info.ANSI, info.UTF8)
+			if info.CTerm != "" {
+				fmt.Fprintf(w, "CTerm: %s\r\n", info.CTerm)
+			}
+		}
 		return true, nil
 	case "whoami":
 		username := "guest"
@@ -60,7 +66,7 @@ func (m *DebugModule) HandleCommand(w io.Writer, node *nodes.Node, cmd string, a
 
 		// Default to ASCII for safety in BBS context, unless we know it's a modern terminal
 		border := asciiBorder
-		if node.Conn.IsUTF8() {
+		if node.IsUTF8() {
 			border = lipgloss.RoundedBorder()
 		}
 
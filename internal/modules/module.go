@@ -2,7 +2,11 @@ package modules
 
 import (
 	"io"
+	"slices"
+	"strings"
 
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
 	"euphio/internal/nodes"
 )
 
@@ -20,6 +24,34 @@ type CommandHandler interface {
 	HandleCommand(w io.Writer, node *nodes.Node, cmd string, args string) (bool, error)
 }
 
+// LineEditor is an optional interface for command modules that want their command line edited differently, e.g. to
+// allow longer lines. A nil History is replaced with the caller's command history.
+type LineEditor interface {
+	CommandHandler
+	LineOptions() lineedit.Options
+}
+
+// KeyHandler is an optional interface for modules that want each key as it's pressed, rather than whole command lines.
+// Returns true if the key was handled, false to let the view match it to an action.
+type KeyHandler interface {
+	Module
+	HandleKey(w io.Writer, node *nodes.Node, key keys.Key) (bool, error)
+}
+
+// GMCPHandler is an optional interface for modules that react to GMCP messages from the caller's client. Every
+// message is offered to every module; use node.SendGMCP to reply.
+type GMCPHandler interface {
+	Module
+	HandleGMCP(w io.Writer, node *nodes.Node, pkg string, data []byte) error
+}
+
+// LogoffHandler is an optional interface for modules that need to clean up when a session ends, however it ended.
+// The connection may already be gone, and node.User is nil if the caller never logged in.
+type LogoffHandler interface {
+	Module
+	Logoff(node *nodes.Node)
+}
+
 // Registry holds all available modules.
 type Registry struct {
 	modules map[string]Module
@@ -38,3 +70,15 @@ func (r *Registry) Register(m Module) {
 func (r *Registry) Get(name string) Module {
 	return r.modules[name]
 }
+
+// All returns every registered module, ordered by name.
+func (r *Registry) All() []Module {
+	all := make([]Module, 0, len(r.modules))
+	for _, m := range r.modules {
+		all = append(all, m)
+	}
+	slices.SortFunc(all, func(a, b Module) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return all
+}
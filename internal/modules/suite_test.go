@@ -0,0 +1,20 @@
+package modules_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+)
+
+func TestModules(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	RunSpecs(t, "Modules Suite")
+}
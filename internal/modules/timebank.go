@@ -0,0 +1,90 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+)
+
+// TimeBankModule lets callers save unused time online for another day, and take it back out. How much they can save
+// depends on their security level (see the limits config). Use it from a view with `module: timebank`.
+type TimeBankModule struct{}
+
+func (m *TimeBankModule) Name() string {
+	return "timebank"
+}
+
+func (m *TimeBankModule) HandleCommand(w io.Writer, node *nodes.Node, cmd string, args string) (bool, error) {
+	switch cmd {
+	case "help":
+		io.WriteString(w, "Time bank commands: balance, deposit <minutes>, withdraw <minutes>\r\n")
+		return true, nil
+	case "balance", "deposit", "withdraw":
+	default:
+		return false, nil
+	}
+
+	user := node.User
+	if user == nil {
+		io.WriteString(w, "You need to log in to use the time bank.\r\n")
+		return true, nil
+	}
+	limit := app.Config().Limits.ForLevel(user.SecurityLevel)
+	if limit.Daily() <= 0 || limit.TimeBank <= 0 {
+		io.WriteString(w, "The time bank isn't available to you.\r\n")
+		return true, nil
+	}
+
+	now := time.Now()
+	left := int(max(user.TimeLeft(limit.Daily(), now), 0) / time.Minute)
+
+	var minutes int
+	if cmd != "balance" {
+		n, err := strconv.Atoi(args)
+		if err != nil || n <= 0 {
+			fmt.Fprintf(w, "Usage: %s <minutes>\r\n", cmd)
+			return true, nil
+		}
+		minutes = n
+	}
+
+	switch cmd {
+	case "deposit":
+		switch {
+		case minutes > left:
+			fmt.Fprintf(w, "You only have %d minute(s) left today.\r\n", left)
+			return true, nil
+		case user.TimeBank+minutes > limit.TimeBank:
+			fmt.Fprintf(w, "The time bank can only hold %d minute(s) for you.\r\n", limit.TimeBank)
+			return true, nil
+		}
+	case "withdraw":
+		minutes = -minutes
+	}
+
+	if minutes != 0 {
+		err := app.Store.TransferTimeBank(user.Username, minutes, now)
+		if errors.Is(err, store.ErrTimeBankShort) {
+			fmt.Fprintf(w, "You only have %d minute(s) in the time bank.\r\n", user.TimeBank)
+			return true, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		app.Logger.Info("Time bank transfer", "node", node.ID, "user", user.Username, "minutes", minutes)
+
+		// Keep the session's copy of the user in step
+		user.TimeBank += minutes
+		user.UseTime(time.Duration(minutes)*time.Minute, now)
+		left -= minutes
+	}
+
+	fmt.Fprintf(w, "You have %d minute(s) in the time bank (of %d), and %d minute(s) left today.\r\n", user.TimeBank, limit.TimeBank, left)
+	return true, nil
+}
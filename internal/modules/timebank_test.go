@@ -0,0 +1,104 @@
+package modules_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+)
+
+var _ = Describe("TimeBankModule", func() {
+	var (
+		module *modules.TimeBankModule
+		node   *nodes.Node
+		out    bytes.Buffer
+	)
+
+	// command runs a time bank command, returning what it said
+	command := func(cmd, args string) string {
+		out.Reset()
+		handled, err := module.HandleCommand(&out, node, cmd, args)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handled).To(BeTrue())
+		return out.String()
+	}
+
+	stored := func() *store.User {
+		user, err := app.Store.FindUserByUsername("bob")
+		Expect(err).NotTo(HaveOccurred())
+		return user
+	}
+
+	BeforeEach(func() {
+		app.SetConfig(&config.Config{Limits: config.LimitsConfig{Levels: []config.LevelLimit{
+			{Level: 0, MinutesPerDay: 60, TimeBank: 30},
+			{Level: 90},
+		}}})
+		db, err := store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		app.Store = db
+		Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+
+		module = &modules.TimeBankModule{}
+		node = &nodes.Node{ID: 1}
+		node.SetUser(stored())
+	})
+
+	It("shows the balance", func() {
+		Expect(command("balance", "")).To(Equal("You have 0 minute(s) in the time bank (of 30), and 60 minute(s) left today.\r\n"))
+	})
+
+	It("deposits time, taking it from today", func() {
+		Expect(command("deposit", "20")).To(ContainSubstring("20 minute(s) in the time bank (of 30), and 40 minute(s) left today"))
+		Expect(stored().TimeBank).To(Equal(20))
+		Expect(stored().TimeLeft(time.Hour, time.Now())).To(Equal(40 * time.Minute))
+		Expect(node.User.TimeBank).To(Equal(20))
+	})
+
+	It("withdraws time, adding it to today", func() {
+		command("deposit", "20")
+		Expect(command("withdraw", "5")).To(ContainSubstring("15 minute(s) in the time bank (of 30), and 45 minute(s) left today"))
+		Expect(stored().TimeBank).To(Equal(15))
+	})
+
+	It("refuses to deposit more than is left today, or the bank holds", func() {
+		node.User.UseTime(50*time.Minute, time.Now())
+		Expect(command("deposit", "11")).To(Equal("You only have 10 minute(s) left today.\r\n"))
+
+		node.User.UseTime(-50*time.Minute, time.Now())
+		Expect(command("deposit", "31")).To(Equal("The time bank can only hold 30 minute(s) for you.\r\n"))
+		Expect(stored().TimeBank).To(BeZero())
+	})
+
+	It("refuses to withdraw more than the bank holds", func() {
+		command("deposit", "5")
+		Expect(command("withdraw", "6")).To(Equal("You only have 5 minute(s) in the time bank.\r\n"))
+		Expect(stored().TimeBank).To(Equal(5))
+	})
+
+	It("asks for a number of minutes", func() {
+		Expect(command("deposit", "")).To(Equal("Usage: deposit <minutes>\r\n"))
+		Expect(command("withdraw", "-5")).To(Equal("Usage: withdraw <minutes>\r\n"))
+	})
+
+	It("is only for callers with a time limit and a time bank", func() {
+		node.User.SecurityLevel = 90
+		Expect(command("balance", "")).To(Equal("The time bank isn't available to you.\r\n"))
+
+		node.SetUser(nil)
+		Expect(command("balance", "")).To(Equal("You need to log in to use the time bank.\r\n"))
+	})
+
+	It("leaves other commands alone", func() {
+		handled, err := module.HandleCommand(&out, node, "dance", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(handled).To(BeFalse())
+	})
+})
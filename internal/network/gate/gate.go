@@ -0,0 +1,197 @@
+package gate
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/network/proxyproto"
+	"euphio/internal/store"
+)
+
+var (
+	ErrBanned             = errors.New("address is banned")
+	ErrTooManyConnections = errors.New("too many connections from address")
+	ErrRateLimited        = errors.New("connecting too quickly")
+)
+
+// Gate decides whether a new connection is let in, before a node is acquired for it. It's shared by all listeners so
+// limits apply to a caller no matter how they connect.
+type Gate struct {
+	mu        sync.Mutex
+	active    map[string]int         // Open connections per IP
+	accepts   map[string][]time.Time // Recent connection times per IP
+	lastSweep time.Time
+}
+
+// Default is the gate used by all of the listeners.
+var Default = New()
+
+func New() *Gate {
+	return &Gate{
+		active:  make(map[string]int),
+		accepts: make(map[string][]time.Time),
+	}
+}
+
+// Admit checks a new connection against the default gate.
+func Admit(addr net.Addr) (func(), error) {
+	return Default.Admit(addr)
+}
+
+// AdmitConn checks a new connection against the default gate, by its remote address. Connections that should have
+// sent a PROXY protocol header but didn't are refused outright, without counting them against the proxy's address,
+// which would soon lock out everyone behind the proxy. The header comes before TLS, so TLS connections are checked
+// underneath.
+func AdmitConn(conn net.Conn) (func(), error) {
+	raw := conn
+	if tlsConn, ok := raw.(*tls.Conn); ok {
+		raw = tlsConn.NetConn()
+	}
+	if pc, ok := raw.(*proxyproto.Conn); ok {
+		if err := pc.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return Default.Admit(conn.RemoteAddr())
+}
+
+// Admit checks the address against the allow and deny lists, bans and connection limits. If the connection is let in,
+// the returned release function must be called once it closes.
+func (g *Gate) Admit(addr net.Addr) (func(), error) {
+	ip := ipOf(addr)
+	if ip == nil {
+		return func() {}, nil
+	}
+	cfg := app.Config().Security
+
+	allowed := matchesAny(ip, cfg.Allow)
+	if !allowed {
+		if matchesAny(ip, cfg.Deny) || banned(ip) {
+			return nil, ErrBanned
+		}
+	}
+
+	key := ip.String()
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+
+	if !allowed {
+		if cfg.MaxConnectionsPerIP > 0 && g.active[key] >= cfg.MaxConnectionsPerIP {
+			return nil, ErrTooManyConnections
+		}
+
+		recent := g.accepts[key]
+		if cfg.MaxConnectsPerMinute > 0 && len(recent) >= cfg.MaxConnectsPerMinute {
+			return nil, ErrRateLimited
+		}
+	}
+
+	g.active[key]++
+	g.accepts[key] = append(g.accepts[key], now)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if g.active[key]--; g.active[key] <= 0 {
+				delete(g.active, key)
+			}
+		})
+	}, nil
+}
+
+// Active returns the number of open connections from the address.
+func (g *Gate) Active(addr net.Addr) int {
+	ip := ipOf(addr)
+	if ip == nil {
+		return 0
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.active[ip.String()]
+}
+
+// sweep forgets connection times older than a minute. Must be called with the lock held.
+func (g *Gate) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < time.Second {
+		return
+	}
+	g.lastSweep = now
+
+	cutoff := now.Add(-time.Minute)
+	for key, times := range g.accepts {
+		i := 0
+		for i < len(times) && times[i].Before(cutoff) {
+			i++
+		}
+		if i == len(times) {
+			delete(g.accepts, key)
+		} else {
+			g.accepts[key] = times[i:]
+		}
+	}
+}
+
+// Conn wraps a connection so the gate is released when it's closed.
+type Conn struct {
+	net.Conn
+	release func()
+}
+
+func NewConn(conn net.Conn, release func()) *Conn {
+	return &Conn{Conn: conn, release: release}
+}
+
+func (c *Conn) Close() error {
+	c.release()
+	return c.Conn.Close()
+}
+
+func banned(ip net.IP) bool {
+	banned, err := app.Store.IsBanned(ip)
+	if err != nil {
+		app.Logger.Error("Failed to check bans", "err", err)
+		return false
+	}
+	return banned
+}
+
+func matchesAny(ip net.IP, cidrs []string) bool {
+	for _, cidr := range cidrs {
+		normalized, err := store.NormalizeCIDR(cidr)
+		if err != nil {
+			app.Logger.Warn("Invalid CIDR in security config", "cidr", cidr, "err", err)
+			continue
+		}
+		_, network, _ := net.ParseCIDR(normalized)
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func ipOf(addr net.Addr) net.IP {
+	if addr == nil {
+		return nil
+	}
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+	return net.ParseIP(host)
+}
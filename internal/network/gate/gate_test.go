@@ -0,0 +1,140 @@
+package gate_test
+
+import (
+	"crypto/tls"
+	"net"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/network/gate"
+	"euphio/internal/network/proxyproto"
+	"euphio/internal/store"
+)
+
+func addr(ip string) net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: 12345}
+}
+
+var _ = Describe("Gate", func() {
+	var g *gate.Gate
+
+	BeforeEach(func() {
+		var err error
+		app.Store, err = store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		app.SetConfig(&config.Config{})
+		g = gate.New()
+	})
+
+	It("admits connections when nothing is configured", func() {
+		release, err := g.Admit(addr("10.0.0.1"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(g.Active(addr("10.0.0.1"))).To(Equal(1))
+
+		release()
+		release()
+		Expect(g.Active(addr("10.0.0.1"))).To(Equal(0))
+	})
+
+	It("refuses addresses on the deny list", func() {
+		app.Config().Security.Deny = []string{"10.0.0.0/8"}
+		_, err := g.Admit(addr("10.1.2.3"))
+		Expect(err).To(MatchError(gate.ErrBanned))
+
+		_, err = g.Admit(addr("192.168.0.1"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("refuses banned addresses", func() {
+		_, err := app.Store.AddBan("192.168.0.1", "")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = g.Admit(addr("192.168.0.1"))
+		Expect(err).To(MatchError(gate.ErrBanned))
+	})
+
+	It("limits concurrent connections per address", func() {
+		app.Config().Security.MaxConnectionsPerIP = 2
+		release, err := g.Admit(addr("10.0.0.1"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = g.Admit(addr("10.0.0.1"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = g.Admit(addr("10.0.0.1"))
+		Expect(err).To(MatchError(gate.ErrTooManyConnections))
+		_, err = g.Admit(addr("10.0.0.2"))
+		Expect(err).NotTo(HaveOccurred())
+
+		release()
+		_, err = g.Admit(addr("10.0.0.1"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("limits how quickly an address can connect", func() {
+		app.Config().Security.MaxConnectsPerMinute = 2
+		for range 2 {
+			release, err := g.Admit(addr("10.0.0.1"))
+			Expect(err).NotTo(HaveOccurred())
+			release()
+		}
+
+		_, err := g.Admit(addr("10.0.0.1"))
+		Expect(err).To(MatchError(gate.ErrRateLimited))
+	})
+
+	It("lets the allow list bypass bans and limits", func() {
+		app.Config().Security.Allow = []string{"10.0.0.1"}
+		app.Config().Security.Deny = []string{"10.0.0.0/8"}
+		app.Config().Security.MaxConnectionsPerIP = 1
+
+		for range 3 {
+			_, err := g.Admit(addr("10.0.0.1"))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := g.Admit(addr("10.0.0.2"))
+		Expect(err).To(MatchError(gate.ErrBanned))
+	})
+
+	Describe("AdmitConn", func() {
+		// accept returns a listener's connection from a client that sends a TLS handshake rather than a PROXY header
+		accept := func(ln net.Listener) (client, conn net.Conn) {
+			DeferCleanup(ln.Close)
+			client, err := net.Dial("tcp", ln.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(client.Close)
+			_, err = client.Write([]byte("\x16\x03\x01\x00\xa5\x01\x00\x00\xa1\x03\x03" + strings.Repeat("\x00", 32)))
+			Expect(err).NotTo(HaveOccurred())
+			conn, err = ln.Accept()
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(conn.Close)
+			return client, conn
+		}
+
+		proxyListener := func() net.Listener {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			return proxyproto.NewListener(ln, config.ProxyProtocolConfig{Enabled: true})
+		}
+
+		It("refuses connections missing their PROXY protocol header, without counting them against the proxy", func() {
+			client, conn := accept(proxyListener())
+
+			_, err := gate.AdmitConn(conn)
+			Expect(err).To(MatchError(proxyproto.ErrInvalidHeader))
+			Expect(gate.Default.Active(client.LocalAddr())).To(Equal(0))
+		})
+
+		It("checks for the header underneath TLS, as telnets listens", func() {
+			client, conn := accept(tls.NewListener(proxyListener(), &tls.Config{}))
+			Expect(conn).To(BeAssignableToTypeOf(&tls.Conn{}))
+
+			_, err := gate.AdmitConn(conn)
+			Expect(err).To(MatchError(proxyproto.ErrInvalidHeader))
+			Expect(gate.Default.Active(client.LocalAddr())).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,286 @@
+package proxyproto
+
+// The PROXY protocol lets a load balancer (e.g. HAProxy) pass on the caller's real address, by sending a header before
+// any of the caller's data. Both the text (v1) and binary (v2) formats are supported.
+// See https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/store"
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	v1MaxLength   = 107 // Including the CRLF
+	headerTimeout = 5 * time.Second
+)
+
+var ErrInvalidHeader = errors.New("invalid PROXY protocol header")
+
+// Listener reads PROXY protocol headers from connections accepted from trusted proxies.
+type Listener struct {
+	net.Listener
+	trusted []*net.IPNet
+}
+
+// NewListener wraps the listener if the PROXY protocol is enabled, otherwise it's returned as is. If no trusted proxies
+// are configured, every connection is expected to send a header.
+func NewListener(ln net.Listener, cfg config.ProxyProtocolConfig) net.Listener {
+	if !cfg.Enabled {
+		return ln
+	}
+
+	l := &Listener{Listener: ln}
+	for _, cidr := range cfg.TrustedProxies {
+		normalized, err := store.NormalizeCIDR(cidr)
+		if err != nil {
+			app.Logger.Warn("Invalid CIDR in PROXY protocol trusted proxies", "cidr", cidr, "err", err)
+			continue
+		}
+		_, network, _ := net.ParseCIDR(normalized)
+		l.trusted = append(l.trusted, network)
+	}
+	return l
+}
+
+// Accept waits for the next connection. The header isn't read until the connection is first used, so a slow client
+// can't hold up the accept loop.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if !l.trustedSource(conn.RemoteAddr()) {
+		return conn, nil
+	}
+	return NewConn(conn), nil
+}
+
+func (l *Listener) trustedSource(addr net.Addr) bool {
+	if len(l.trusted) == 0 {
+		return true
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn reports the addresses from the PROXY protocol header in place of the proxy's own.
+type Conn struct {
+	net.Conn
+	reader *bufio.Reader
+	once   sync.Once
+	src    net.Addr
+	dst    net.Addr
+	err    error
+
+	mu       sync.Mutex
+	deadline time.Time // The caller's read deadline, put back once the header has been read
+}
+
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{
+		Conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(p)
+}
+
+// Err reads the header if it hasn't been read yet, and returns the error reading it.
+func (c *Conn) Err() error {
+	c.once.Do(c.readHeader)
+	return c.err
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.src != nil {
+		return c.src
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.dst != nil {
+		return c.dst
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readHeader reads the header, giving the proxy headerTimeout to send it (or less if the caller set an earlier
+// deadline).
+func (c *Conn) readHeader() {
+	c.mu.Lock()
+	deadline := time.Now().Add(headerTimeout)
+	if !c.deadline.IsZero() && c.deadline.Before(deadline) {
+		deadline = c.deadline
+	}
+	c.Conn.SetReadDeadline(deadline)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.Conn.SetReadDeadline(c.deadline)
+	}()
+
+	c.src, c.dst, c.err = ReadHeader(c.reader)
+	if c.err != nil {
+		app.Logger.Warn("Failed to read PROXY protocol header", "proxy", c.Conn.RemoteAddr(), "err", c.err)
+	}
+}
+
+// ReadHeader reads a v1 or v2 header. The addresses are nil if the proxy didn't pass any on, e.g. for health checks.
+func ReadHeader(r *bufio.Reader) (src, dst net.Addr, err error) {
+	peek, err := r.Peek(len(v1Prefix))
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(peek, v1Prefix) {
+		return readV1(r)
+	}
+
+	peek, err = r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, nil, err
+	}
+	if bytes.Equal(peek, v2Signature) {
+		return readV2(r)
+	}
+	return nil, nil, ErrInvalidHeader
+}
+
+// readV1 reads a text header, e.g. "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"
+func readV1(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	var line []byte
+	for !bytes.HasSuffix(line, []byte("\r\n")) {
+		if len(line) >= v1MaxLength {
+			return nil, nil, ErrInvalidHeader
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		line = append(line, b)
+	}
+
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, nil, ErrInvalidHeader
+	}
+
+	src, err := parseV1Addr(fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dst, err := parseV1Addr(fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, dst, nil
+}
+
+func parseV1Addr(host, port string) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	p, err := strconv.ParseUint(port, 10, 16)
+	if ip == nil || err != nil {
+		return nil, ErrInvalidHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// readV2 reads a binary header: the signature, version and command, address family and protocol, then the length of
+// the addresses that follow.
+func readV2(r *bufio.Reader) (net.Addr, net.Addr, error) {
+	header := make([]byte, len(v2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+
+	verCmd, family := header[12], header[13]
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, err
+	}
+
+	if verCmd>>4 != 2 {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, verCmd>>4)
+	}
+	switch verCmd & 0x0f {
+	case 0x0: // LOCAL, the proxy's own connection
+		return nil, nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, nil, ErrInvalidHeader
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if length < 12 {
+			return nil, nil, ErrInvalidHeader
+		}
+		src := &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}
+		dst := &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:12]))}
+		return src, dst, nil
+	case 0x21: // TCP over IPv6
+		if length < 36 {
+			return nil, nil, ErrInvalidHeader
+		}
+		src := &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}
+		dst := &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:36]))}
+		return src, dst, nil
+	}
+
+	// Other families (UDP, unix sockets) don't carry a caller address we can use
+	return nil, nil, nil
+}
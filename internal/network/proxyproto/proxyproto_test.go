@@ -0,0 +1,147 @@
+package proxyproto_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/config"
+	"euphio/internal/network/proxyproto"
+)
+
+const v2Signature = "\r\n\r\n\x00\r\nQUIT\n"
+
+var _ = Describe("PROXY Protocol", func() {
+	read := func(header string) (net.Addr, net.Addr, error) {
+		return proxyproto.ReadHeader(bufio.NewReader(strings.NewReader(header)))
+	}
+
+	Context("v1", func() {
+		It("should parse TCP4 addresses", func() {
+			src, dst, err := read("PROXY TCP4 203.0.113.7 192.168.0.11 56324 23\r\nhello")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src.String()).To(Equal("203.0.113.7:56324"))
+			Expect(dst.String()).To(Equal("192.168.0.11:23"))
+		})
+
+		It("should parse TCP6 addresses", func() {
+			src, _, err := read("PROXY TCP6 2001:db8::1 2001:db8::2 40000 22\r\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src.String()).To(Equal("[2001:db8::1]:40000"))
+		})
+
+		It("should accept UNKNOWN without addresses", func() {
+			src, dst, err := read("PROXY UNKNOWN\r\n")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src).To(BeNil())
+			Expect(dst).To(BeNil())
+		})
+
+		It("should reject malformed headers", func() {
+			_, _, err := read("PROXY TCP4 nonsense\r\n")
+			Expect(err).To(MatchError(proxyproto.ErrInvalidHeader))
+
+			_, _, err = read("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n")
+			Expect(err).To(MatchError(proxyproto.ErrInvalidHeader))
+		})
+	})
+
+	Context("v2", func() {
+		It("should parse IPv4 addresses", func() {
+			header := v2Signature + "\x21\x11\x00\x0c" +
+				"\xcb\x00\x71\x07" + "\xc0\xa8\x00\x0b" + "\xdc\x04" + "\x00\x17"
+			src, dst, err := read(header)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src.String()).To(Equal("203.0.113.7:56324"))
+			Expect(dst.String()).To(Equal("192.168.0.11:23"))
+		})
+
+		It("should ignore addresses for LOCAL connections", func() {
+			src, _, err := read(v2Signature + "\x20\x00\x00\x00")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(src).To(BeNil())
+		})
+	})
+
+	It("should reject connections without a header", func() {
+		_, _, err := read("hello there\r\n")
+		Expect(err).To(MatchError(proxyproto.ErrInvalidHeader))
+	})
+
+	Context("Listener", func() {
+		var ln net.Listener
+
+		listen := func(cfg config.ProxyProtocolConfig) {
+			var err error
+			ln, err = net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).NotTo(HaveOccurred())
+			ln = proxyproto.NewListener(ln, cfg)
+		}
+
+		// dial connects, sends the data and returns the server's side of the connection
+		dial := func(data string) net.Conn {
+			client, err := net.Dial("tcp", ln.Addr().String())
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(client.Close)
+			_, err = client.Write([]byte(data))
+			Expect(err).NotTo(HaveOccurred())
+
+			conn, err := ln.Accept()
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(conn.Close)
+			return conn
+		}
+
+		AfterEach(func() {
+			ln.Close()
+		})
+
+		It("should report the caller's address and pass on the data", func() {
+			listen(config.ProxyProtocolConfig{Enabled: true, TrustedProxies: []string{"127.0.0.1"}})
+			conn := dial("PROXY TCP4 203.0.113.7 127.0.0.1 56324 23\r\nhello")
+
+			Expect(conn.RemoteAddr().String()).To(Equal("203.0.113.7:56324"))
+			data := make([]byte, 5)
+			_, err := io.ReadFull(conn, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal("hello"))
+		})
+
+		It("should keep the caller's read deadline", func() {
+			listen(config.ProxyProtocolConfig{Enabled: true})
+			conn := dial("PROXY TCP4 203.0.113.7 127.0.0.1 56324 23\r\nhi")
+			Expect(conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))).To(Succeed())
+
+			data := make([]byte, 2)
+			_, err := io.ReadFull(conn, data)
+			Expect(err).NotTo(HaveOccurred())
+
+			errs := make(chan error, 1)
+			go func() {
+				_, err := conn.Read(data)
+				errs <- err
+			}()
+			Eventually(errs).Should(Receive(MatchError(os.ErrDeadlineExceeded)))
+		})
+
+		It("should report a missing header", func() {
+			listen(config.ProxyProtocolConfig{Enabled: true})
+			conn := dial("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+			Expect(conn.(*proxyproto.Conn).Err()).To(MatchError(proxyproto.ErrInvalidHeader))
+		})
+
+		It("should not read headers from untrusted sources", func() {
+			listen(config.ProxyProtocolConfig{Enabled: true, TrustedProxies: []string{"10.0.0.0/8"}})
+			conn := dial("PROXY TCP4 203.0.113.7 127.0.0.1 56324 23\r\n")
+
+			Expect(conn.RemoteAddr().(*net.TCPAddr).IP.String()).To(Equal("127.0.0.1"))
+		})
+	})
+})
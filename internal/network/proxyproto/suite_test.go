@@ -0,0 +1,22 @@
+package proxyproto_test
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+)
+
+func TestProxyProto(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	app.Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	RunSpecs(t, "PROXY Protocol Suite")
+}
@@ -0,0 +1,9 @@
+package network
+
+import (
+	"euphio/internal/network/rlogin"
+)
+
+func NewRLogin() *rlogin.Server {
+	return rlogin.NewServer()
+}
@@ -0,0 +1,220 @@
+package rlogin
+
+// RLogin is described in RFC 1282: https://www.rfc-editor.org/rfc/rfc1282
+//
+// After connecting, the client sends four NUL terminated strings:
+//
+//	<null> client-user-name <null> server-user-name <null> terminal-type/speed <null>
+//
+// and the server acknowledges with a single NUL. Everything after that is terminal data, apart from window size
+// changes which the client sends in-band as a "magic cookie" (0xFF 0xFF 's' 's') followed by rows, columns, x pixels
+// and y pixels as 16-bit big endian values. Clients only send these once the server asks for them, by sending a 0x80
+// byte as TCP urgent data.
+//
+// BBS software (SyncTERM, Synchronet, door servers) commonly puts the password in the client-user-name field and the
+// username in the server-user-name field, which is what allows callers to be logged in automatically.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"euphio/internal/nodes"
+)
+
+var windowCookie = []byte{0xff, 0xff, 's', 's'}
+
+const (
+	windowCookieLen = 12   // Cookie followed by four 16-bit values
+	windowRequest   = 0x80 // Sent as urgent data to ask for window size changes (TIOCPKT_WINDOW)
+	maxFieldLen     = 256  // Longest handshake field accepted
+)
+
+// Handshake holds the details sent by the client when connecting.
+type Handshake struct {
+	ClientUser string
+	ServerUser string
+	Terminal   string
+	Speed      int
+}
+
+type Connection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	mu     sync.RWMutex
+	width  int
+	height int
+	held   []byte // The start of a window size change split across reads, see Read
+
+	Handshake Handshake
+}
+
+func NewConnection(conn net.Conn) *Connection {
+	return &Connection{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// ReadHandshake reads the client's handshake and acknowledges it.
+func (c *Connection) ReadHandshake(timeout time.Duration) error {
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	first, err := c.reader.ReadByte()
+	if err != nil {
+		return err
+	}
+	if first != 0 {
+		return errors.New("rlogin: invalid handshake")
+	}
+
+	fields := make([]string, 3)
+	for i := range fields {
+		field, err := c.readField()
+		if err != nil {
+			return err
+		}
+		fields[i] = field
+	}
+
+	c.Handshake = Handshake{
+		ClientUser: fields[0],
+		ServerUser: fields[1],
+	}
+	term, speed, _ := strings.Cut(fields[2], "/")
+	c.Handshake.Terminal = term
+	c.Handshake.Speed, _ = strconv.Atoi(speed)
+
+	_, err = c.conn.Write([]byte{0})
+	return err
+}
+
+// readField reads a NUL terminated handshake field, giving up on fields longer than maxFieldLen rather than reading
+// whatever the client sends.
+func (c *Connection) readField() (string, error) {
+	var field []byte
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == 0 {
+			return string(field), nil
+		}
+		if len(field) == maxFieldLen {
+			return "", errors.New("rlogin: handshake field too long")
+		}
+		field = append(field, b)
+	}
+}
+
+// RequestWindowSize asks the client to send its window size, and any changes to it. The request has to go out as TCP
+// urgent data, so it can only be sent on a TCP connection (and not through a PROXY protocol proxy, which wouldn't pass
+// it on anyway). Returns errors.ErrUnsupported if it can't be sent.
+func (c *Connection) RequestWindowSize() error {
+	return sendUrgent(c.conn, windowRequest)
+}
+
+// Credentials returns the username and password sent by the client, following the SyncTERM convention of sending the
+// password as the client user. If swap is set the fields are read the other way around.
+func (c *Connection) Credentials(swap bool) (username, password string) {
+	if swap {
+		return c.Handshake.ClientUser, c.Handshake.ServerUser
+	}
+	return c.Handshake.ServerUser, c.Handshake.ClientUser
+}
+
+// Read reads terminal data, with window size changes taken out. A change can be split across reads, so bytes at the
+// end that could be the start of one are held back until the next read shows whether they are. p must have room for
+// at least a whole change.
+func (c *Connection) Read(p []byte) (int, error) {
+	if len(p) < windowCookieLen {
+		return 0, io.ErrShortBuffer
+	}
+	for {
+		n := copy(p, c.held)
+		c.held = nil
+		m, err := c.reader.Read(p[n:])
+		n = c.stripWindowSize(p[:n+m])
+		// Don't hand back nothing just because everything read was held back
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// stripWindowSize removes window size changes from the data, recording the new size, and holds back the start of a
+// change at the end. Returns the new data length.
+func (c *Connection) stripWindowSize(data []byte) int {
+	for {
+		i := bytes.Index(data, windowCookie)
+		if i == -1 || len(data)-i < windowCookieLen {
+			break
+		}
+
+		rows := int(binary.BigEndian.Uint16(data[i+4 : i+6]))
+		cols := int(binary.BigEndian.Uint16(data[i+6 : i+8]))
+		c.mu.Lock()
+		c.width = cols
+		c.height = rows
+		c.mu.Unlock()
+
+		data = append(data[:i], data[i+windowCookieLen:]...)
+	}
+
+	for i := max(0, len(data)-windowCookieLen+1); i < len(data); i++ {
+		if tail := data[i:]; bytes.HasPrefix(windowCookie, tail) || bytes.HasPrefix(tail, windowCookie) {
+			c.held = bytes.Clone(tail)
+			return i
+		}
+	}
+	return len(data)
+}
+
+func (c *Connection) Write(p []byte) (n int, err error) {
+	return c.conn.Write(p)
+}
+
+func (c *Connection) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Connection) Send(msg string) error {
+	_, err := io.WriteString(c.conn, msg+"\r\n")
+	return err
+}
+
+func (c *Connection) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Connection) GetTerminalInfo() nodes.TerminalInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return nodes.TerminalInfo{
+		Type:   c.Handshake.Terminal,
+		Width:  c.width,
+		Height: c.height,
+	}
+}
+
+// IsUTF8 implements the nodes.Connection interface
+func (c *Connection) IsUTF8() bool {
+	termType := strings.ToLower(c.Handshake.Terminal)
+	return strings.Contains(termType, "xterm") || strings.Contains(termType, "utf")
+}
+
+// GetWidth implements the nodes.Connection interface
+func (c *Connection) GetWidth() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.width
+}
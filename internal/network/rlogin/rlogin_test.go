@@ -0,0 +1,160 @@
+package rlogin_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/network/rlogin"
+)
+
+var _ = Describe("RLogin Protocol", func() {
+	var (
+		serverConn net.Conn
+		clientConn net.Conn
+		connection *rlogin.Connection
+	)
+
+	BeforeEach(func() {
+		serverConn, clientConn = net.Pipe()
+		connection = rlogin.NewConnection(serverConn)
+		clientConn.SetDeadline(time.Now().Add(2 * time.Second))
+	})
+
+	AfterEach(func() {
+		connection.Close()
+		clientConn.Close()
+	})
+
+	// handshake sends the client's side of the handshake and returns the server's acknowledgement
+	handshake := func(data string) (chan error, []byte) {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- connection.ReadHandshake(time.Second)
+		}()
+
+		_, err := clientConn.Write([]byte(data))
+		Expect(err).NotTo(HaveOccurred())
+
+		ack := make([]byte, 1)
+		_, err = io.ReadFull(clientConn, ack)
+		Expect(err).NotTo(HaveOccurred())
+		return errs, ack
+	}
+
+	Context("Handshake", func() {
+		It("should parse the users, terminal type and speed", func() {
+			errs, ack := handshake("\x00secret1\x00bob\x00ansi-bbs/115200\x00")
+			Expect(<-errs).To(Succeed())
+			Expect(ack).To(Equal([]byte{0}))
+
+			Expect(connection.Handshake).To(Equal(rlogin.Handshake{
+				ClientUser: "secret1",
+				ServerUser: "bob",
+				Terminal:   "ansi-bbs",
+				Speed:      115200,
+			}))
+			Expect(connection.GetTerminalInfo().Type).To(Equal("ansi-bbs"))
+		})
+
+		It("should read credentials SyncTERM style, or swapped", func() {
+			errs, _ := handshake("\x00secret1\x00bob\x00xterm/38400\x00")
+			Expect(<-errs).To(Succeed())
+
+			username, password := connection.Credentials(false)
+			Expect(username).To(Equal("bob"))
+			Expect(password).To(Equal("secret1"))
+
+			username, password = connection.Credentials(true)
+			Expect(username).To(Equal("secret1"))
+			Expect(password).To(Equal("bob"))
+		})
+
+		It("should reject a handshake not starting with NUL", func() {
+			errs := make(chan error, 1)
+			go func() {
+				errs <- connection.ReadHandshake(time.Second)
+			}()
+			_, err := clientConn.Write([]byte("bob\x00"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(<-errs).To(HaveOccurred())
+		})
+
+		It("should give up on a handshake field that never ends", func() {
+			errs := make(chan error, 1)
+			go func() {
+				errs <- connection.ReadHandshake(time.Second)
+			}()
+			go clientConn.Write(append([]byte{0}, bytes.Repeat([]byte("a"), 1000)...))
+			Eventually(errs).Should(Receive(MatchError("rlogin: handshake field too long")))
+		})
+	})
+
+	Context("Window size", func() {
+		It("should strip window size changes from the data", func() {
+			errs, _ := handshake("\x00\x00bob\x00xterm/38400\x00")
+			Expect(<-errs).To(Succeed())
+
+			go func() {
+				defer GinkgoRecover()
+				// 25 rows, 132 columns, no pixel sizes
+				_, err := clientConn.Write([]byte("ab\xff\xffss\x00\x19\x00\x84\x00\x00\x00\x00cd"))
+				Expect(err).NotTo(HaveOccurred())
+			}()
+
+			buf := make([]byte, 64)
+			n, err := connection.Read(buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buf[:n])).To(Equal("abcd"))
+
+			info := connection.GetTerminalInfo()
+			Expect(info.Width).To(Equal(132))
+			Expect(info.Height).To(Equal(25))
+		})
+
+		It("should strip a window size change split across reads", func() {
+			errs, _ := handshake("\x00\x00bob\x00xterm/38400\x00")
+			Expect(<-errs).To(Succeed())
+
+			buf := make([]byte, 64)
+			go func() {
+				for _, data := range []string{"ab\xff", "\xffss\x00\x19", "\x00\x84\x00\x00\x00\x00cd"} {
+					clientConn.Write([]byte(data))
+				}
+			}()
+			var got []byte
+			for len(got) < 4 {
+				n, err := connection.Read(buf)
+				Expect(err).NotTo(HaveOccurred())
+				got = append(got, buf[:n]...)
+			}
+			Expect(string(got)).To(Equal("abcd"))
+			Expect(connection.GetTerminalInfo().Width).To(Equal(132))
+		})
+
+		It("should pass on bytes that only looked like the start of a change", func() {
+			errs, _ := handshake("\x00\x00bob\x00xterm/38400\x00")
+			Expect(<-errs).To(Succeed())
+
+			buf := make([]byte, 64)
+			go clientConn.Write([]byte("ab\xff"))
+			n, err := connection.Read(buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buf[:n])).To(Equal("ab"))
+
+			go clientConn.Write([]byte("cd"))
+			n, err = connection.Read(buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(buf[:n])).To(Equal("\xffcd"))
+		})
+
+		It("can't ask for window sizes without a TCP connection", func() {
+			Expect(connection.RequestWindowSize()).To(MatchError(errors.ErrUnsupported))
+		})
+	})
+})
@@ -0,0 +1,166 @@
+package rlogin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/auth"
+	"euphio/internal/config"
+	"euphio/internal/network/gate"
+	"euphio/internal/network/proxyproto"
+	"euphio/internal/session"
+	"euphio/internal/store"
+)
+
+type Server struct {
+	config config.RLoginConfig
+	ln     net.Listener
+}
+
+func NewServer() *Server {
+	return &Server{
+		config: app.Config().Listeners.RLogin,
+	}
+}
+
+func (s *Server) ListenAndServe() error {
+	app.Logger.Info("RLogin server listening", "port", s.config.Port)
+
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	if err != nil {
+		return err
+	}
+	s.ln = proxyproto.NewListener(ln, s.config.ProxyProtocol)
+	defer s.ln.Close()
+
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			app.Logger.Error("RLogin accept error", "err", err)
+			continue
+		}
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *Server) Stop() error {
+	if s.ln != nil {
+		return s.ln.Close()
+	}
+	return nil
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	release, err := gate.AdmitConn(conn)
+	if err != nil {
+		app.Logger.Info("RLogin connection refused", "addr", conn.RemoteAddr(), "err", err)
+		conn.Close()
+		return
+	}
+	defer release()
+
+	rloginConn := NewConnection(conn)
+	defer rloginConn.Close()
+
+	if err := rloginConn.ReadHandshake(10 * time.Second); err != nil {
+		app.Logger.Info("RLogin handshake failed", "addr", conn.RemoteAddr(), "err", err)
+		return
+	}
+
+	if err := rloginConn.RequestWindowSize(); err != nil && !errors.Is(err, errors.ErrUnsupported) {
+		app.Logger.Debug("RLogin window size request failed", "addr", conn.RemoteAddr(), "err", err)
+	}
+
+	node, err := app.Nodes.Acquire()
+	if err != nil {
+		app.Logger.Warn("RLogin connection rejected: system full", "addr", conn.RemoteAddr())
+		rloginConn.Send("System full, please try again later.")
+		return
+	}
+	defer app.Nodes.Release(node.ID)
+
+	node.Conn = rloginConn
+	logger := app.Logger.With("node", node.ID)
+
+	handshake := rloginConn.Handshake
+	logger.Info("RLogin connection established", "addr", conn.RemoteAddr(), "term", handshake.Terminal, "speed", handshake.Speed)
+	defer logger.Info("RLogin connection closed", "addr", conn.RemoteAddr())
+
+	// Callers that can't be logged in automatically get the login view as usual
+	if user := s.autoLogin(rloginConn, logger); user != nil {
+		node.SetUser(user)
+	}
+
+	// Hand off to the session manager
+	session.RunSession(rloginConn, node, s.config.InitialView)
+}
+
+// autoLogin logs the caller in with the credentials from the handshake. Trusted hosts, and callers sending the shared
+// secret, only need to send a username.
+func (s *Server) autoLogin(conn *Connection, logger *slog.Logger) *store.User {
+	username, password := conn.Credentials(s.config.SwapUsers)
+	if username == "" {
+		return nil
+	}
+
+	trusted := s.trustedHost(conn.RemoteAddr())
+	if !trusted && s.config.SharedSecret != "" {
+		trusted = subtle.ConstantTimeCompare([]byte(password), []byte(s.config.SharedSecret)) == 1
+	}
+
+	if trusted {
+		user, err := app.Store.FindUserByUsername(username)
+		if err != nil {
+			logger.Info("RLogin auto-login failed", "user", username, "err", err)
+			return nil
+		}
+		if user.Locked {
+			logger.Info("RLogin auto-login refused", "user", username, "err", store.ErrAccountLocked)
+			return nil
+		}
+		if err := auth.CheckTimeLeft(user); err != nil {
+			logger.Info("RLogin auto-login refused", "user", username, "err", err)
+			return nil
+		}
+		logger.Info("RLogin auto-login", "user", username, "trusted", true)
+		return user
+	}
+
+	if password == "" {
+		return nil
+	}
+	user, err := auth.Login(username, password, conn.RemoteAddr())
+	if err != nil {
+		logger.Info("RLogin auto-login failed", "user", username, "err", err)
+		return nil
+	}
+	logger.Info("RLogin auto-login", "user", username)
+	return user
+}
+
+func (s *Server) trustedHost(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, cidr := range s.config.TrustedHosts {
+		normalized, err := store.NormalizeCIDR(cidr)
+		if err != nil {
+			app.Logger.Warn("Invalid CIDR in RLogin trusted hosts", "cidr", cidr, "err", err)
+			continue
+		}
+		_, network, _ := net.ParseCIDR(normalized)
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package rlogin_test
+
+import (
+	"log/slog"
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+)
+
+func TestRLogin(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	app.Logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError,
+	}))
+
+	RunSpecs(t, "RLogin Suite")
+}
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package rlogin
+
+import (
+	"errors"
+	"net"
+)
+
+// sendUrgent sends a byte as TCP urgent (out of band) data, which isn't supported on this platform.
+func sendUrgent(conn net.Conn, b byte) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package rlogin
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// sendUrgent sends a byte as TCP urgent (out of band) data.
+func sendUrgent(conn net.Conn, b byte) error {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	raw, err := tcpConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sendErr error
+	err = raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendto(int(fd), []byte{b}, syscall.MSG_OOB, nil)
+		// Not done yet if the socket's buffer is full, the runtime waits until it's writable and calls again
+		return !errors.Is(sendErr, syscall.EAGAIN)
+	})
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package rlogin_test
+
+import (
+	"net"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/network/rlogin"
+)
+
+var _ = Describe("RLogin window size request", func() {
+	It("should be sent as urgent data", func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer ln.Close()
+
+		client, err := net.Dial("tcp", ln.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		defer client.Close()
+		server, err := ln.Accept()
+		Expect(err).NotTo(HaveOccurred())
+		defer server.Close()
+
+		Expect(rlogin.NewConnection(server).RequestWindowSize()).To(Succeed())
+
+		raw, err := client.(*net.TCPConn).SyscallConn()
+		Expect(err).NotTo(HaveOccurred())
+		urgent := func() []byte {
+			buf := make([]byte, 1)
+			var n int
+			raw.Control(func(fd uintptr) {
+				n, _, _ = syscall.Recvfrom(int(fd), buf, syscall.MSG_OOB|syscall.MSG_DONTWAIT)
+			})
+			return buf[:max(n, 0)]
+		}
+		Eventually(urgent).Should(Equal([]byte{0x80}))
+	})
+})
@@ -1,12 +1,17 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
+	"net"
 
 	"github.com/gliderlabs/ssh"
 
 	"euphio/internal/app"
+	"euphio/internal/auth"
 	"euphio/internal/config"
+	"euphio/internal/network/gate"
+	"euphio/internal/network/proxyproto"
 	"euphio/internal/session"
 	"euphio/internal/store"
 )
@@ -14,11 +19,12 @@ import (
 type Server struct {
 	config config.SSHConfig
 	server *ssh.Server
+	ln     net.Listener
 }
 
 func NewServer() *Server {
 	return &Server{
-		config: app.Config.Listeners.SSH,
+		config: app.Config().Listeners.SSH,
 	}
 }
 
@@ -26,16 +32,25 @@ func (s *Server) ListenAndServe() error {
 	app.Logger.Info("SSH server listening", "port", s.config.Port)
 
 	s.server = &ssh.Server{
-		Addr:            fmt.Sprintf(":%d", s.config.Port),
-		Handler:         s.HandleSession,
-		PasswordHandler: s.PasswordHandler,
+		Addr:             fmt.Sprintf(":%d", s.config.Port),
+		Handler:          s.HandleSession,
+		PasswordHandler:  s.PasswordHandler,
+		PublicKeyHandler: s.PublicKeyHandler,
+		ConnCallback:     s.ConnCallback,
 	}
 
 	err := s.server.SetOption(ssh.HostKeyFile(s.config.KeyFile))
 	if err != nil {
 		return err
 	}
-	if err := s.server.ListenAndServe(); err != nil && err != ssh.ErrServerClosed {
+
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	s.ln = proxyproto.NewListener(ln, s.config.ProxyProtocol)
+
+	if err := s.server.Serve(s.ln); err != nil && err != ssh.ErrServerClosed && !errors.Is(err, net.ErrClosed) {
 		// gliderlabs/ssh returns "ssh: Server closed" (ssh.ErrServerClosed) on Close
 		// We want to suppress that error as it is expected during shutdown
 		return err
@@ -43,18 +58,47 @@ func (s *Server) ListenAndServe() error {
 	return nil
 }
 
+// Stop stops accepting connections. Callers already connected stay online.
 func (s *Server) Stop() error {
-	if s.server != nil {
-		return s.server.Close()
+	if s.ln != nil {
+		return s.ln.Close()
 	}
 	return nil
 }
 
+// ConnCallback checks new connections against the gate before the SSH handshake even begins.
+func (s *Server) ConnCallback(ctx ssh.Context, conn net.Conn) net.Conn {
+	release, err := gate.AdmitConn(conn)
+	if err != nil {
+		app.Logger.Info("SSH connection refused", "addr", conn.RemoteAddr(), "err", err)
+		return nil
+	}
+	return gate.NewConn(conn, release)
+}
+
 func (s *Server) PasswordHandler(ctx ssh.Context, password string) bool {
-	user, err := app.Store.Authenticate(ctx.User(), password)
+	user, err := auth.Login(ctx.User(), password, ctx.RemoteAddr())
+	if err != nil {
+		app.Logger.Debug("Login failed", "user", ctx.User(), "addr", ctx.RemoteAddr(), "err", err)
+		return false
+	}
+	ctx.SetValue("user", user)
+	return true
+}
+
+func (s *Server) PublicKeyHandler(ctx ssh.Context, key ssh.PublicKey) bool {
+	// Clients commonly offer several keys, so rejected keys don't count as failures, but lockouts still apply
+	if err := auth.CheckLocked(ctx.User(), ctx.RemoteAddr()); err != nil {
+		app.Logger.Debug("Public key login failed", "user", ctx.User(), "addr", ctx.RemoteAddr(), "err", err)
+		return false
+	}
+
+	user, err := app.Store.AuthenticateKey(ctx.User(), key)
+	if err == nil {
+		err = auth.CheckTimeLeft(user)
+	}
 	if err != nil {
-		// TODO: Make login failure limit, etc.
-		app.Logger.Debug("Login failed", "user", ctx.User(), "err", err)
+		app.Logger.Debug("Public key login failed", "user", ctx.User(), "err", err)
 		return false
 	}
 	ctx.SetValue("user", user)
@@ -76,7 +120,7 @@ func (s *Server) HandleSession(sess ssh.Session) {
 
 	// Retrieve authenticated user from context
 	if user, ok := sess.Context().Value("user").(*store.User); ok {
-		node.User = user
+		node.SetUser(user)
 	}
 
 	logger := app.Logger.With("node", node.ID)
@@ -7,3 +7,7 @@ import (
 func NewTelnet() *telnet.Server {
 	return telnet.NewServer()
 }
+
+func NewTelnetTLS() *telnet.Server {
+	return telnet.NewTLSServer()
+}
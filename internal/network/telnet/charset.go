@@ -0,0 +1,124 @@
+package telnet
+
+// CHARSET (RFC 2066) lets both sides agree on a character set, which is the most reliable way to know if a client
+// wants UTF-8. See https://www.rfc-editor.org/rfc/rfc2066
+//
+// We offer WILL CHARSET, and when the client agrees we send our preferences:
+//
+//	IAC SB CHARSET REQUEST ";UTF-8;CP437;US-ASCII" IAC SE
+//
+// The client answers with ACCEPTED and the charset it picked, or REJECTED if it supports none of them. Clients can
+// also send a REQUEST of their own after WILL CHARSET, which we answer the same way.
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Charsets we can send, most preferred first.
+var supportedCharsets = []string{"UTF-8", "CP437", "US-ASCII"}
+
+// requestCharset sends our charset preferences to the client.
+func (c *Connection) requestCharset() {
+	c.mu.Lock()
+	c.charsetPending = true
+	c.mu.Unlock()
+
+	data := []byte{CharsetRequest}
+	for _, charset := range supportedCharsets {
+		data = append(data, ';')
+		data = append(data, charset...)
+	}
+	c.SendSubNegotiation(Charset, data)
+}
+
+func (c *Connection) handleCharset(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	switch data[0] {
+	case CharsetRequest:
+		c.mu.RLock()
+		pending := c.charsetPending
+		c.mu.RUnlock()
+
+		// If both sides sent a REQUEST at the same time, the server's takes priority
+		if pending {
+			c.SendSubNegotiation(Charset, []byte{CharsetRejected})
+			return
+		}
+
+		charset := pickCharset(data[1:])
+		if charset == "" {
+			c.SendSubNegotiation(Charset, []byte{CharsetRejected})
+			return
+		}
+		c.setCharset(charset)
+		c.SendSubNegotiation(Charset, append([]byte{CharsetAccepted}, charset...))
+
+	case CharsetAccepted:
+		c.setCharset(string(data[1:]))
+
+	case CharsetRejected:
+		c.logger.Debug("Telnet charset rejected")
+		c.mu.Lock()
+		c.charsetPending = false
+		c.mu.Unlock()
+
+	case CharsetTTableIs:
+		// Translation tables aren't supported
+		c.SendSubNegotiation(Charset, []byte{CharsetTTableRejected})
+	}
+
+	c.checkNegotiationComplete()
+}
+
+func (c *Connection) setCharset(charset string) {
+	c.logger.Debug("Telnet charset", "charset", charset)
+	c.mu.Lock()
+	c.charset = charset
+	c.charsetPending = false
+	c.mu.Unlock()
+}
+
+// pickCharset chooses our preferred charset from a REQUEST's list, which starts with the separator character.
+func pickCharset(data []byte) string {
+	// Skip the optional translation table version
+	if bytes.HasPrefix(data, []byte("[TTABLE]")) && len(data) > 9 {
+		data = data[9:]
+	}
+	if len(data) < 2 {
+		return ""
+	}
+
+	offered := strings.Split(string(data[1:]), string(data[0]))
+	for _, charset := range supportedCharsets {
+		for _, o := range offered {
+			if strings.EqualFold(normalizeCharset(o), charset) {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeCharset maps common aliases to the names we use.
+func normalizeCharset(charset string) string {
+	switch strings.ToUpper(strings.TrimSpace(charset)) {
+	case "UTF8", "UTF-8":
+		return "UTF-8"
+	case "CP437", "IBM437", "IBM-437", "437":
+		return "CP437"
+	case "ASCII", "US-ASCII", "ANSI_X3.4-1968":
+		return "US-ASCII"
+	}
+	return charset
+}
+
+// Charset returns the character set agreed with the client, or an empty string if none was.
+func (c *Connection) Charset() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.charset
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net"
 	"strings"
 	"sync"
@@ -34,12 +35,31 @@ type Connection struct {
 	sentWill map[byte]bool
 	sentDo   map[byte]bool
 
-	mu sync.RWMutex
+	optMu sync.Mutex // Guards the option state above, which the session changes too, see SetLineEditing
+	mu    sync.RWMutex
 
 	// Terminal Info
 	TerminalType string
 	WindowWidth  int
 	WindowHeight int
+	env          map[string]string // From NEW-ENVIRON
+
+	terminalTypes  []string // Every type sent while cycling through TTYPE
+	ttypeCycling   bool
+	mtts           int
+	hasMTTS        bool
+	charset        string // Agreed with CHARSET
+	charsetPending bool
+
+	gmcpEnabled  bool     // Tracked separately from the option maps as the session checks it
+	gmcpSupports []string // Packages the client asked for
+	gmcpHandler  func(pkg string, data []byte)
+	gmcpQueue    []gmcpMessage // Received before a handler was set
+
+	linemode       bool // The client agreed to LINEMODE
+	lineEditWanted bool // We asked the client to edit lines locally
+	lineEditing    bool // The client acknowledged editing lines locally
+	echoPaused     bool // We stopped echoing while the client edits lines
 
 	// Negotiation completion channel
 	negotiationDone chan struct{}
@@ -98,10 +118,7 @@ func (c *Connection) HandleCommand(cmd, option byte) {
 		// Client wants US to do something
 		switch option {
 		case Echo:
-			if !c.IsLocalOptionEnabled(Echo) {
-				c.EnableLocalOption(Echo)
-				c.SendWill(Echo)
-			}
+			c.setLocalOption(Echo, true)
 		case SGA:
 			if !c.IsLocalOptionEnabled(SGA) {
 				c.EnableLocalOption(SGA)
@@ -112,6 +129,23 @@ func (c *Connection) HandleCommand(cmd, option byte) {
 				c.EnableLocalOption(TransmitBinary)
 				c.SendWill(TransmitBinary)
 			}
+		case Charset:
+			if !c.IsLocalOptionEnabled(Charset) {
+				c.EnableLocalOption(Charset)
+				c.SendWill(Charset)
+				c.requestCharset()
+			}
+		case GMCP:
+			if !c.IsLocalOptionEnabled(GMCP) {
+				c.EnableLocalOption(GMCP)
+				c.SendWill(GMCP)
+				c.setGMCPEnabled(true)
+			}
+		case MSSP:
+			// Crawlers ask for the status each time, so always answer
+			c.EnableLocalOption(MSSP)
+			c.SendWill(MSSP)
+			c.SendMSSP(MSSPVariables())
 		default:
 			c.SendWont(option)
 		}
@@ -120,10 +154,11 @@ func (c *Connection) HandleCommand(cmd, option byte) {
 		// Client wants us NOT to do something
 		switch option {
 		case Echo:
-			if c.IsLocalOptionEnabled(Echo) {
-				c.DisableLocalOption(Echo)
-				c.SendWont(Echo)
-			}
+			c.setLocalOption(Echo, false)
+		case GMCP:
+			c.setGMCPEnabled(false)
+			c.DisableLocalOption(GMCP)
+			c.SendWont(GMCP)
 		default:
 			c.DisableLocalOption(option)
 			c.SendWont(option)
@@ -155,12 +190,35 @@ func (c *Connection) HandleCommand(cmd, option byte) {
 				// We must explicitly ask for the terminal type
 				c.SendSubNegotiation(TType, []byte{SEND})
 			}
+		case Charset:
+			// The client may send a REQUEST of its own
+			if !c.IsRemoteOptionEnabled(Charset) {
+				c.EnableRemoteOption(Charset)
+				c.SendDo(Charset)
+			}
+		case NewEnviron:
+			if !c.IsRemoteOptionEnabled(NewEnviron) {
+				c.EnableRemoteOption(NewEnviron)
+				c.SendDo(NewEnviron)
+				c.requestEnviron()
+			}
+		case Linemode:
+			// Only if we offered it, as it's off unless configured
+			if !c.offered(Linemode) {
+				c.SendDont(Linemode)
+			} else if !c.IsRemoteOptionEnabled(Linemode) {
+				c.EnableRemoteOption(Linemode)
+				c.setLinemode(true)
+			}
 		default:
 			c.SendDont(option)
 		}
 
 	case WONT:
 		// Client refuses to do something
+		if option == Linemode {
+			c.setLinemode(false)
+		}
 		if c.IsRemoteOptionEnabled(option) {
 			c.DisableRemoteOption(option)
 			c.SendDont(option)
@@ -207,15 +265,16 @@ func (c *Connection) HandleSubNegotiation(option byte, data []byte) {
 	case TType:
 		// RFC 1091: IAC SB TTYPE IS <terminal-type-string> IAC SE
 		if len(data) > 1 && data[0] == IS {
-			ttype := string(data[1:])
-
-			c.mu.Lock()
-			c.TerminalType = ttype
-			c.mu.Unlock()
-
-			c.logger.Debug("Telnet terminal type", "type", ttype)
-			c.checkNegotiationComplete()
+			c.handleTerminalType(string(data[1:]))
 		}
+	case Charset:
+		c.handleCharset(data)
+	case GMCP:
+		c.handleGMCP(data)
+	case NewEnviron:
+		c.handleEnviron(data)
+	case Linemode:
+		c.handleLinemode(data)
 	}
 }
 
@@ -223,9 +282,9 @@ func (c *Connection) checkNegotiationComplete() {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	// We consider negotiation "complete enough" when we have both TType and Window Size
-	// OR if we've waited long enough (handled by timeout elsewhere)
-	if c.TerminalType != "" && c.WindowWidth > 0 {
+	// We consider negotiation "complete enough" when we have both TType and Window Size, and aren't waiting on more
+	// terminal types or a charset, OR if we've waited long enough (handled by timeout elsewhere)
+	if c.TerminalType != "" && c.WindowWidth > 0 && !c.ttypeCycling && !c.charsetPending {
 		c.negotiationOnce.Do(func() {
 			close(c.negotiationDone)
 		})
@@ -245,34 +304,67 @@ func (c *Connection) WaitForNegotiation(timeout time.Duration) {
 
 // EnableLocalOption marks an option as enabled for the server side
 func (c *Connection) EnableLocalOption(option byte) {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	c.localOptions[option] = OptionEnabled
 }
 
 // DisableLocalOption marks an option as disabled for the server side
 func (c *Connection) DisableLocalOption(option byte) {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	c.localOptions[option] = OptionDisabled
 }
 
 // EnableRemoteOption marks an option as enabled for the client side
 func (c *Connection) EnableRemoteOption(option byte) {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	c.remoteOptions[option] = OptionEnabled
 }
 
 // DisableRemoteOption marks an option as disabled for the client side
 func (c *Connection) DisableRemoteOption(option byte) {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	c.remoteOptions[option] = OptionDisabled
 }
 
 // IsLocalOptionEnabled checks if we have enabled a specific option
 func (c *Connection) IsLocalOptionEnabled(option byte) bool {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	return c.localOptions[option] == OptionEnabled
 }
 
 // IsRemoteOptionEnabled checks if the client has enabled a specific option
 func (c *Connection) IsRemoteOptionEnabled(option byte) bool {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	return c.remoteOptions[option] == OptionEnabled
 }
 
+// setLocalOption enables or disables an option on our side, telling the client only if that changes it and we haven't
+// told them already, so replies to our own WILL or WONT don't start a loop (RFC 1143).
+func (c *Connection) setLocalOption(option byte, enabled bool) error {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
+	changed := (c.localOptions[option] == OptionEnabled) != enabled
+	announced := c.sentWill[option] == enabled
+
+	cmd, state := WONT, OptionDisabled
+	if enabled {
+		cmd, state = WILL, OptionEnabled
+	}
+	c.localOptions[option] = state
+	c.sentWill[option] = enabled
+	if !changed || announced {
+		return nil
+	}
+	c.logCommand("OUT", cmd, option)
+	return c.writer.WriteCommand(cmd, option)
+}
+
 func (c *Connection) logCommand(direction string, cmd, option byte) {
 	cmdName := CommandNames[cmd]
 	optName := OptionNames[option]
@@ -290,6 +382,8 @@ func (c *Connection) SendCommand(cmd, option byte) error {
 
 // SendWill sends IAC WILL <option>
 func (c *Connection) SendWill(option byte) error {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	// If we already sent WILL for this option, don't send it again
 	if c.sentWill[option] {
 		return nil
@@ -303,6 +397,8 @@ func (c *Connection) SendWill(option byte) error {
 func (c *Connection) SendWont(option byte) error {
 	// We can always send WONT to be safe, or track it too.
 	// Usually WONT is final, so tracking isn't as critical for loops, but good for noise.
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	c.sentWill[option] = false // Reset WILL state
 	c.logCommand("OUT", WONT, option)
 	return c.writer.WriteCommand(WONT, option)
@@ -310,6 +406,8 @@ func (c *Connection) SendWont(option byte) error {
 
 // SendDo sends IAC DO <option>
 func (c *Connection) SendDo(option byte) error {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	if c.sentDo[option] {
 		return nil
 	}
@@ -320,11 +418,20 @@ func (c *Connection) SendDo(option byte) error {
 
 // SendDont sends IAC DONT <option>
 func (c *Connection) SendDont(option byte) error {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
 	c.sentDo[option] = false // Reset DO state
 	c.logCommand("OUT", DONT, option)
 	return c.writer.WriteCommand(DONT, option)
 }
 
+// offered returns true if we asked the client to enable the option.
+func (c *Connection) offered(option byte) bool {
+	c.optMu.Lock()
+	defer c.optMu.Unlock()
+	return c.sentDo[option]
+}
+
 // SendSubNegotiation sends a sub-negotiation sequence
 func (c *Connection) SendSubNegotiation(option byte, data []byte) error {
 	optName := OptionNames[option]
@@ -372,14 +479,28 @@ func (c *Connection) GetTerminalInfo() nodes.TerminalInfo {
 		Type:   c.TerminalType,
 		Width:  c.WindowWidth,
 		Height: c.WindowHeight,
+		Env:    maps.Clone(c.env),
 	}
 }
 
-// IsUTF8 implements the nodes.Connection interface
+// IsUTF8 implements the nodes.Connection interface. The most reliable signal the client gave is used: an agreed
+// charset, then MTTS flags, then the client's locale, and finally a guess from the terminal type.
 func (c *Connection) IsUTF8() bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
+	if c.charset != "" {
+		return normalizeCharset(c.charset) == "UTF-8"
+	}
+	if c.hasMTTS {
+		return c.mtts&MTTSUTF8 != 0
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG", "CHARSET"} {
+		if value := strings.ToUpper(c.env[name]); value != "" {
+			return strings.Contains(value, "UTF-8") || strings.Contains(value, "UTF8")
+		}
+	}
+
 	termType := strings.ToLower(c.TerminalType)
 	return strings.Contains(termType, "xterm") || strings.Contains(termType, "vscode")
 }
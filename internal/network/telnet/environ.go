@@ -0,0 +1,108 @@
+package telnet
+
+// NEW-ENVIRON (RFC 1572) lets the client pass on environment variables, such as the user's login name and terminal.
+// See https://www.rfc-editor.org/rfc/rfc1572
+//
+// After the client agrees with WILL NEW-ENVIRON, we ask for the variables we're interested in:
+//
+//	IAC SB NEW-ENVIRON SEND VAR "USER" ... USERVAR IAC SE
+//
+// A type with no name asks for all variables of that type. The client replies with IS, and may later send INFO when a
+// variable changes:
+//
+//	IAC SB NEW-ENVIRON IS VAR "USER" VALUE "bob" USERVAR "COLUMNS" VALUE "80" IAC SE
+
+import (
+	"maps"
+	"strconv"
+)
+
+// Well known variables requested from the client, along with all user variables.
+var environVars = []string{"USER", "TERM", "SYSTEMTYPE", "COLUMNS", "LINES"}
+
+// requestEnviron asks the client for its environment variables.
+func (c *Connection) requestEnviron() {
+	data := []byte{SEND}
+	for _, name := range environVars {
+		data = append(data, VAR)
+		data = append(data, name...)
+	}
+	data = append(data, USERVAR)
+	c.SendSubNegotiation(NewEnviron, data)
+}
+
+// handleEnviron records the variables sent by the client. Terminal type and window size are only filled in from them
+// if the client hasn't negotiated TTYPE or NAWS.
+func (c *Connection) handleEnviron(data []byte) {
+	if len(data) == 0 || (data[0] != IS && data[0] != INFO) {
+		return
+	}
+	vars := ParseEnviron(data[1:])
+
+	c.mu.Lock()
+	if c.env == nil {
+		c.env = make(map[string]string)
+	}
+	maps.Copy(c.env, vars)
+
+	if term := vars["TERM"]; term != "" && c.TerminalType == "" {
+		c.TerminalType = term
+	}
+	if cols, err := strconv.Atoi(vars["COLUMNS"]); err == nil && cols > 0 && !c.IsRemoteOptionEnabled(NAWS) {
+		c.WindowWidth = cols
+	}
+	if lines, err := strconv.Atoi(vars["LINES"]); err == nil && lines > 0 && !c.IsRemoteOptionEnabled(NAWS) {
+		c.WindowHeight = lines
+	}
+	c.mu.Unlock()
+
+	c.logger.Debug("Telnet environment", "vars", vars)
+	c.checkNegotiationComplete()
+}
+
+// ParseEnviron parses the variables from an IS or INFO message (without the leading IS/INFO byte). Variables without
+// a value are recorded with an empty value.
+func ParseEnviron(data []byte) map[string]string {
+	vars := make(map[string]string)
+
+	var name, value []byte
+	inValue, haveVar := false, false
+	flush := func() {
+		if haveVar && len(name) > 0 {
+			vars[string(name)] = string(value)
+		}
+		name, value = nil, nil
+		inValue = false
+	}
+
+	for i := 0; i < len(data); i++ {
+		b := data[i]
+		switch b {
+		case VAR, USERVAR:
+			flush()
+			haveVar = true
+		case VALUE:
+			inValue = true
+		default:
+			if b == ESC && i+1 < len(data) {
+				i++
+				b = data[i]
+			}
+			if inValue {
+				value = append(value, b)
+			} else {
+				name = append(name, b)
+			}
+		}
+	}
+	flush()
+
+	return vars
+}
+
+// Env returns the environment variables sent by the client.
+func (c *Connection) Env() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.env)
+}
@@ -0,0 +1,150 @@
+package telnet
+
+// GMCP (Generic MUD Communication Protocol) carries JSON messages between the server and MUD clients such as Mudlet,
+// alongside the regular terminal data. See https://tintin.mudhalla.net/protocols/gmcp/
+//
+// Each message is a package name, optionally followed by a space and JSON data:
+//
+//	IAC SB GMCP "Core.Hello {\"client\":\"Mudlet\",\"version\":\"4.17\"}" IAC SE
+//
+// Clients list the packages they want with Core.Supports.Set, Add and Remove, e.g. ["Char 1", "Room 1"].
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+const maxQueuedGMCP = 32
+
+type gmcpMessage struct {
+	pkg  string
+	data []byte
+}
+
+// SendGMCP sends a GMCP message. The data is encoded as JSON, or left off if nil.
+func (c *Connection) SendGMCP(pkg string, data any) error {
+	msg := []byte(pkg)
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		msg = append(msg, ' ')
+		msg = append(msg, encoded...)
+	}
+	return c.SendSubNegotiation(GMCP, msg)
+}
+
+// GMCPEnabled returns true if the client agreed to GMCP.
+func (c *Connection) GMCPEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.gmcpEnabled
+}
+
+func (c *Connection) setGMCPEnabled(enabled bool) {
+	c.mu.Lock()
+	c.gmcpEnabled = enabled
+	c.mu.Unlock()
+}
+
+// GMCPSupports returns true if the client asked for the package (or its top level package), e.g. "Char" or
+// "Char.Vitals". Core is always supported.
+func (c *Connection) GMCPSupports(pkg string) bool {
+	module, _, _ := strings.Cut(pkg, ".")
+	if strings.EqualFold(module, "Core") {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.ContainsFunc(c.gmcpSupports, func(s string) bool {
+		return strings.EqualFold(s, pkg) || strings.EqualFold(s, module)
+	})
+}
+
+// SetGMCPHandler sets the function called with each GMCP message from the client. Messages received before a handler
+// is set are held on to and passed on first, so it returns once the handler has been called with all of them.
+func (c *Connection) SetGMCPHandler(handler func(pkg string, data []byte)) {
+	for {
+		c.mu.Lock()
+		queued := c.gmcpQueue
+		c.gmcpQueue = nil
+		// Messages still arriving are queued until the earlier ones have been passed on, keeping them in order
+		if len(queued) == 0 {
+			c.gmcpHandler = handler
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		for _, msg := range queued {
+			handler(msg.pkg, msg.data)
+		}
+	}
+}
+
+func (c *Connection) handleGMCP(data []byte) {
+	pkg, payload, _ := bytes.Cut(data, []byte{' '})
+	msg := gmcpMessage{pkg: string(pkg), data: bytes.Clone(bytes.TrimSpace(payload))}
+	c.logger.Debug("Telnet GMCP [IN]", "package", msg.pkg, "len", len(msg.data))
+
+	switch strings.ToLower(msg.pkg) {
+	case "core.hello":
+		var hello struct {
+			Client  string `json:"client"`
+			Version string `json:"version"`
+		}
+		if json.Unmarshal(msg.data, &hello) == nil {
+			c.logger.Debug("Telnet GMCP client", "client", hello.Client, "version", hello.Version)
+		}
+	case "core.ping":
+		c.SendGMCP("Core.Ping", nil)
+	case "core.supports.set", "core.supports.add", "core.supports.remove":
+		c.updateGMCPSupports(strings.ToLower(msg.pkg), msg.data)
+	}
+
+	c.mu.Lock()
+	handler := c.gmcpHandler
+	if handler == nil && len(c.gmcpQueue) < maxQueuedGMCP {
+		c.gmcpQueue = append(c.gmcpQueue, msg)
+	}
+	c.mu.Unlock()
+
+	if handler != nil {
+		handler(msg.pkg, msg.data)
+	}
+}
+
+// updateGMCPSupports records the packages the client asked for. Entries are a package name and version, e.g. "Char 1".
+func (c *Connection) updateGMCPSupports(pkg string, data []byte) {
+	var entries []string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		c.logger.Debug("Invalid GMCP supports list", "err", err)
+		return
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name, _, _ := strings.Cut(strings.TrimSpace(entry), " ")
+		names = append(names, name)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch pkg {
+	case "core.supports.set":
+		c.gmcpSupports = names
+	case "core.supports.add":
+		for _, name := range names {
+			if !slices.Contains(c.gmcpSupports, name) {
+				c.gmcpSupports = append(c.gmcpSupports, name)
+			}
+		}
+	case "core.supports.remove":
+		c.gmcpSupports = slices.DeleteFunc(c.gmcpSupports, func(s string) bool {
+			return slices.Contains(names, s)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package telnet
+
+// LINEMODE (RFC 1184) lets the client edit a line locally and send it whole, which saves a round trip per key on slow
+// links. See https://www.rfc-editor.org/rfc/rfc1184
+//
+// It's only offered when enabled in the config. Clients that agree are kept in character-at-a-time mode, and the line
+// discipline switches EDIT on while it reads a line that isn't masked:
+//
+//	IAC SB LINEMODE MODE EDIT IAC SE
+//
+// The client acknowledges with MODE_ACK set. While editing locally the client also echoes, so we say WONT ECHO.
+// Forwarding masks and special characters aren't supported; the client's defaults are fine for a command line.
+
+// SetLineEditing asks a client that supports LINEMODE to edit lines locally, or go back to sending each key as it's
+// typed. It does nothing for other clients.
+func (c *Connection) SetLineEditing(enabled bool) {
+	c.mu.Lock()
+	if !c.linemode || c.lineEditWanted == enabled {
+		c.mu.Unlock()
+		return
+	}
+	c.lineEditWanted = enabled
+	// The client echoes while editing, so we stop, and start again afterwards. Only if it agreed to our echo in the
+	// first place, otherwise it's been echoing all along.
+	echo := !enabled && c.echoPaused
+	if enabled {
+		c.echoPaused = c.IsLocalOptionEnabled(Echo)
+	} else {
+		c.echoPaused = false
+	}
+	pause := enabled && c.echoPaused
+	c.mu.Unlock()
+
+	var mode byte
+	if enabled {
+		mode = LinemodeEdit
+	}
+	if pause || echo {
+		c.setLocalOption(Echo, echo)
+	}
+	c.SendSubNegotiation(Linemode, []byte{LinemodeMode, mode})
+}
+
+// LineEditing returns true if the client has agreed to edit lines locally.
+func (c *Connection) LineEditing() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.lineEditing
+}
+
+func (c *Connection) setLinemode(enabled bool) {
+	c.mu.Lock()
+	c.linemode = enabled
+	c.lineEditWanted = false
+	c.lineEditing = false
+	c.echoPaused = false
+	c.mu.Unlock()
+
+	// Start out a key at a time, as everything but the line discipline expects
+	if enabled {
+		c.SendSubNegotiation(Linemode, []byte{LinemodeMode, 0})
+	}
+}
+
+func (c *Connection) handleLinemode(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	switch data[0] {
+	case LinemodeMode:
+		// Only acknowledgements matter; clients shouldn't propose a mode of their own
+		if len(data) < 2 || data[1]&LinemodeModeAck == 0 {
+			return
+		}
+		editing := data[1]&LinemodeEdit != 0
+		c.logger.Debug("Telnet linemode", "edit", editing)
+		c.mu.Lock()
+		c.lineEditing = editing
+		c.mu.Unlock()
+
+	case DO, WILL:
+		// Forwarding masks are the only thing negotiated this way
+		if len(data) >= 2 && data[1] == LinemodeForwardMask {
+			refusal := WONT
+			if data[0] == WILL {
+				refusal = DONT
+			}
+			c.SendSubNegotiation(Linemode, []byte{refusal, LinemodeForwardMask})
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package telnet
+
+// MSSP lets MUD and BBS listing sites crawl the board for its details.
+// See https://tintin.mudhalla.net/protocols/mssp/
+//
+// The server offers WILL MSSP, and when the client answers DO MSSP it sends:
+//
+//	IAC SB MSSP MSSP_VAR "NAME" MSSP_VAL "value" ... IAC SE
+
+import (
+	"strconv"
+	"strings"
+
+	"euphio/internal/app"
+)
+
+// MSSPVariable is a single name/value pair reported to crawlers.
+type MSSPVariable struct {
+	Name  string
+	Value string
+}
+
+// MSSPVariables returns the board's current status from the config and nodes.
+func MSSPVariables() []MSSPVariable {
+	general := app.Config().General
+	listeners := app.Config().Listeners
+
+	online := 0
+	for _, node := range app.Nodes.Active() {
+		if node.LoggedIn() != nil {
+			online++
+		}
+	}
+
+	vars := []MSSPVariable{
+		{"NAME", general.BoardName},
+		{"PLAYERS", strconv.Itoa(online)},
+		{"UPTIME", strconv.FormatInt(app.StartedAt.Unix(), 10)},
+		{"CODEBASE", "Euphio " + app.Version},
+		{"FAMILY", "Custom"},
+		{"GENRE", "None"},
+		{"NODES", strconv.Itoa(app.Nodes.Max())},
+		{"CONNECTIONS", strconv.Itoa(len(app.Nodes.Active()))},
+		{"ANSI", "1"},
+		{"UTF-8", "1"}, // CP437 art is translated for UTF-8 terminals
+		{"VT100", "1"},
+		{"XTERM 256 COLORS", msspFlag(general.Colors == "256" || general.Colors == "truecolor")},
+		{"XTERM TRUE COLORS", msspFlag(general.Colors == "truecolor")},
+	}
+
+	optional := []MSSPVariable{
+		{"DESCRIPTION", general.Description},
+		{"HOSTNAME", general.Hostname},
+		{"WEBSITE", general.Website},
+	}
+	if listeners.Telnet.Enabled {
+		optional = append(optional, MSSPVariable{"PORT", strconv.Itoa(listeners.Telnet.Port)})
+	}
+	if listeners.Telnet.TLSEnabled {
+		optional = append(optional, MSSPVariable{"SSL", strconv.Itoa(listeners.Telnet.TLSPort)})
+	}
+	for _, v := range optional {
+		if v.Value != "" {
+			vars = append(vars, v)
+		}
+	}
+
+	return vars
+}
+
+func msspFlag(set bool) string {
+	if set {
+		return "1"
+	}
+	return "0"
+}
+
+// SendMSSP sends the variables in an MSSP sub-negotiation.
+func (c *Connection) SendMSSP(vars []MSSPVariable) error {
+	var data []byte
+	for _, v := range vars {
+		data = append(data, MSSPVAR)
+		data = append(data, msspClean(v.Name)...)
+		data = append(data, MSSPVAL)
+		data = append(data, msspClean(v.Value)...)
+	}
+	c.logger.Debug("Telnet MSSP [OUT]", "vars", len(vars))
+	return c.SendSubNegotiation(MSSP, data)
+}
+
+// msspClean removes bytes that would break the sub-negotiation framing. Invalid UTF-8 (which includes a stray IAC) is
+// replaced too.
+func msspClean(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case 0, rune(MSSPVAR), rune(MSSPVAL):
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -1,34 +1,66 @@
 package telnet
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"time"
 
 	"euphio/internal/app"
 	"euphio/internal/config"
+	"euphio/internal/network/gate"
+	"euphio/internal/network/proxyproto"
 	"euphio/internal/session"
 )
 
 type Server struct {
 	config config.TelnetConfig
+	secure bool
 	ln     net.Listener
 }
 
 func NewServer() *Server {
 	return &Server{
-		config: app.Config.Listeners.Telnet,
+		config: app.Config().Listeners.Telnet,
+	}
+}
+
+// NewTLSServer creates a server for Telnet over TLS, using the TLS port and certificate from the Telnet config.
+func NewTLSServer() *Server {
+	return &Server{
+		config: app.Config().Listeners.Telnet,
+		secure: true,
 	}
 }
 
 func (s *Server) ListenAndServe() error {
-	app.Logger.Info("Telnet server listening", "port", s.config.Port)
+	port := s.config.Port
+	if s.secure {
+		port = s.config.TLSPort
+	}
+	app.Logger.Info("Telnet server listening", "port", port, "tls", s.secure)
 
-	var err error
-	s.ln, err = net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		return err
 	}
+	// The PROXY protocol header comes before the TLS handshake
+	ln = proxyproto.NewListener(ln, s.config.ProxyProtocol)
+
+	if s.secure {
+		cert, err := tls.LoadX509KeyPair(s.config.CertFile, s.config.KeyFile)
+		if err != nil {
+			ln.Close()
+			return fmt.Errorf("loading telnet TLS certificate: %w", err)
+		}
+		ln = tls.NewListener(ln, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+	s.ln = ln
 	defer s.ln.Close()
 
 	for {
@@ -53,6 +85,35 @@ func (s *Server) Stop() error {
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
+	release, err := gate.AdmitConn(conn)
+	if err != nil {
+		app.Logger.Info("Telnet connection refused", "addr", conn.RemoteAddr(), "err", err)
+		if !errors.Is(err, gate.ErrBanned) {
+			conn.Write([]byte("Too many connections, please try again later.\r\n"))
+		}
+		conn.Close()
+		return
+	}
+	defer release()
+
+	// Complete the TLS handshake up front, so a client that never finishes it doesn't take a node
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := tlsConn.HandshakeContext(ctx)
+		cancel()
+		if err != nil {
+			app.Logger.Info("Telnet TLS handshake failed", "addr", conn.RemoteAddr(), "err", err)
+			conn.Close()
+			return
+		}
+	}
+
+	Serve(conn, s.config.InitialView)
+}
+
+// Serve runs a Telnet session over an accepted connection, blocking until the caller disconnects. Other transports
+// that carry Telnet (e.g. WebSockets) use this too, and are expected to have checked the connection with the gate.
+func Serve(conn net.Conn, initialView string) {
 	node, err := app.Nodes.Acquire()
 	if err != nil {
 		app.Logger.Warn("Connection rejected: system full", "addr", conn.RemoteAddr())
@@ -79,6 +140,13 @@ func (s *Server) handleConnection(conn net.Conn) {
 	telnetConn.SendWill(SGA)
 	telnetConn.SendDo(NAWS)
 	telnetConn.SendDo(TType)
+	telnetConn.SendDo(NewEnviron)
+	telnetConn.SendWill(Charset)
+	telnetConn.SendWill(GMCP)
+	telnetConn.SendWill(MSSP)
+	if app.Config().Listeners.Telnet.Linemode {
+		telnetConn.SendDo(Linemode)
+	}
 
 	// Wait for negotiation to complete (or timeout)
 	// This ensures we have terminal type and window size before starting the session
@@ -87,5 +155,5 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 	// Hand off to the session manager
 	// RunSession blocks until the user disconnects
-	session.RunSession(telnetConn, node, s.config.InitialView)
+	session.RunSession(telnetConn, node, initialView)
 }
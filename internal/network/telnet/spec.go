@@ -22,6 +22,7 @@ package telnet
 // - RFC 856  : Telnet End of Record Option
 // - RFC 1073 : Telnet Window Size Option
 // - RFC 1572 : Telnet Environment Option (replaces RFC 1404)
+// - RFC 2066 : Telnet Charset Option
 
 const (
 	// RFC 854: Telnet Protocol Specification
@@ -53,6 +54,25 @@ const (
 	MSSPVAR byte = 1
 	MSSPVAL byte = 2
 
+	// CHARSET Sub-negotiation Commands (RFC 2066)
+	CharsetRequest        byte = 1
+	CharsetAccepted       byte = 2
+	CharsetRejected       byte = 3
+	CharsetTTableIs       byte = 4
+	CharsetTTableRejected byte = 5
+	CharsetTTableAck      byte = 6
+	CharsetTTableNak      byte = 7
+
+	// LINEMODE Sub-negotiation Commands (RFC 1184)
+	LinemodeMode        byte = 1
+	LinemodeForwardMask byte = 2
+	LinemodeSLC         byte = 3
+
+	// LINEMODE MODE bits
+	LinemodeEdit    byte = 1
+	LinemodeTrapSig byte = 2
+	LinemodeModeAck byte = 4
+
 	// Telnet Options
 	TransmitBinary byte = 0   // RFC 854
 	Echo           byte = 1   // RFC 857
@@ -65,10 +85,11 @@ const (
 	OutputMarking  byte = 27  // RFC 933
 	NAWS           byte = 31  // RFC 1073 - Negotiate About Window Size
 	TerminalSpeed  byte = 32  // RFC 1079
-	Linemode       byte = 34  // RFC 1148
+	Linemode       byte = 34  // RFC 1184
 	NewEnvironOld  byte = 36  // Deprecated RFC 1408 'NEW-ENVIRON'
 	Encrypt        byte = 38  // RFC 2496
 	NewEnviron     byte = 39  // RFC 1572 'NEW-ENVIRON'
+	Charset        byte = 42  // RFC 2066
 	MSSP           byte = 70  // MUD Server Status Protocol
 	GMCP           byte = 201 // Generic MUD Communication Protocol
 	Exopl          byte = 255 // RFC 860 - Extended Options List
@@ -111,6 +132,7 @@ var OptionNames = map[byte]string{
 	NewEnvironOld:  "NewEnvironOld",
 	Encrypt:        "Encrypt",
 	NewEnviron:     "NewEnviron",
+	Charset:        "Charset",
 	MSSP:           "MSSP",
 	GMCP:           "GMCP",
 	Exopl:          "Exopl",
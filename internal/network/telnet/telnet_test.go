@@ -1,6 +1,8 @@
 package telnet_test
 
 import (
+	"bytes"
+	"io"
 	"net"
 	"time"
 
@@ -8,7 +10,10 @@ import (
 	. "github.com/onsi/gomega"
 
 	"euphio/internal/app"
+	"euphio/internal/config"
 	"euphio/internal/network/telnet"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
 )
 
 var _ = Describe("Telnet Protocol", func() {
@@ -32,6 +37,42 @@ var _ = Describe("Telnet Protocol", func() {
 		clientConn.Close()
 	})
 
+	// serve processes everything the client sends, as the session would
+	serve := func() {
+		conn := connection
+		go func() {
+			defer GinkgoRecover()
+			buf := make([]byte, 1024)
+			for {
+				_, err := conn.Read(buf)
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	// readUntilSE reads what the server sends up to the end of a sub-negotiation
+	readUntilSE := func() []byte {
+		var received []byte
+		buf := make([]byte, 1024)
+		for !bytes.HasSuffix(received, []byte{telnet.IAC, telnet.SE}) {
+			n, err := clientConn.Read(buf)
+			Expect(err).NotTo(HaveOccurred())
+			received = append(received, buf[:n]...)
+		}
+		return received
+	}
+
+	// subNegotiation builds IAC SB <option> <data> IAC SE
+	subNegotiation := func(option byte, data ...[]byte) []byte {
+		msg := []byte{telnet.IAC, telnet.SB, option}
+		for _, d := range data {
+			msg = append(msg, d...)
+		}
+		return append(msg, telnet.IAC, telnet.SE)
+	}
+
 	Context("Negotiation", func() {
 		It("should respond to DO ECHO with WILL ECHO", func() {
 			// Start reading on the server side to process incoming data
@@ -66,6 +107,28 @@ var _ = Describe("Telnet Protocol", func() {
 			}).Should(BeTrue())
 		})
 
+		It("should not answer DO ECHO for our own WILL ECHO, or again once it's enabled", func() {
+			go connection.SendWill(telnet.Echo)
+			buf := make([]byte, 3)
+			_, err := io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.WILL, telnet.Echo}))
+
+			serve()
+			_, err = clientConn.Write([]byte{telnet.IAC, telnet.DO, telnet.Echo, telnet.IAC, telnet.DO, telnet.Echo})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() bool {
+				return connection.IsLocalOptionEnabled(telnet.Echo)
+			}).Should(BeTrue())
+
+			// Nothing was sent in reply, so the next thing the client sees is the next thing sent
+			_, err = clientConn.Write([]byte{telnet.IAC, telnet.WILL, telnet.NAWS})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.DO, telnet.NAWS}))
+		})
+
 		It("should respond to WILL NAWS with DO NAWS", func() {
 			go func() {
 				defer GinkgoRecover()
@@ -156,4 +219,335 @@ var _ = Describe("Telnet Protocol", func() {
 			Expect(connection.WindowHeight).To(Equal(24))
 		})
 	})
+
+	Context("MSSP", func() {
+		BeforeEach(func() {
+			app.SetConfig(&config.Config{
+				General: config.GeneralConfig{BoardName: "Test BBS", Website: "https://example.com"},
+			})
+			app.Nodes = nodes.NewManager(4)
+			node, err := app.Nodes.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+			node.SetUser(&store.User{Username: "bob"})
+			_, err = app.Nodes.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should send the board status on DO MSSP", func() {
+			go func() {
+				defer GinkgoRecover()
+				buf := make([]byte, 1024)
+				for {
+					_, err := connection.Read(buf)
+					if err != nil {
+						return
+					}
+				}
+			}()
+
+			_, err := clientConn.Write([]byte{telnet.IAC, telnet.DO, telnet.MSSP})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Read until the end of the sub-negotiation
+			var received []byte
+			buf := make([]byte, 1024)
+			for !bytes.HasSuffix(received, []byte{telnet.IAC, telnet.SE}) {
+				n, err := clientConn.Read(buf)
+				Expect(err).NotTo(HaveOccurred())
+				received = append(received, buf[:n]...)
+			}
+
+			Expect(received).To(HavePrefix(string([]byte{
+				telnet.IAC, telnet.WILL, telnet.MSSP,
+				telnet.IAC, telnet.SB, telnet.MSSP,
+			})))
+
+			variable := func(name, value string) string {
+				return string(telnet.MSSPVAR) + name + string(telnet.MSSPVAL) + value
+			}
+			Expect(string(received)).To(ContainSubstring(variable("NAME", "Test BBS")))
+			Expect(string(received)).To(ContainSubstring(variable("PLAYERS", "1")))
+			Expect(string(received)).To(ContainSubstring(variable("CONNECTIONS", "2")))
+			Expect(string(received)).To(ContainSubstring(variable("NODES", "4")))
+			Expect(string(received)).To(ContainSubstring(variable("WEBSITE", "https://example.com")))
+			Expect(string(received)).NotTo(ContainSubstring("HOSTNAME"))
+			Expect(string(received)).To(ContainSubstring(variable("XTERM 256 COLORS", "0")))
+			Expect(string(received)).To(ContainSubstring(variable("XTERM TRUE COLORS", "0")))
+		})
+
+		It("should report the colours the board is configured for", func() {
+			app.Config().General.Colors = "256"
+			vars := telnet.MSSPVariables()
+			Expect(vars).To(ContainElement(telnet.MSSPVariable{Name: "XTERM 256 COLORS", Value: "1"}))
+			Expect(vars).To(ContainElement(telnet.MSSPVariable{Name: "XTERM TRUE COLORS", Value: "0"}))
+		})
+	})
+
+	Context("NEW-ENVIRON", func() {
+		It("should parse variables, values and escapes", func() {
+			data := []byte{telnet.VAR}
+			data = append(data, "USER"...)
+			data = append(data, telnet.VALUE)
+			data = append(data, "bob"...)
+			data = append(data, telnet.USERVAR)
+			data = append(data, "ODD"...)
+			data = append(data, telnet.VALUE, 'a', telnet.ESC, telnet.VAR, 'b')
+			data = append(data, telnet.VAR)
+			data = append(data, "EMPTY"...)
+
+			Expect(telnet.ParseEnviron(data)).To(Equal(map[string]string{
+				"USER":  "bob",
+				"ODD":   "a\x00b",
+				"EMPTY": "",
+			}))
+		})
+
+		It("should request variables on WILL NEW-ENVIRON and record the reply", func() {
+			go func() {
+				defer GinkgoRecover()
+				buf := make([]byte, 1024)
+				for {
+					_, err := connection.Read(buf)
+					if err != nil {
+						return
+					}
+				}
+			}()
+
+			_, err := clientConn.Write([]byte{telnet.IAC, telnet.WILL, telnet.NewEnviron})
+			Expect(err).NotTo(HaveOccurred())
+
+			var received []byte
+			buf := make([]byte, 1024)
+			for !bytes.HasSuffix(received, []byte{telnet.IAC, telnet.SE}) {
+				n, err := clientConn.Read(buf)
+				Expect(err).NotTo(HaveOccurred())
+				received = append(received, buf[:n]...)
+			}
+			Expect(received).To(HavePrefix(string([]byte{
+				telnet.IAC, telnet.DO, telnet.NewEnviron,
+				telnet.IAC, telnet.SB, telnet.NewEnviron, telnet.SEND,
+			})))
+
+			reply := []byte{telnet.IAC, telnet.SB, telnet.NewEnviron, telnet.IS, telnet.VAR}
+			reply = append(reply, "USER"...)
+			reply = append(reply, telnet.VALUE)
+			reply = append(reply, "bob"...)
+			reply = append(reply, telnet.USERVAR)
+			reply = append(reply, "COLUMNS"...)
+			reply = append(reply, telnet.VALUE)
+			reply = append(reply, "132"...)
+			reply = append(reply, telnet.IAC, telnet.SE)
+			_, err = clientConn.Write(reply)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() string {
+				return connection.Env()["USER"]
+			}).Should(Equal("bob"))
+
+			info := connection.GetTerminalInfo()
+			Expect(info.Env).To(HaveKeyWithValue("COLUMNS", "132"))
+			Expect(info.Width).To(Equal(132))
+		})
+	})
+
+	Context("CHARSET", func() {
+		It("should request UTF-8 on DO CHARSET and use the accepted charset", func() {
+			// The charset wins over the terminal type guess
+			connection.TerminalType = "xterm"
+			serve()
+
+			_, err := clientConn.Write([]byte{telnet.IAC, telnet.DO, telnet.Charset})
+			Expect(err).NotTo(HaveOccurred())
+
+			received := readUntilSE()
+			Expect(received).To(Equal([]byte(string([]byte{
+				telnet.IAC, telnet.WILL, telnet.Charset,
+				telnet.IAC, telnet.SB, telnet.Charset, telnet.CharsetRequest,
+			}) + ";UTF-8;CP437;US-ASCII" + string([]byte{telnet.IAC, telnet.SE}))))
+
+			_, err = clientConn.Write(subNegotiation(telnet.Charset, []byte{telnet.CharsetAccepted}, []byte("CP437")))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(connection.Charset).Should(Equal("CP437"))
+			Expect(connection.IsUTF8()).To(BeFalse())
+		})
+
+		It("should answer the client's request with a supported charset", func() {
+			serve()
+
+			_, err := clientConn.Write(subNegotiation(telnet.Charset, []byte{telnet.CharsetRequest}, []byte(" ISO-8859-1 utf-8")))
+			Expect(err).NotTo(HaveOccurred())
+
+			received := readUntilSE()
+			Expect(received).To(Equal(subNegotiation(telnet.Charset, []byte{telnet.CharsetAccepted}, []byte("utf-8"))))
+			Expect(connection.IsUTF8()).To(BeTrue())
+		})
+	})
+
+	Context("Terminal types", func() {
+		It("should cycle through terminal types and read MTTS flags", func() {
+			serve()
+
+			for _, ttype := range []string{"MUDLET", "ANSI-TRUECOLOR", "MTTS 271"} {
+				_, err := clientConn.Write(subNegotiation(telnet.TType, []byte{telnet.IS}, []byte(ttype)))
+				Expect(err).NotTo(HaveOccurred())
+				if ttype != "MTTS 271" {
+					// Asked for the next one
+					Expect(readUntilSE()).To(Equal(subNegotiation(telnet.TType, []byte{telnet.SEND})))
+				}
+			}
+
+			Eventually(connection.MTTS).Should(Equal(271))
+			Expect(connection.TerminalTypes()).To(Equal([]string{"MUDLET", "ANSI-TRUECOLOR", "MTTS 271"}))
+			Expect(connection.TerminalType).To(Equal("ANSI-TRUECOLOR"))
+			Expect(connection.IsUTF8()).To(BeTrue())
+		})
+
+		It("should stop when a terminal type repeats", func() {
+			serve()
+
+			_, err := clientConn.Write(subNegotiation(telnet.TType, []byte{telnet.IS}, []byte("syncterm")))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.TType, []byte{telnet.SEND})))
+
+			_, err = clientConn.Write(subNegotiation(telnet.TType, []byte{telnet.IS}, []byte("syncterm")))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(connection.TerminalTypes).Should(Equal([]string{"syncterm"}))
+			Expect(connection.MTTS()).To(Equal(0))
+		})
+	})
+
+	Context("GMCP", func() {
+		It("should agree to GMCP and track the packages the client supports", func() {
+			serve()
+
+			_, err := clientConn.Write([]byte{telnet.IAC, telnet.DO, telnet.GMCP})
+			Expect(err).NotTo(HaveOccurred())
+
+			buf := make([]byte, 3)
+			_, err = io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.WILL, telnet.GMCP}))
+			Eventually(connection.GMCPEnabled).Should(BeTrue())
+
+			_, err = clientConn.Write(subNegotiation(telnet.GMCP, []byte(`Core.Supports.Set ["Char 1", "Room.Info 1"]`)))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() bool { return connection.GMCPSupports("Char.Status") }).Should(BeTrue())
+			Expect(connection.GMCPSupports("Room.Info")).To(BeTrue())
+			Expect(connection.GMCPSupports("Room.Exits")).To(BeFalse())
+			Expect(connection.GMCPSupports("Core.Ping")).To(BeTrue())
+
+			_, err = clientConn.Write(subNegotiation(telnet.GMCP, []byte(`Core.Supports.Remove ["Char 1"]`)))
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() bool { return connection.GMCPSupports("Char.Status") }).Should(BeFalse())
+		})
+
+		It("should pass on messages received before and after the handler is set", func() {
+			serve()
+
+			_, err := clientConn.Write(subNegotiation(telnet.GMCP, []byte(`Core.Supports.Set ["Char 1"]`)))
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(func() bool { return connection.GMCPSupports("Char") }).Should(BeTrue())
+
+			received := make(chan string, 2)
+			connection.SetGMCPHandler(func(pkg string, data []byte) {
+				received <- pkg + " " + string(data)
+			})
+
+			_, err = clientConn.Write(subNegotiation(telnet.GMCP, []byte("Char.Login")))
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(received).Should(Receive(Equal(`Core.Supports.Set ["Char 1"]`)))
+			Eventually(received).Should(Receive(Equal("Char.Login ")))
+		})
+
+		It("should send messages as JSON", func() {
+			go func() {
+				defer GinkgoRecover()
+				Expect(connection.SendGMCP("Char.Status", map[string]int{"node": 2})).To(Succeed())
+			}()
+
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.GMCP, []byte(`Char.Status {"node":2}`))))
+		})
+	})
+
+	Context("LINEMODE", func() {
+		It("should refuse LINEMODE unless it was offered", func() {
+			serve()
+
+			_, err := clientConn.Write([]byte{telnet.IAC, telnet.WILL, telnet.Linemode})
+			Expect(err).NotTo(HaveOccurred())
+
+			buf := make([]byte, 3)
+			_, err = io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.DONT, telnet.Linemode}))
+		})
+
+		It("should switch local editing on and off once the client agrees", func() {
+			go connection.SendDo(telnet.Linemode)
+			buf := make([]byte, 3)
+			_, err := io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.DO, telnet.Linemode}))
+
+			serve()
+			_, err = clientConn.Write([]byte{telnet.IAC, telnet.WILL, telnet.Linemode})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, 0})))
+
+			_, err = clientConn.Write([]byte{telnet.IAC, telnet.DO, telnet.Echo})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.WILL, telnet.Echo}))
+
+			// Editing locally means the client echoes too
+			go connection.SetLineEditing(true)
+			_, err = io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.WONT, telnet.Echo}))
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, telnet.LinemodeEdit})))
+			Expect(connection.LineEditing()).To(BeFalse())
+
+			// The client's agreement isn't answered again
+			_, err = clientConn.Write([]byte{telnet.IAC, telnet.DONT, telnet.Echo})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = clientConn.Write(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, telnet.LinemodeEdit | telnet.LinemodeModeAck}))
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(connection.LineEditing).Should(BeTrue())
+			Expect(connection.IsLocalOptionEnabled(telnet.Echo)).To(BeFalse())
+
+			go connection.SetLineEditing(false)
+			_, err = io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(buf).To(Equal([]byte{telnet.IAC, telnet.WILL, telnet.Echo}))
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, 0})))
+			Expect(connection.IsLocalOptionEnabled(telnet.Echo)).To(BeTrue())
+
+			_, err = clientConn.Write(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, telnet.LinemodeModeAck}))
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(connection.LineEditing).Should(BeFalse())
+		})
+
+		It("should leave echo alone for a client that echoes itself", func() {
+			go connection.SendDo(telnet.Linemode)
+			buf := make([]byte, 3)
+			_, err := io.ReadFull(clientConn, buf)
+			Expect(err).NotTo(HaveOccurred())
+
+			serve()
+			_, err = clientConn.Write([]byte{telnet.IAC, telnet.WILL, telnet.Linemode})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, 0})))
+
+			go connection.SetLineEditing(true)
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, telnet.LinemodeEdit})))
+			go connection.SetLineEditing(false)
+			Expect(readUntilSE()).To(Equal(subNegotiation(telnet.Linemode, []byte{telnet.LinemodeMode, 0})))
+		})
+	})
 })
@@ -0,0 +1,89 @@
+package telnet
+
+// Terminal types (RFC 1091) can be cycled through by repeatedly asking with SEND, the client moving on to its next
+// type each time and repeating the last one once it runs out. MUD clients use this for MTTS, where the first type is
+// the client name, the second the terminal type, and the third "MTTS <flags>" describing what the client supports.
+// See https://tintin.mudhalla.net/protocols/mtts/
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MTTS flags
+const (
+	MTTSAnsi         = 1
+	MTTSVT100        = 2
+	MTTSUTF8         = 4
+	MTTS256Colors    = 8
+	MTTSMouse        = 16
+	MTTSOSCColors    = 32
+	MTTSScreenReader = 64
+	MTTSProxy        = 128
+	MTTSTrueColor    = 256
+	MTTSMNES         = 512
+	MTTSMSLP         = 1024
+	MTTSSSL          = 2048
+)
+
+const maxTerminalTypes = 4
+
+// handleTerminalType records a terminal type from the client, asking for the next one until the list is exhausted.
+// The first type is kept as the connection's terminal type, the client's preferred one, except for MTTS clients where
+// the first is the client's name and the terminal type comes second.
+func (c *Connection) handleTerminalType(ttype string) {
+	c.mu.Lock()
+	seen := slices.ContainsFunc(c.terminalTypes, func(t string) bool { return strings.EqualFold(t, ttype) })
+	if !seen {
+		c.terminalTypes = append(c.terminalTypes, ttype)
+	}
+	c.TerminalType = c.terminalTypes[0]
+
+	mtts, isMTTS := parseMTTS(ttype)
+	if isMTTS {
+		c.mtts = mtts
+		c.hasMTTS = true
+	}
+	if c.hasMTTS && len(c.terminalTypes) >= 3 {
+		c.TerminalType = c.terminalTypes[1]
+	}
+
+	// A repeated type (or MTTS, which is always last) ends the list
+	more := !seen && !isMTTS && len(c.terminalTypes) < maxTerminalTypes
+	c.ttypeCycling = more
+	c.mu.Unlock()
+
+	c.logger.Debug("Telnet terminal type", "type", ttype, "mtts", mtts)
+
+	if more {
+		c.SendSubNegotiation(TType, []byte{SEND})
+	}
+	c.checkNegotiationComplete()
+}
+
+func parseMTTS(ttype string) (int, bool) {
+	value, ok := strings.CutPrefix(strings.ToUpper(ttype), "MTTS ")
+	if !ok {
+		return 0, false
+	}
+	flags, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, false
+	}
+	return flags, true
+}
+
+// TerminalTypes returns all of the terminal types sent by the client, in order.
+func (c *Connection) TerminalTypes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.terminalTypes)
+}
+
+// MTTS returns the MTTS flags sent by the client, or 0 if it didn't send any.
+func (c *Connection) MTTS() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mtts
+}
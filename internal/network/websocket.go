@@ -0,0 +1,9 @@
+package network
+
+import (
+	"euphio/internal/network/websocket"
+)
+
+func NewWebSocket() *websocket.Server {
+	return websocket.NewServer()
+}
@@ -0,0 +1,71 @@
+package websocket
+
+import (
+	"net"
+	"sync"
+
+	"euphio/internal/nodes"
+)
+
+// Connection adapts a raw WebSocket (one without Telnet framing) to the nodes.Connection interface. Browser clients
+// can't negotiate terminal details, so they're provided up front in the connection URL.
+type Connection struct {
+	conn   net.Conn
+	mu     sync.RWMutex
+	width  int
+	height int
+	term   string
+}
+
+func NewConnection(conn net.Conn, term string, width, height int) *Connection {
+	return &Connection{
+		conn:   conn,
+		term:   term,
+		width:  width,
+		height: height,
+	}
+}
+
+func (c *Connection) Send(msg string) error {
+	_, err := c.conn.Write([]byte(msg + "\r\n"))
+	return err
+}
+
+func (c *Connection) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+func (c *Connection) GetTerminalInfo() nodes.TerminalInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return nodes.TerminalInfo{
+		Type:   c.term,
+		Width:  c.width,
+		Height: c.height,
+	}
+}
+
+// IsUTF8 implements the nodes.Connection interface
+func (c *Connection) IsUTF8() bool {
+	// Browser terminals (xterm.js and friends) are UTF-8
+	return true
+}
+
+// GetWidth implements the nodes.Connection interface
+func (c *Connection) GetWidth() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.width
+}
+
+func (c *Connection) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Connection) Read(p []byte) (n int, err error) {
+	return c.conn.Read(p)
+}
+
+func (c *Connection) Write(p []byte) (n int, err error) {
+	return c.conn.Write(p)
+}
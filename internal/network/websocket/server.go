@@ -0,0 +1,211 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"euphio/internal/app"
+	"euphio/internal/assets"
+	"euphio/internal/config"
+	"euphio/internal/network/gate"
+	"euphio/internal/network/proxyproto"
+	"euphio/internal/network/telnet"
+	"euphio/internal/session"
+)
+
+// Subprotocols a client can ask for. Raw sockets carry terminal data as is, while Telnet sockets carry a full Telnet
+// stream (as used by fTelnet and similar clients) and get the same negotiation as a regular Telnet connection.
+const (
+	ProtocolRaw    = "raw"
+	ProtocolTelnet = "telnet"
+)
+
+var rawProtocols = []string{ProtocolRaw, "binary", "plain"}
+
+// The parts of the vendored xterm.js the client page loads, by path, each served from web/xterm
+var terminalFiles = []string{"/xterm/xterm.js", "/xterm/xterm.css", "/xterm/addon-fit.js"}
+
+type Server struct {
+	config config.WebSocketConfig
+	server *http.Server
+	page   *template.Template
+}
+
+func NewServer() *Server {
+	s := &Server{
+		config: app.Config().Listeners.WebSocket,
+	}
+	if s.config.ServePage {
+		if _, err := fs.Stat(assets.FS, "web/xterm/xterm.js"); err != nil {
+			app.Logger.Error("WebSocket client page unavailable, as xterm.js isn't vendored: run go generate ./internal/assets")
+			return s
+		}
+		// The page is embedded, so it can only fail to parse if it's broken at build time
+		s.page = template.Must(template.ParseFS(assets.FS, "web/index.html"))
+	}
+	return s
+}
+
+func (s *Server) ListenAndServe() error {
+	app.Logger.Info("WebSocket server listening", "port", s.config.Port, "page", s.config.ServePage)
+
+	s.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", s.config.Port),
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ln, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	ln = proxyproto.NewListener(ln, s.config.ProxyProtocol)
+
+	if err := s.server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Stop() error {
+	if s.server != nil {
+		return s.server.Close()
+	}
+	return nil
+}
+
+// ServeHTTP upgrades WebSocket requests on any path, and serves the client page for everything else.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		s.servePage(w, r)
+		return
+	}
+
+	addr := remoteAddr(r)
+	release, err := gate.Admit(addr)
+	if err != nil {
+		app.Logger.Info("WebSocket connection refused", "addr", addr, "err", err)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	defer release()
+
+	websocket.Server{
+		Handshake: s.handshake,
+		Handler:   s.handleSocket,
+	}.ServeHTTP(w, r)
+}
+
+// handshake picks the subprotocol to speak. Browsers send an Origin header, but since the board is public any origin
+// is accepted.
+func (s *Server) handshake(cfg *websocket.Config, r *http.Request) error {
+	offered := cfg.Protocol
+	cfg.Protocol = nil
+	for _, protocol := range offered {
+		if protocol == ProtocolTelnet || slices.Contains(rawProtocols, protocol) {
+			cfg.Protocol = []string{protocol}
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleSocket(ws *websocket.Conn) {
+	ws.PayloadType = websocket.BinaryFrame
+	r := ws.Request()
+	conn := &socketConn{Conn: ws, addr: remoteAddr(r)}
+
+	protocol := s.config.Protocol
+	if len(ws.Config().Protocol) > 0 {
+		protocol = ws.Config().Protocol[0]
+	}
+
+	if protocol == ProtocolTelnet {
+		telnet.Serve(conn, s.config.InitialView)
+		return
+	}
+
+	node, err := app.Nodes.Acquire()
+	if err != nil {
+		app.Logger.Warn("WebSocket connection rejected: system full", "addr", conn.RemoteAddr())
+		conn.Write([]byte("System full, please try again later.\r\n"))
+		conn.Close()
+		return
+	}
+	defer app.Nodes.Release(node.ID)
+
+	query := r.URL.Query()
+	term := query.Get("term")
+	if term == "" {
+		term = "xterm"
+	}
+	wsConn := NewConnection(conn, term, queryInt(query.Get("cols"), 80), queryInt(query.Get("rows"), 24))
+	node.Conn = wsConn
+
+	logger := app.Logger.With("node", node.ID)
+	info := wsConn.GetTerminalInfo()
+
+	logger.Info("WebSocket connection established", "addr", conn.RemoteAddr(), "term", info.Type, "width", info.Width, "height", info.Height)
+	defer logger.Info("WebSocket connection closed", "addr", conn.RemoteAddr())
+	defer wsConn.Close()
+
+	// Hand off to the session manager
+	session.RunSession(wsConn, node, s.config.InitialView)
+}
+
+// servePage serves the client page and the xterm.js terminal it runs, if enabled.
+func (s *Server) servePage(w http.ResponseWriter, r *http.Request) {
+	if s.page == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := s.page.Execute(w, app.Config().General); err != nil {
+			app.Logger.Error("Failed to render WebSocket client page", "err", err)
+		}
+	default:
+		if !slices.Contains(terminalFiles, r.URL.Path) {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFileFS(w, r, assets.FS, "web"+r.URL.Path)
+	}
+}
+
+// socketConn reports the caller's real address, since websocket.Conn reports the Origin as its remote address.
+type socketConn struct {
+	*websocket.Conn
+	addr net.Addr
+}
+
+func (c *socketConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func remoteAddr(r *http.Request) net.Addr {
+	addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr)
+	if err != nil {
+		return &net.TCPAddr{}
+	}
+	return addr
+}
+
+func queryInt(value string, def int) int {
+	if n, err := strconv.Atoi(value); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,130 @@
+package websocket_test
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	xws "golang.org/x/net/websocket"
+
+	"euphio/internal/app"
+	"euphio/internal/assets"
+	"euphio/internal/config"
+	"euphio/internal/network/websocket"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+)
+
+var _ = Describe("WebSocket Server", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		var err error
+		app.Store, err = store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		app.SetConfig(&config.Config{})
+		app.Config().General.BoardName = "Test Board"
+		app.Config().Listeners.WebSocket.ServePage = true
+		app.Nodes = nodes.NewManager(1)
+	})
+
+	JustBeforeEach(func() {
+		server = httptest.NewServer(websocket.NewServer())
+		DeferCleanup(server.Close)
+	})
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	dial := func(protocols ...string) (*xws.Conn, error) {
+		cfg, err := xws.NewConfig(strings.Replace(server.URL, "http", "ws", 1)+"/?cols=100&rows=30", server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		cfg.Protocol = protocols
+		return xws.DialConfig(cfg)
+	}
+
+	Describe("client page", func() {
+		It("serves the page and the vendored xterm.js it runs, without anything from elsewhere", func() {
+			if _, err := fs.Stat(assets.FS, "web/xterm/xterm.js"); err != nil {
+				Skip("xterm.js isn't vendored, run go generate ./internal/assets")
+			}
+
+			status, body := get("/")
+			Expect(status).To(Equal(http.StatusOK))
+			Expect(body).To(ContainSubstring("<title>Test Board</title>"))
+			Expect(body).To(ContainSubstring(`<script src="/xterm/xterm.js">`))
+			Expect(body).NotTo(ContainSubstring("https://"))
+
+			for _, path := range []string{"/xterm/xterm.js", "/xterm/xterm.css", "/xterm/addon-fit.js"} {
+				status, body = get(path)
+				Expect(status).To(Equal(http.StatusOK), path)
+				Expect(body).NotTo(BeEmpty(), path)
+			}
+
+			status, _ = get("/xterm/LICENSE")
+			Expect(status).To(Equal(http.StatusNotFound))
+			status, _ = get("/other")
+			Expect(status).To(Equal(http.StatusNotFound))
+		})
+
+		Context("when disabled", func() {
+			BeforeEach(func() {
+				app.Config().Listeners.WebSocket.ServePage = false
+			})
+
+			It("serves nothing", func() {
+				status, _ := get("/")
+				Expect(status).To(Equal(http.StatusNotFound))
+				status, _ = get("/xterm/xterm.js")
+				Expect(status).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("connections", func() {
+		It("picks the first subprotocol it speaks", func() {
+			_, err := app.Nodes.Acquire()
+			Expect(err).NotTo(HaveOccurred())
+
+			ws, err := dial("chat", "raw", "telnet")
+			Expect(err).NotTo(HaveOccurred())
+			defer ws.Close()
+			Expect(ws.Config().Protocol).To(Equal([]string{"raw"}))
+
+			// With the only node taken, the caller is told the board is full
+			data, err := io.ReadAll(ws)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(ContainSubstring("System full"))
+		})
+
+		It("refuses banned addresses before upgrading", func() {
+			_, err := app.Store.AddBan("127.0.0.1", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = dial("raw")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Connection", func() {
+		It("reports the terminal the client gave", func() {
+			conn := websocket.NewConnection(nil, "xterm-256color", 100, 30)
+			info := conn.GetTerminalInfo()
+			Expect(info.Type).To(Equal("xterm-256color"))
+			Expect(info.Width).To(Equal(100))
+			Expect(info.Height).To(Equal(30))
+			Expect(conn.GetWidth()).To(Equal(100))
+			Expect(conn.IsUTF8()).To(BeTrue())
+		})
+	})
+})
@@ -1,8 +1,10 @@
 package nodes
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 type Manager struct {
@@ -57,6 +59,26 @@ func (m *Manager) Get(id int) *Node {
 	return m.nodes[id-1]
 }
 
+// Max returns the number of nodes available.
+func (m *Manager) Max() int {
+	return m.maxNodes
+}
+
+// Active returns the nodes currently in use. Their sessions run on other goroutines, so should only be read through
+// methods safe for that, e.g. LoggedIn rather than User.
+func (m *Manager) Active() []*Node {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var active []*Node
+	for _, n := range m.nodes {
+		if n != nil {
+			active = append(active, n)
+		}
+	}
+	return active
+}
+
 func (m *Manager) Broadcast(msg string) {
 	m.BroadcastExcept(msg, -1)
 }
@@ -72,3 +94,25 @@ func (m *Manager) BroadcastExcept(msg string, exceptID int) {
 		}
 	}
 }
+
+// HangupAll ends every caller's session.
+func (m *Manager) HangupAll() {
+	for _, n := range m.Active() {
+		n.Hangup()
+	}
+}
+
+// Wait blocks until every node has been released, or ctx is done. Returns false if nodes were still in use.
+func (m *Manager) Wait(ctx context.Context) bool {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for len(m.Active()) > 0 {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+	return true
+}
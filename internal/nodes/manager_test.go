@@ -0,0 +1,78 @@
+package nodes_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/nodes"
+)
+
+var _ = Describe("Manager", func() {
+	var manager *nodes.Manager
+
+	BeforeEach(func() {
+		manager = nodes.NewManager(2)
+	})
+
+	It("hands out nodes until the system is full", func() {
+		first, err := manager.Acquire()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.ID).To(Equal(1))
+		second, err := manager.Acquire()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.ID).To(Equal(2))
+
+		_, err = manager.Acquire()
+		Expect(err).To(MatchError("system full"))
+
+		manager.Release(first.ID)
+		again, err := manager.Acquire()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again.ID).To(Equal(1))
+		Expect(manager.Active()).To(ConsistOf(again, second))
+	})
+
+	Describe("Wait", func() {
+		It("returns straight away with no nodes in use", func() {
+			Expect(manager.Wait(context.Background())).To(BeTrue())
+		})
+
+		It("waits for every node to be released", func() {
+			first, _ := manager.Acquire()
+			second, _ := manager.Acquire()
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				manager.Release(first.ID)
+				time.Sleep(50 * time.Millisecond)
+				manager.Release(second.ID)
+			}()
+
+			start := time.Now()
+			Expect(manager.Wait(context.Background())).To(BeTrue())
+			Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+			Expect(manager.Active()).To(BeEmpty())
+		})
+
+		It("gives up when the context is done", func() {
+			manager.Acquire()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			Expect(manager.Wait(ctx)).To(BeFalse())
+			Expect(manager.Active()).To(HaveLen(1))
+		})
+	})
+
+	It("hangs up on every caller", func() {
+		first, _ := manager.Acquire()
+		second, _ := manager.Acquire()
+		hungUp := 0
+		first.SetHangup(func() { hungUp++ })
+		second.SetHangup(func() { hungUp++ })
+
+		manager.HangupAll()
+		Expect(hungUp).To(Equal(2))
+	})
+})
@@ -1,8 +1,10 @@
 package nodes
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"sync"
 
 	"euphio/internal/store"
 )
@@ -11,6 +13,13 @@ type TerminalInfo struct {
 	Type   string
 	Width  int
 	Height int
+	Env    map[string]string // Environment variables passed on by the client, if the protocol supports it
+
+	// Measured by probing the terminal with cursor position reports
+	Probed bool   // Whether the terminal answered, the fields below are only meaningful if it did
+	ANSI   bool   // Understands ANSI escape sequences
+	UTF8   bool   // Renders a multi-byte UTF-8 character as a single cell
+	CTerm  string // CTerm version, for SyncTERM and other CTerm based clients
 }
 
 type Connection interface {
@@ -21,10 +30,75 @@ type Connection interface {
 	GetWidth() int
 }
 
+// GMCPConnection is implemented by connections that can exchange GMCP messages with MUD clients.
+type GMCPConnection interface {
+	GMCPEnabled() bool
+	GMCPSupports(pkg string) bool
+	SendGMCP(pkg string, data any) error
+	SetGMCPHandler(handler func(pkg string, data []byte))
+}
+
+var ErrGMCPUnavailable = errors.New("GMCP is not enabled for this connection")
+
+// LineEditingConnection is implemented by connections that can hand line editing over to the client (Telnet LINEMODE).
+type LineEditingConnection interface {
+	SetLineEditing(enabled bool)
+	LineEditing() bool
+}
+
 type Node struct {
 	ID   int
 	Conn Connection
-	User *store.User
+	User *store.User       // The logged in user, set with SetUser as other goroutines read it with LoggedIn
+	Vars map[string]string // Session variables, e.g. answers to prompts, for views, ACS and templates to read
+
+	mu      sync.Mutex
+	probe   TerminalInfo      // What was learnt by probing the terminal, see SetProbe
+	hangup  func()            // Ends the caller's session, see SetHangup
+	secrets map[string]string // Answers kept out of Vars, see SetSecret
+}
+
+// TerminalInfo returns what the connection knows about the terminal, filled out with anything learnt by probing it.
+// Sizes negotiated by the protocol take precedence, as they're kept up to date when the window is resized.
+func (n *Node) TerminalInfo() TerminalInfo {
+	var info TerminalInfo
+	if n.Conn != nil {
+		info = n.Conn.GetTerminalInfo()
+	}
+	if probe := n.probed(); probe.Probed {
+		if info.Width == 0 {
+			info.Width = probe.Width
+		}
+		if info.Height == 0 {
+			info.Height = probe.Height
+		}
+		info.Probed = true
+		info.ANSI = probe.ANSI
+		info.UTF8 = probe.UTF8
+		info.CTerm = probe.CTerm
+	} else if n.Conn != nil {
+		info.UTF8 = n.Conn.IsUTF8()
+	}
+	return info
+}
+
+// Width returns the terminal width, falling back on the probed width if the connection doesn't know it.
+func (n *Node) Width() int {
+	if n.Conn != nil {
+		if width := n.Conn.GetWidth(); width > 0 {
+			return width
+		}
+	}
+	return n.probed().Width
+}
+
+// IsUTF8 returns true if the terminal renders UTF-8. Probing measures this directly, so it's preferred over what the
+// connection guesses from the protocol.
+func (n *Node) IsUTF8() bool {
+	if probe := n.probed(); probe.Probed {
+		return probe.UTF8
+	}
+	return n.Conn != nil && n.Conn.IsUTF8()
 }
 
 func (n *Node) String() string {
@@ -33,3 +107,104 @@ func (n *Node) String() string {
 	}
 	return fmt.Sprintf("Node %d (%s)", n.ID, n.Conn.RemoteAddr())
 }
+
+// SendGMCP sends a GMCP message to the caller's client, if it has GMCP enabled.
+func (n *Node) SendGMCP(pkg string, data any) error {
+	conn, ok := n.Conn.(GMCPConnection)
+	if !ok || !conn.GMCPEnabled() {
+		return ErrGMCPUnavailable
+	}
+	return conn.SendGMCP(pkg, data)
+}
+
+// GMCPSupports returns true if the caller's client has GMCP enabled and asked for the package.
+func (n *Node) GMCPSupports(pkg string) bool {
+	conn, ok := n.Conn.(GMCPConnection)
+	return ok && conn.GMCPEnabled() && conn.GMCPSupports(pkg)
+}
+
+// SetLineEditing asks the caller's client to edit lines locally, or stop doing so, if it's able to.
+func (n *Node) SetLineEditing(enabled bool) {
+	if conn, ok := n.Conn.(LineEditingConnection); ok {
+		conn.SetLineEditing(enabled)
+	}
+}
+
+// LineEditing returns true if the caller's client is editing lines locally, and echoing them itself.
+func (n *Node) LineEditing() bool {
+	conn, ok := n.Conn.(LineEditingConnection)
+	return ok && conn.LineEditing()
+}
+
+// SetProbe records what was learnt by probing the terminal, which TerminalInfo, Width and IsUTF8 then take into account.
+func (n *Node) SetProbe(info TerminalInfo) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.probe = info
+}
+
+func (n *Node) probed() TerminalInfo {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.probe
+}
+
+// SetUser logs the user in on the node, or out if it's nil.
+func (n *Node) SetUser(user *store.User) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.User = user
+}
+
+// LoggedIn returns the user logged in on the node, or nil if nobody is. Unlike reading User, it's safe to call from
+// goroutines other than the node's session, e.g. for nodes from Manager.Active. The user's details can still change
+// under the caller, so only the pointer should be relied on.
+func (n *Node) LoggedIn() *store.User {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.User
+}
+
+// SetVar sets a session variable.
+func (n *Node) SetVar(name, value string) {
+	if n.Vars == nil {
+		n.Vars = map[string]string{}
+	}
+	n.Vars[name] = value
+}
+
+// SetSecret keeps something the caller typed that isn't for showing, e.g. the answer to a password prompt. Secrets
+// aren't session variables, so ACS and templates can't read them, only code that asks for them with Secret.
+func (n *Node) SetSecret(name, value string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.secrets == nil {
+		n.secrets = map[string]string{}
+	}
+	n.secrets[name] = value
+}
+
+// Secret returns a secret kept with SetSecret, and whether there is one.
+func (n *Node) Secret(name string) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	value, ok := n.secrets[name]
+	return value, ok
+}
+
+// SetHangup sets how to end the caller's session. The session sets this when it starts.
+func (n *Node) SetHangup(hangup func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.hangup = hangup
+}
+
+// Hangup ends the caller's session, if it has started.
+func (n *Node) Hangup() {
+	n.mu.Lock()
+	hangup := n.hangup
+	n.mu.Unlock()
+	if hangup != nil {
+		hangup()
+	}
+}
@@ -0,0 +1,96 @@
+package prompts
+
+import (
+	"io"
+	"strings"
+
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/nodes"
+)
+
+// YesNoPrompt asks a question answered with Y or N, or Enter for the default: yes, unless the default is "no". The
+// answer is stored as "yes" or "no". Other keys are ignored.
+type YesNoPrompt struct {
+	cfg config.Prompt
+}
+
+func NewYesNo(cfg config.Prompt) *YesNoPrompt {
+	return &YesNoPrompt{cfg: cfg}
+}
+
+func (p *YesNoPrompt) Render(w io.Writer, node *nodes.Node) error {
+	return render(w, p.cfg, node)
+}
+
+func (p *YesNoPrompt) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (bool, bool, error) {
+	if len(input) == 0 {
+		return false, false, nil
+	}
+	for _, k := range input {
+		var answer string
+		switch {
+		case k == keys.Char('y') || k == keys.Char('Y'):
+			answer = "yes"
+		case k == keys.Char('n') || k == keys.Char('N'):
+			answer = "no"
+		case k.Code == keys.Enter:
+			answer = "yes"
+			if strings.EqualFold(p.cfg.Default, "no") {
+				answer = "no"
+			}
+		default:
+			continue
+		}
+		io.WriteString(w, strings.ToUpper(answer[:1])+answer[1:]+"\r\n")
+		store(node, p.cfg, answer)
+		return true, true, nil
+	}
+	return true, false, nil
+}
+
+// ChoicePrompt is answered with a single key from its choices, or Enter for the default if it has one. The answer is
+// stored as the key appears in the choices, whatever case it's typed in. Other keys are ignored.
+type ChoicePrompt struct {
+	cfg config.Prompt
+}
+
+func NewChoice(cfg config.Prompt) *ChoicePrompt {
+	return &ChoicePrompt{cfg: cfg}
+}
+
+func (p *ChoicePrompt) Render(w io.Writer, node *nodes.Node) error {
+	return render(w, p.cfg, node)
+}
+
+func (p *ChoicePrompt) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (bool, bool, error) {
+	if len(input) == 0 {
+		return false, false, nil
+	}
+	for _, k := range input {
+		answer := p.choice(k)
+		if answer == "" {
+			continue
+		}
+		io.WriteString(w, answer+"\r\n")
+		store(node, p.cfg, answer)
+		return true, true, nil
+	}
+	return true, false, nil
+}
+
+// choice returns the choice a key makes, or an empty string if it isn't one.
+func (p *ChoicePrompt) choice(k keys.Key) string {
+	if k.Code == keys.Enter {
+		return p.cfg.Default
+	}
+	if k.Code != keys.Rune {
+		return ""
+	}
+	for _, choice := range p.cfg.Choices {
+		if strings.EqualFold(string(choice), string(k.Rune)) {
+			return string(choice)
+		}
+	}
+	return ""
+}
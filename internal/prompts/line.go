@@ -0,0 +1,114 @@
+package prompts
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
+	"euphio/internal/nodes"
+)
+
+// LinePrompt asks for a line of text: text, a password (masked as it's typed, and kept as typed) or a number, which
+// has to be within the prompt's min and max if it has them. Text and numbers start out as the default, if there is
+// one. Answers that won't do are asked for again.
+type LinePrompt struct {
+	cfg    config.Prompt
+	editor *lineedit.Editor
+}
+
+// Longest answer if the config doesn't say
+const defaultMaxLength = 255
+
+func NewLine(cfg config.Prompt) *LinePrompt {
+	return &LinePrompt{cfg: cfg}
+}
+
+func (p *LinePrompt) Render(w io.Writer, node *nodes.Node) error {
+	if err := render(w, p.cfg, node); err != nil {
+		return err
+	}
+	p.begin(w)
+	return nil
+}
+
+// begin starts editing a new answer.
+func (p *LinePrompt) begin(w io.Writer) {
+	maxLength := p.cfg.MaxLength
+	if maxLength == 0 {
+		maxLength = defaultMaxLength
+	}
+	password := p.cfg.Type == "password"
+	p.editor = lineedit.New(lineedit.Options{MaxLength: maxLength, Masked: password})
+	if !password {
+		p.editor.SetLine(w, p.cfg.Default)
+	}
+}
+
+func (p *LinePrompt) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (bool, bool, error) {
+	if len(input) == 0 {
+		return false, false, nil
+	}
+	if p.editor == nil {
+		p.begin(w)
+	}
+
+	for _, k := range input {
+		if p.cfg.Type == "number" && k.Code == keys.Rune && (k.Rune < '0' || k.Rune > '9') && k.Rune != '-' {
+			continue
+		}
+		line, done, _ := p.editor.Feed(w, []keys.Key{k})
+		if !done {
+			continue
+		}
+
+		answer, msg := p.check(line)
+		if msg != "" {
+			io.WriteString(w, msg+"\r\n")
+			if err := renderText(w, p.cfg, node); err != nil {
+				return true, false, err
+			}
+			p.begin(w)
+			return true, false, nil
+		}
+		store(node, p.cfg, answer)
+		return true, true, nil
+	}
+	return true, false, nil
+}
+
+// check returns the answer to store for a line, or a message for the caller if it won't do.
+func (p *LinePrompt) check(line string) (string, string) {
+	switch p.cfg.Type {
+	case "password":
+		return line, ""
+
+	case "number":
+		line = strings.TrimSpace(line)
+		if line == "" {
+			line = p.cfg.Default
+		}
+		n, err := strconv.Atoi(line)
+		lo, hi := p.cfg.Min, p.cfg.Max
+		outOfRange := lo != nil && n < *lo || hi != nil && n > *hi
+		switch {
+		case (err != nil || outOfRange) && lo != nil && hi != nil:
+			return "", fmt.Sprintf("Please enter a number from %d to %d.", *lo, *hi)
+		case err != nil:
+			return "", "Please enter a number."
+		case lo != nil && n < *lo:
+			return "", fmt.Sprintf("Please enter a number of at least %d.", *lo)
+		case outOfRange:
+			return "", fmt.Sprintf("Please enter a number of at most %d.", *hi)
+		}
+		return strconv.Itoa(n), ""
+	}
+
+	if line = strings.TrimSpace(line); line == "" {
+		return p.cfg.Default, ""
+	}
+	return line, ""
+}
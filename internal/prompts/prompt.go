@@ -2,14 +2,35 @@ package prompts
 
 import (
 	"euphio/internal/ansi"
+	"euphio/internal/app"
 	"euphio/internal/config"
+	"euphio/internal/keys"
 	"euphio/internal/nodes"
 	"io"
 )
 
+// Prompt asks the caller something once a view is drawn, taking input before the view does. Prompts that get an
+// answer store it in the session variable named in their config, for later views, ACS (SV) and templates to read.
+// Passwords are kept as a secret under that name instead (see nodes.Node.SetSecret), out of their reach.
 type Prompt interface {
 	Render(w io.Writer, node *nodes.Node) error
-	HandleInput(input string, node *nodes.Node) (bool, bool, error) // handled, done, error
+	HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (bool, bool, error) // handled, done, error
+}
+
+// New creates a prompt of the type in its config, falling back on a pause for unknown types.
+func New(cfg config.Prompt) Prompt {
+	switch cfg.Type {
+	case "", "pause":
+		return NewBasic(cfg)
+	case "yesNo":
+		return NewYesNo(cfg)
+	case "choice":
+		return NewChoice(cfg)
+	case "text", "password", "number":
+		return NewLine(cfg)
+	}
+	app.Logger.Warn("Unknown prompt type, pausing instead", "type", cfg.Type)
+	return NewBasic(cfg)
 }
 
 type BasicPrompt struct {
@@ -21,25 +42,53 @@ func NewBasic(cfg config.Prompt) *BasicPrompt {
 }
 
 func (p *BasicPrompt) Render(w io.Writer, node *nodes.Node) error {
-	isUTF8 := false
-	if node.Conn != nil {
-		isUTF8 = node.Conn.IsUTF8()
+	return render(w, p.cfg, node)
+}
+
+func (p *BasicPrompt) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (bool, bool, error) {
+	if len(input) == 0 {
+		return false, false, nil
 	}
-	if p.cfg.Ansi != "" {
-		if err := ansi.RenderArt(w, p.cfg.Ansi, isUTF8); err != nil {
+	// Basic prompt (like pause) accepts any input and is done.
+	return true, true, nil
+}
+
+// render draws a prompt's art, then its text.
+func render(w io.Writer, cfg config.Prompt, node *nodes.Node) error {
+	isUTF8 := node.IsUTF8()
+	if cfg.Ansi != "" {
+		if _, err := ansi.RenderArt(w, cfg.Ansi, isUTF8, ansi.CallerVars(node)); err != nil {
 			return err
 		}
 	}
-	if p.cfg.LineFeed {
+	if cfg.LineFeed {
 		w.Write([]byte("\r\n"))
 	}
-	return nil
+	return renderText(w, cfg, node)
 }
 
-func (p *BasicPrompt) HandleInput(input string, node *nodes.Node) (bool, bool, error) {
-	if len(input) == 0 {
-		return false, false, nil
+// renderText draws a prompt's text, e.g. to ask again after an answer that won't do.
+func renderText(w io.Writer, cfg config.Prompt, node *nodes.Node) error {
+	if cfg.Text == "" {
+		return nil
 	}
-	// Basic prompt (like pause) accepts any input and is done.
-	return true, true, nil
+	text, err := ansi.RenderTemplate([]byte(cfg.Text), ansi.CallerVars(node))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(text)
+	return err
+}
+
+// store keeps the answer in the prompt's session variable, or as a secret for passwords, if it has one.
+func store(node *nodes.Node, cfg config.Prompt, answer string) {
+	if cfg.Var == "" {
+		return
+	}
+	if cfg.Type == "password" {
+		node.SetSecret(cfg.Var, answer)
+		return
+	}
+	node.SetVar(cfg.Var, answer)
+	app.Logger.Debug("Prompt answered", "node", node.ID, "var", cfg.Var, "answer", answer)
 }
@@ -0,0 +1,144 @@
+package prompts_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/acs"
+	"euphio/internal/ansi"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/nodes"
+	"euphio/internal/prompts"
+)
+
+var _ = Describe("Prompts", func() {
+	var (
+		node *nodes.Node
+		out  bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		node = &nodes.Node{ID: 1}
+		out.Reset()
+	})
+
+	// typed turns a string into keys, with \r as Enter.
+	typed := func(s string) []keys.Key {
+		var input []keys.Key
+		for _, r := range s {
+			if r == '\r' {
+				input = append(input, keys.Key{Code: keys.Enter})
+			} else {
+				input = append(input, keys.Char(r))
+			}
+		}
+		return input
+	}
+
+	// answer renders a prompt and feeds it each string in turn, returning whether it's done after the last.
+	answer := func(cfg config.Prompt, inputs ...string) bool {
+		p := prompts.New(cfg)
+		Expect(p.Render(&out, node)).To(Succeed())
+		done := false
+		for _, input := range inputs {
+			handled, d, err := p.HandleInput(&out, typed(input), node)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(handled).To(BeTrue())
+			done = d
+		}
+		return done
+	}
+
+	It("pauses for any key when it has no type", func() {
+		Expect(answer(config.Prompt{}, "x")).To(BeTrue())
+	})
+
+	It("shows its text as a template", func() {
+		node.SetVar("name", "Bob")
+		answer(config.Prompt{Text: "Hello {{ .Custom.Vars.name }}? "})
+		Expect(out.String()).To(Equal("Hello Bob? "))
+	})
+
+	Describe("yes/no", func() {
+		cfg := config.Prompt{Type: "yesNo", Var: "newscan"}
+
+		It("stores the answer, ignoring other keys", func() {
+			Expect(answer(cfg, "x")).To(BeFalse())
+			Expect(answer(cfg, "N")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("newscan", "no"))
+		})
+
+		It("takes the default at Enter", func() {
+			Expect(answer(cfg, "\r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("newscan", "yes"))
+
+			withDefault := cfg
+			withDefault.Default = "no"
+			Expect(answer(withDefault, "\r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("newscan", "no"))
+		})
+	})
+
+	Describe("choice", func() {
+		cfg := config.Prompt{Type: "choice", Var: "action", Choices: "ARQ"}
+
+		It("stores the key as it appears in the choices", func() {
+			Expect(answer(cfg, "x")).To(BeFalse())
+			Expect(answer(cfg, "r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("action", "R"))
+		})
+
+		It("only takes Enter if it has a default", func() {
+			Expect(answer(cfg, "\r")).To(BeFalse())
+			withDefault := cfg
+			withDefault.Default = "Q"
+			Expect(answer(withDefault, "\r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("action", "Q"))
+		})
+	})
+
+	Describe("text", func() {
+		It("stores the line, up to its max length", func() {
+			Expect(answer(config.Prompt{Type: "text", Var: "city", MaxLength: 5}, "  Lond", "on\r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("city", "Lon"))
+		})
+
+		It("starts out as the default", func() {
+			Expect(answer(config.Prompt{Type: "text", Var: "city", Default: "Paris"}, "\r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("city", "Paris"))
+		})
+	})
+
+	It("masks passwords and keeps them as typed, out of the session variables", func() {
+		Expect(answer(config.Prompt{Type: "password", Var: "pw"}, " s3cret\r")).To(BeTrue())
+		secret, ok := node.Secret("pw")
+		Expect(ok).To(BeTrue())
+		Expect(secret).To(Equal(" s3cret"))
+		Expect(node.Vars).NotTo(HaveKey("pw"))
+		Expect(out.String()).NotTo(ContainSubstring("s3cret"))
+
+		ok, err := acs.Check("SV[pw]", acs.ContextFor(node))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(ansi.CallerVars(node)["Vars"]).NotTo(HaveKey("pw"))
+	})
+
+	Describe("number", func() {
+		lo, hi := 1, 10
+		cfg := config.Prompt{Type: "number", Var: "lines", Text: "Lines? ", Min: &lo, Max: &hi}
+
+		It("asks again for numbers out of range", func() {
+			Expect(answer(cfg, "12\r")).To(BeFalse())
+			Expect(out.String()).To(ContainSubstring("Please enter a number from 1 to 10.\r\nLines? "))
+			Expect(node.Vars).NotTo(HaveKey("lines"))
+		})
+
+		It("ignores keys that aren't digits", func() {
+			Expect(answer(cfg, "1x0\r")).To(BeTrue())
+			Expect(node.Vars).To(HaveKeyWithValue("lines", "10"))
+		})
+	})
+})
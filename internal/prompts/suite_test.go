@@ -0,0 +1,22 @@
+package prompts_test
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+)
+
+func TestPrompts(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	app.SetConfig(&config.Config{})
+
+	RunSpecs(t, "Prompts Suite")
+}
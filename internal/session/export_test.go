@@ -0,0 +1,39 @@
+package session
+
+import "time"
+
+// Unexported helpers, for the session_test package.
+var (
+	ParseProbe = parseProbe
+	NeedsProbe = needsProbe
+	NewSession = newSession
+)
+
+func (s *Session) HandleEvent(event interface{}) {
+	s.handleEvent(event)
+}
+
+func (s *Session) Teardown() {
+	s.teardown()
+}
+
+func (s *Session) ChargeTime(now time.Time) {
+	s.chargeTime(now)
+}
+
+func (s *Session) View() string {
+	return s.vm.Current()
+}
+
+func (s *Session) CheckLimits(now time.Time) {
+	s.checkLimits(now)
+}
+
+// Ended returns true once the session has been told to end.
+func (s *Session) Ended() bool {
+	return s.ctx.Err() != nil
+}
+
+func (s *Session) KeyPressed(now time.Time) {
+	s.keyPressed(now)
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"strings"
+
+	"euphio/internal/app"
+	"euphio/internal/modules"
+	"euphio/internal/views"
+)
+
+// charStatus is sent as Char.Status whenever the caller's node status changes.
+type charStatus struct {
+	Node  int    `json:"node"`
+	Name  string `json:"name,omitempty"`
+	Level int    `json:"level,omitempty"`
+	View  string `json:"view"`
+}
+
+// roomInfo is sent as Room.Info when the caller moves to another view, which MUD clients treat as the room.
+type roomInfo struct {
+	Name string `json:"name"`
+	Area string `json:"area"`
+}
+
+// gmcpState remembers what was last sent, so only changes are sent.
+type gmcpState struct {
+	view   string
+	user   string
+	status charStatus
+}
+
+// handleGMCP passes a message from the client on to every module that wants GMCP.
+func (s *Session) handleGMCP(e views.GMCPEvent) {
+	// The client changed what it wants, so send everything again
+	if strings.HasPrefix(strings.ToLower(e.Package), "core.supports.") {
+		s.gmcp = gmcpState{}
+	}
+
+	for _, mod := range s.registry.All() {
+		handler, ok := mod.(modules.GMCPHandler)
+		if !ok {
+			continue
+		}
+		if err := handler.HandleGMCP(s.rw, s.node, e.Package, e.Data); err != nil {
+			app.Logger.Error("Module failed to handle GMCP", "module", mod.Name(), "package", e.Package, "err", err)
+		}
+	}
+}
+
+// syncGMCP tells the caller's client about changes to their view and status.
+func (s *Session) syncGMCP() {
+	node := s.node
+	view := s.vm.Current()
+
+	if view != s.gmcp.view && node.GMCPSupports("Room.Info") {
+		node.SendGMCP("Room.Info", roomInfo{Name: view, Area: app.Config().General.BoardName})
+		s.gmcp.view = view
+	}
+
+	status := charStatus{Node: node.ID, View: view}
+	if node.User != nil {
+		status.Name = node.User.Username
+		status.Level = node.User.SecurityLevel
+	}
+	if status.Name != s.gmcp.user && status.Name != "" && node.GMCPSupports("Char.Name") {
+		node.SendGMCP("Char.Name", map[string]string{"name": status.Name, "fullname": status.Name})
+		s.gmcp.user = status.Name
+	}
+	if status != s.gmcp.status && node.GMCPSupports("Char.Status") {
+		node.SendGMCP("Char.Status", status)
+		s.gmcp.status = status
+	}
+}
@@ -0,0 +1,125 @@
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"euphio/internal/app"
+)
+
+const (
+	// How often time online is saved to the caller's record
+	timeSaveInterval = time.Minute
+)
+
+// The caller is warned as each of these is passed, most time left first
+var timeWarnings = []time.Duration{5 * time.Minute, time.Minute}
+
+// limitState tracks the caller's idle time and time online.
+type limitState struct {
+	lastInput  time.Time
+	idleWarned bool
+
+	charged     time.Time     // Time online has been charged to the caller up to here
+	unsaved     time.Duration // Charged but not yet saved to their record
+	nextWarning int           // Index into timeWarnings
+}
+
+// keyPressed resets the idle timer.
+func (s *Session) keyPressed(now time.Time) {
+	s.limits.lastInput = now
+	s.limits.idleWarned = false
+}
+
+// checkLimits charges the caller for their time online, and hangs up on them if they've been idle too long or have
+// run out of time for the day. They get a warning first.
+func (s *Session) checkLimits(now time.Time) {
+	s.chargeTime(now)
+	cfg := app.Config().Limits
+
+	if cfg.IdleTimeout > 0 {
+		timeout := time.Duration(cfg.IdleTimeout) * time.Minute
+		warning := time.Duration(cfg.IdleWarning) * time.Second
+		idle := now.Sub(s.limits.lastInput)
+		switch {
+		case idle >= timeout:
+			app.Logger.Info("Hanging up on idle caller", "node", s.node.ID, "idle", idle.Round(time.Second))
+			s.rw.Write([]byte("\r\n\r\nYou've been idle too long. Goodbye!\r\n"))
+			s.cancel()
+			return
+		case warning > 0 && idle >= timeout-warning && !s.limits.idleWarned:
+			s.limits.idleWarned = true
+			fmt.Fprintf(s.rw, "\r\n\r\nAre you still there? Press a key within %d seconds to stay connected.\r\n", int((timeout-idle)/time.Second))
+		}
+	}
+
+	user := s.node.User
+	if user == nil {
+		return
+	}
+	daily := cfg.ForLevel(user.SecurityLevel).Daily()
+	if daily <= 0 {
+		return
+	}
+
+	left := user.TimeLeft(daily, now)
+	if left <= 0 {
+		app.Logger.Info("Caller out of time", "node", s.node.ID, "user", user.Username)
+		s.rw.Write([]byte("\r\n\r\nYour time is up for today. Goodbye!\r\n"))
+		s.cancel()
+		return
+	}
+
+	// Time withdrawn from the time bank re-arms the warnings
+	if left > timeWarnings[0] {
+		s.limits.nextWarning = 0
+	}
+	warn := false
+	for s.limits.nextWarning < len(timeWarnings) && left <= timeWarnings[s.limits.nextWarning] {
+		s.limits.nextWarning++
+		warn = true
+	}
+	if warn {
+		minutes := int((left + time.Minute - 1) / time.Minute)
+		fmt.Fprintf(s.rw, "\r\n\r\nYou have %d minute(s) left today.\r\n", minutes)
+	}
+}
+
+// chargeTime charges the caller for their time online since the last charge, in whole seconds, saving it to their
+// record every so often.
+func (s *Session) chargeTime(now time.Time) {
+	user := s.node.User
+	if user == nil {
+		return
+	}
+	if s.limits.charged.IsZero() {
+		s.limits.charged = now
+		return
+	}
+
+	d := now.Sub(s.limits.charged).Truncate(time.Second)
+	s.limits.charged = s.limits.charged.Add(d)
+	s.limits.unsaved += d
+	user.UseTime(d, now)
+
+	if s.limits.unsaved >= timeSaveInterval {
+		s.saveTime(now)
+	}
+}
+
+// saveTime saves the time charged to the caller to their record, and picks up the time charged by their sessions on
+// other nodes, so they share one allowance. Each session is at most timeSaveInterval behind the others.
+func (s *Session) saveTime(now time.Time) {
+	user := s.node.User
+	if user == nil || s.limits.unsaved == 0 {
+		return
+	}
+	if err := app.Store.AddTimeUsed(user.Username, s.limits.unsaved, now); err != nil {
+		app.Logger.Error("Failed to save time online", "node", s.node.ID, "user", user.Username, "err", err)
+		return
+	}
+	s.limits.unsaved = 0
+	if err := app.Store.LoadTime(user); err != nil {
+		app.Logger.Error("Failed to load time online", "node", s.node.ID, "user", user.Username, "err", err)
+	}
+}
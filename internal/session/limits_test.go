@@ -0,0 +1,144 @@
+package session_test
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/nodes"
+	"euphio/internal/session"
+	"euphio/internal/store"
+)
+
+// screen is a connection that keeps what's written to it, and never has anything to read.
+type screen struct {
+	bytes.Buffer
+}
+
+func (s *screen) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+var _ = Describe("Limits", func() {
+	var (
+		out *screen
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		app.SetConfig(&config.Config{Limits: config.LimitsConfig{
+			Levels: []config.LevelLimit{{Level: 0, MinutesPerDay: 10, TimeBank: 5}},
+		}})
+		db, err := store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		app.Store = db
+		Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+
+		out = &screen{}
+		now = time.Date(2024, 3, 1, 18, 0, 0, 0, time.Local)
+	})
+
+	// login returns a node with its own copy of bob logged in, as each session has.
+	login := func(id int) *nodes.Node {
+		user, err := app.Store.FindUserByUsername("bob")
+		Expect(err).NotTo(HaveOccurred())
+		node := &nodes.Node{ID: id}
+		node.SetUser(user)
+		return node
+	}
+
+	Describe("checkLimits", func() {
+		It("warns an idle caller, then hangs up on them", func() {
+			app.Config().Limits.IdleTimeout = 1
+			app.Config().Limits.IdleWarning = 20
+			s := session.NewSession(out, &nodes.Node{ID: 1}, "welcome")
+			start := time.Now()
+			s.KeyPressed(start)
+
+			s.CheckLimits(start.Add(30 * time.Second))
+			Expect(out.String()).To(BeEmpty())
+
+			s.CheckLimits(start.Add(45 * time.Second))
+			Expect(out.String()).To(ContainSubstring("Press a key within 15 seconds"))
+			Expect(s.Ended()).To(BeFalse())
+
+			s.CheckLimits(start.Add(time.Minute))
+			Expect(out.String()).To(ContainSubstring("You've been idle too long."))
+			Expect(s.Ended()).To(BeTrue())
+		})
+
+		It("warns the caller as their time runs out, once for each warning", func() {
+			node := login(1)
+			node.User.UseTime(5*time.Minute+30*time.Second, now)
+			s := session.NewSession(out, node, "welcome")
+
+			s.CheckLimits(now)
+			Expect(out.String()).To(ContainSubstring("You have 5 minute(s) left today."))
+			out.Reset()
+			s.CheckLimits(now.Add(time.Second))
+			Expect(out.String()).To(BeEmpty())
+
+			node.User.UseTime(4*time.Minute, now)
+			s.CheckLimits(now.Add(2 * time.Second))
+			Expect(out.String()).To(ContainSubstring("You have 1 minute(s) left today."))
+			Expect(s.Ended()).To(BeFalse())
+		})
+
+		It("hangs up on a caller out of time", func() {
+			node := login(1)
+			node.User.UseTime(10*time.Minute, now)
+			s := session.NewSession(out, node, "welcome")
+
+			s.CheckLimits(now)
+			Expect(out.String()).To(ContainSubstring("Your time is up for today."))
+			Expect(s.Ended()).To(BeTrue())
+		})
+
+		It("shares the caller's time between their sessions", func() {
+			first := session.NewSession(out, login(1), "welcome")
+			second := session.NewSession(&screen{}, login(2), "welcome")
+			first.CheckLimits(now)
+			second.CheckLimits(now)
+
+			// Each session only uses 6 minutes, but the first saves before the second does
+			first.CheckLimits(now.Add(6 * time.Minute))
+			Expect(first.Ended()).To(BeFalse())
+			second.CheckLimits(now.Add(6 * time.Minute))
+			Expect(second.Ended()).To(BeTrue())
+		})
+	})
+
+	Describe("chargeTime", func() {
+		It("charges whole seconds, saving them every so often", func() {
+			node := login(1)
+			s := session.NewSession(out, node, "welcome")
+
+			s.ChargeTime(now)
+			s.ChargeTime(now.Add(1500 * time.Millisecond))
+			Expect(node.User.TimeUsedToday(now)).To(Equal(time.Second))
+			s.ChargeTime(now.Add(2 * time.Second))
+			Expect(node.User.TimeUsedToday(now)).To(Equal(2 * time.Second))
+
+			stored, err := app.Store.FindUserByUsername("bob")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored.TimeUsedToday(now)).To(BeZero())
+
+			s.ChargeTime(now.Add(time.Minute))
+			stored, err = app.Store.FindUserByUsername("bob")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored.TimeUsedToday(now)).To(Equal(time.Minute))
+		})
+
+		It("doesn't charge callers who haven't logged in", func() {
+			s := session.NewSession(out, &nodes.Node{ID: 1}, "welcome")
+			s.ChargeTime(now)
+			s.ChargeTime(now.Add(time.Hour))
+			Expect(s.Ended()).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/nodes"
+	"euphio/internal/views"
+)
+
+// The probe asks the terminal where the cursor is (DSR, answered with a CPR) at a few points:
+//
+//  1. Where it starts, to see if it understands ANSI at all.
+//  2. After moving to 999;999, which the terminal clamps to its real size.
+//  3. After printing a two byte UTF-8 character at the top left, which moves the cursor one cell if the terminal
+//     renders UTF-8, and two if it treats each byte as a character (e.g. CP437).
+//
+// It then asks for the device attributes, which CTerm (SyncTERM) answers with CSI = 67;84;101;114;109;<version> c
+// ("CTerm" in decimal). Terminals answer in order, so the attributes also mark the end of the answers.
+const probeSequence = "\x1b7" + // Save the cursor
+	"\x1b[6n" +
+	"\x1b[999;999H\x1b[6n" +
+	"\x1b[1;1Hé\x1b[6n" +
+	"\x1b[c" +
+	"\x1b[1;1H\x1b[K\x1b8" // Erase the test character and restore the cursor
+
+const (
+	probeCPRs  = 3
+	probeGrace = 250 * time.Millisecond // How long to wait for device attributes once the positions are in
+)
+
+var (
+	cprPattern = regexp.MustCompile(`\x1b\[(\d+);(\d+)R`)
+	daPattern  = regexp.MustCompile(`\x1b\[([=?>])([\d;]*)c`)
+	ctermCodes = []string{"67", "84", "101", "114", "109"}
+
+	// Prefixes of terminal types known to understand ANSI, including MTTS types such as ANSI-TRUECOLOR
+	ansiTerminals = []string{"ansi", "xterm", "vt1", "vt2", "vt3", "vt4", "vt5", "linux", "screen", "tmux", "rxvt", "putty"}
+)
+
+// needsProbe returns false if the connection already knows the terminal's size and that it's an ANSI terminal, which
+// is most of what the probe would find out. Other callers would rather not have queries sent to their terminal.
+func needsProbe(info nodes.TerminalInfo) bool {
+	return info.Width == 0 || info.Height == 0 || !ansiTerminal(info.Type)
+}
+
+// ansiTerminal returns true for terminal types that understand ANSI escape sequences.
+func ansiTerminal(termType string) bool {
+	termType = strings.ToLower(termType)
+	for _, prefix := range ansiTerminals {
+		if strings.HasPrefix(termType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeTerminal sends the probe and waits for the answers, which arrive as input. Anything typed in the meantime, and
+// any other events, are returned so they can be handled once the session starts.
+func (s *Session) probeTerminal(timeout time.Duration) []interface{} {
+	if _, err := s.rw.Write([]byte(probeSequence)); err != nil {
+		return nil
+	}
+
+	var deferred []interface{}
+
+	var input strings.Builder
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+wait:
+	for {
+		select {
+		case event := <-s.events:
+			in, ok := event.(views.InputEvent)
+			if !ok {
+				deferred = append(deferred, event)
+				continue
+			}
+			input.WriteString(in.Input)
+			data := input.String()
+			if daPattern.MatchString(data) {
+				break wait
+			}
+			if len(cprPattern.FindAllString(data, -1)) >= probeCPRs {
+				deadline.Reset(probeGrace)
+			}
+		case <-deadline.C:
+			break wait
+		case <-s.ctx.Done():
+			break wait
+		}
+	}
+
+	data := input.String()
+	info := parseProbe(data)
+	s.node.SetProbe(info)
+	app.Logger.Debug("Terminal probed", "node", s.node.ID, "ansi", info.ANSI, "width", info.Width, "height", info.Height, "utf8", info.UTF8, "cterm", info.CTerm)
+
+	// Strip the answers, leaving whatever the caller typed
+	data = cprPattern.ReplaceAllString(data, "")
+	if typeahead := daPattern.ReplaceAllString(data, ""); typeahead != "" {
+		deferred = append(deferred, views.InputEvent{Input: typeahead})
+	}
+	return deferred
+}
+
+// parseProbe reads the terminal's answers to the probe sequence.
+func parseProbe(data string) nodes.TerminalInfo {
+	var info nodes.TerminalInfo
+
+	cprs := cprPattern.FindAllStringSubmatch(data, -1)
+	if len(cprs) == 0 {
+		return info
+	}
+	info.Probed = true
+	info.ANSI = true
+
+	if len(cprs) >= 2 {
+		info.Height, _ = strconv.Atoi(cprs[1][1])
+		info.Width, _ = strconv.Atoi(cprs[1][2])
+	}
+	if len(cprs) >= 3 {
+		col, _ := strconv.Atoi(cprs[2][2])
+		info.UTF8 = col == 2
+	}
+
+	if da := daPattern.FindStringSubmatch(data); da != nil && da[1] == "=" {
+		codes := strings.Split(da[2], ";")
+		if len(codes) > len(ctermCodes) && strings.Join(codes[:len(ctermCodes)], ";") == strings.Join(ctermCodes, ";") {
+			info.CTerm = strings.Join(codes[len(ctermCodes):], ".")
+		}
+	}
+
+	return info
+}
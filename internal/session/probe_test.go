@@ -0,0 +1,68 @@
+package session_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/nodes"
+	"euphio/internal/session"
+)
+
+var _ = Describe("Probe", func() {
+	Context("parseProbe", func() {
+		It("should find nothing if the terminal didn't answer", func() {
+			Expect(session.ParseProbe("")).To(Equal(nodes.TerminalInfo{}))
+			Expect(session.ParseProbe("hello")).To(Equal(nodes.TerminalInfo{}))
+		})
+
+		It("should read the size and UTF-8 support from the cursor positions", func() {
+			info := session.ParseProbe("\x1b[1;1R\x1b[25;80R\x1b[1;2R\x1b[?1;2c")
+			Expect(info).To(Equal(nodes.TerminalInfo{Probed: true, ANSI: true, Width: 80, Height: 25, UTF8: true}))
+		})
+
+		It("should take two cells for the test character as no UTF-8", func() {
+			info := session.ParseProbe("\x1b[1;1R\x1b[50;132R\x1b[1;3R")
+			Expect(info.UTF8).To(BeFalse())
+			Expect(info.Width).To(Equal(132))
+			Expect(info.Height).To(Equal(50))
+		})
+
+		It("should make do with only some of the answers", func() {
+			info := session.ParseProbe("\x1b[1;1R")
+			Expect(info).To(Equal(nodes.TerminalInfo{Probed: true, ANSI: true}))
+		})
+
+		It("should read the CTerm version from the device attributes", func() {
+			info := session.ParseProbe("\x1b[1;1R\x1b[25;80R\x1b[1;3R\x1b[=67;84;101;114;109;1;316c")
+			Expect(info.CTerm).To(Equal("1.316"))
+		})
+
+		It("should ignore device attributes from other terminals", func() {
+			Expect(session.ParseProbe("\x1b[1;1R\x1b[?67;84;101;114;109;1;316c").CTerm).To(BeEmpty())
+			Expect(session.ParseProbe("\x1b[1;1R\x1b[=1;2c").CTerm).To(BeEmpty())
+		})
+
+		It("should find the answers among typed keys", func() {
+			info := session.ParseProbe("a\x1b[1;1Rb\x1b[24;80Rc\x1b[1;2R")
+			Expect(info.Width).To(Equal(80))
+			Expect(info.UTF8).To(BeTrue())
+		})
+	})
+
+	Context("needsProbe", func() {
+		It("should skip terminals that report an ANSI type and a size", func() {
+			Expect(session.NeedsProbe(nodes.TerminalInfo{Type: "xterm-256color", Width: 80, Height: 24})).To(BeFalse())
+			Expect(session.NeedsProbe(nodes.TerminalInfo{Type: "ANSI-TRUECOLOR", Width: 80, Height: 24})).To(BeFalse())
+		})
+
+		It("should probe terminals missing a size", func() {
+			Expect(session.NeedsProbe(nodes.TerminalInfo{Type: "xterm", Width: 80})).To(BeTrue())
+			Expect(session.NeedsProbe(nodes.TerminalInfo{Type: "ansi"})).To(BeTrue())
+		})
+
+		It("should probe terminals of unknown types", func() {
+			Expect(session.NeedsProbe(nodes.TerminalInfo{Type: "syncterm", Width: 80, Height: 25})).To(BeTrue())
+			Expect(session.NeedsProbe(nodes.TerminalInfo{Width: 80, Height: 25})).To(BeTrue())
+		})
+	})
+})
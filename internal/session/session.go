@@ -1,11 +1,14 @@
 package session
 
 import (
+	"context"
+	"errors"
 	"io"
 	"time"
 
 	"euphio/internal/ansi"
 	"euphio/internal/app"
+	"euphio/internal/keys"
 	"euphio/internal/modules"
 	"euphio/internal/nodes"
 	"euphio/internal/views"
@@ -13,31 +16,88 @@ import (
 
 // Session represents an active user session.
 type Session struct {
-	rw   io.ReadWriter
-	node *nodes.Node
-	vm   *views.Manager
+	rw       io.ReadWriter
+	node     *nodes.Node
+	vm       *views.Manager
+	registry *modules.Registry
 	// Event channel for session-wide events
 	events chan interface{}
+	gmcp   gmcpState
+
+	// Cancelled when the caller disconnects or is hung up on
+	ctx     context.Context
+	cancel  context.CancelFunc
+	started time.Time
+	limits  limitState
+
+	decoder  *keys.Decoder
+	escTimer *time.Timer // Flushes the decoder if nothing follows an ESC
 }
 
-// RunSession starts the REPL for an authenticated user.
+// escapeTimeoutEvent is sent when nothing followed an ESC in time, so it's the Escape key
+type escapeTimeoutEvent struct{}
+
+// RunSession starts the REPL for a caller, returning once they disconnect or are hung up on. The connection is closed
+// by then.
 func RunSession(rw io.ReadWriter, node *nodes.Node, initialView string) {
+	s := newSession(rw, node, initialView)
+
+	// Messages from MUD clients arrive as session events. Any that arrived before now are passed on as the handler is
+	// set, which would fill the events channel before Run starts reading it, so it's set in the background
+	if conn, ok := node.Conn.(nodes.GMCPConnection); ok {
+		go conn.SetGMCPHandler(func(pkg string, data []byte) {
+			s.send(views.GMCPEvent{Package: pkg, Data: data})
+		})
+	}
+
+	s.Run()
+}
+
+// newSession sets up a session for the caller, starting on the initial view once it's run.
+func newSession(rw io.ReadWriter, node *nodes.Node, initialView string) *Session {
 	// Initialize Module Registry
 	registry := modules.NewRegistry()
 	registry.Register(&modules.DebugModule{})
+	registry.Register(&modules.TimeBankModule{})
+
+	viewTypes := views.NewTypeRegistry()
+	viewTypes.RegisterBuiltins()
 
 	events := make(chan interface{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
 
 	s := &Session{
-		rw:     rw,
-		node:   node,
-		vm:     views.NewManager(app.Config.Views, registry, initialView, events),
-		events: events,
+		rw:       rw,
+		node:     node,
+		vm:       views.NewManager(ctx, registry, viewTypes, initialView, events),
+		registry: registry,
+		events:   events,
+		ctx:      ctx,
+		cancel:   cancel,
+		started:  time.Now(),
+		decoder:  keys.NewDecoder(),
 	}
-	s.Run()
+	s.keyPressed(s.started)
+	node.SetHangup(cancel)
+	return s
 }
 
 func (s *Session) Run() {
+	defer s.teardown()
+
+	// Start Input Listener
+	go s.readInput()
+
+	// Find out what the terminal can do before anything is drawn
+	var deferred []interface{}
+	if cfg := app.Config().Terminal; cfg.Probe && (s.node.Conn == nil || needsProbe(s.node.Conn.GetTerminalInfo())) {
+		timeout := time.Duration(cfg.ProbeTimeout) * time.Millisecond
+		if timeout <= 0 {
+			timeout = time.Second
+		}
+		deferred = s.probeTerminal(timeout)
+	}
+
 	// Hide the cursor
 	s.rw.Write([]byte(ansi.HideCursor))
 
@@ -46,34 +106,81 @@ func (s *Session) Run() {
 		app.Logger.Error("Failed to render initial view", "view", s.vm.Current(), "err", err)
 	}
 
-	// Start Input Listener
-	go s.readInput()
+	// Handle anything that arrived while probing
+	for _, event := range deferred {
+		s.handleEvent(event)
+	}
+	s.syncGMCP()
 
 	// Main Event Loop
-	ticker := time.NewTicker(100 * time.Millisecond)
+	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
+		case <-s.ctx.Done():
+			return
 		case event := <-s.events:
 			// Handle session events (e.g., view changes, messages)
 			s.handleEvent(event)
-		case <-ticker.C:
-			// Periodic updates if needed
+			s.syncGMCP()
+		case now := <-ticker.C:
+			s.checkLimits(now)
 		}
 	}
 }
 
+// readInput passes on everything the caller sends, ending the session when the connection is closed.
 func (s *Session) readInput() {
 	buf := make([]byte, 1024)
 	for {
 		n, err := s.rw.Read(buf)
+		if n > 0 {
+			s.send(views.InputEvent{Input: string(buf[:n])})
+		}
 		if err != nil {
-			// TODO: Handle disconnect
+			if !errors.Is(err, io.EOF) && s.ctx.Err() == nil {
+				app.Logger.Debug("Session read failed", "node", s.node.ID, "err", err)
+			}
+			s.cancel()
 			return
 		}
-		if n > 0 {
-			s.events <- views.InputEvent{Input: string(buf[:n])}
+	}
+}
+
+// send passes an event to the session loop, giving up if the session has ended.
+func (s *Session) send(event interface{}) {
+	select {
+	case s.events <- event:
+	case <-s.ctx.Done():
+	}
+}
+
+// teardown runs once the session has ended, stopping anything still pending and letting modules know the caller has
+// gone before closing the connection.
+func (s *Session) teardown() {
+	s.cancel()
+	if s.escTimer != nil {
+		s.escTimer.Stop()
+	}
+	s.vm.Close()
+
+	now := time.Now()
+	s.chargeTime(now)
+	s.saveTime(now)
+
+	for _, mod := range s.registry.All() {
+		if handler, ok := mod.(modules.LogoffHandler); ok {
+			handler.Logoff(s.node)
+		}
+	}
+	if s.node.User != nil {
+		app.Logger.Info("User logged off", "node", s.node.ID, "user", s.node.User.Username, "duration", time.Since(s.started).Round(time.Second))
+	}
+
+	if closer, ok := s.rw.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			app.Logger.Error("Failed to close connection", "node", s.node.ID, "err", err)
 		}
 	}
 }
@@ -83,20 +190,54 @@ func (s *Session) handleEvent(event interface{}) {
 	case string:
 		app.Logger.Debug("Session event", "msg", e)
 	case views.ChangeViewEvent:
+		if e.From != "" && e.From != s.vm.Current() {
+			app.Logger.Debug("Ignoring ChangeViewEvent from an earlier view", "view", e.ViewID, "from", e.From)
+			break
+		}
 		app.Logger.Debug("Handling ChangeViewEvent", "view", e.ViewID)
 		s.vm.Push(e.ViewID)
 		if err := s.vm.RenderCurrent(s.rw, s.node); err != nil {
 			app.Logger.Error("Failed to render view", "view", e.ViewID, "err", err)
 		}
+	case views.DisconnectEvent:
+		app.Logger.Debug("Handling DisconnectEvent", "node", s.node.ID)
+		s.cancel()
+	case views.GMCPEvent:
+		s.handleGMCP(e)
 	case views.InputEvent:
-		handled, err := s.vm.HandleInput(s.rw, e.Input, s.node)
-		if err != nil {
-			app.Logger.Error("Error handling input", "err", err)
+		s.keyPressed(time.Now())
+		if s.escTimer != nil {
+			s.escTimer.Stop()
 		}
-		if handled {
-			if err := s.vm.RenderCurrent(s.rw, s.node); err != nil {
-				app.Logger.Error("Failed to render view after input", "err", err)
-			}
+		s.handleKeys(s.decoder.Feed([]byte(e.Input)))
+		if s.decoder.Pending() {
+			s.escTimer = time.AfterFunc(s.escapeTimeout(), func() {
+				s.send(escapeTimeoutEvent{})
+			})
 		}
+	case escapeTimeoutEvent:
+		s.handleKeys(s.decoder.Flush())
+	}
+}
+
+func (s *Session) handleKeys(input []keys.Key) {
+	if len(input) == 0 {
+		return
+	}
+	handled, err := s.vm.HandleInput(s.rw, input, s.node)
+	if err != nil {
+		app.Logger.Error("Error handling input", "err", err)
+	}
+	if handled {
+		if err := s.vm.RenderCurrent(s.rw, s.node); err != nil {
+			app.Logger.Error("Failed to render view after input", "err", err)
+		}
+	}
+}
+
+func (s *Session) escapeTimeout() time.Duration {
+	if ms := app.Config().Terminal.EscapeTimeout; ms > 0 {
+		return time.Duration(ms) * time.Millisecond
 	}
+	return 100 * time.Millisecond
 }
@@ -0,0 +1,170 @@
+package session_test
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/nodes"
+	"euphio/internal/session"
+	"euphio/internal/store"
+	"euphio/internal/views"
+)
+
+// gmcpConn is a connection from a MUD client that sent GMCP messages before the session started.
+type gmcpConn struct {
+	queued int // Messages to pass on when the handler is set
+
+	mu   sync.Mutex
+	sent []string // Packages sent to the client
+}
+
+func (c *gmcpConn) Send(msg string) error { return nil }
+func (c *gmcpConn) RemoteAddr() net.Addr  { return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)} }
+func (c *gmcpConn) GetTerminalInfo() nodes.TerminalInfo {
+	return nodes.TerminalInfo{Type: "xterm", Width: 80, Height: 24}
+}
+func (c *gmcpConn) IsUTF8() bool                 { return true }
+func (c *gmcpConn) GetWidth() int                { return 80 }
+func (c *gmcpConn) GMCPEnabled() bool            { return true }
+func (c *gmcpConn) GMCPSupports(pkg string) bool { return true }
+func (c *gmcpConn) SetGMCPHandler(handler func(string, []byte)) {
+	for range c.queued {
+		handler("Core.Supports.Set", []byte(`["Room 1"]`))
+	}
+}
+
+func (c *gmcpConn) SendGMCP(pkg string, data any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, pkg)
+	return nil
+}
+
+func (c *gmcpConn) roomInfos() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count := 0
+	for _, pkg := range c.sent {
+		if pkg == "Room.Info" {
+			count++
+		}
+	}
+	return count
+}
+
+var _ = Describe("Session", func() {
+	var (
+		serverConn net.Conn
+		clientConn net.Conn
+	)
+
+	BeforeEach(func() {
+		app.SetConfig(&config.Config{Views: map[string]config.View{
+			"welcome": {Type: "art"},
+			"main":    {Type: "art"},
+			"goodbye": {Type: "art"},
+			"logoff":  {Type: "logoff"},
+		}})
+		db, err := store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		app.Store = db
+
+		serverConn, clientConn = net.Pipe()
+		go io.Copy(io.Discard, clientConn)
+	})
+
+	AfterEach(func() {
+		clientConn.Close()
+	})
+
+	// run runs a session for the node in the background, closing the channel returned once it ends.
+	run := func(node *nodes.Node, initialView string) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			session.RunSession(serverConn, node, initialView)
+			close(done)
+		}()
+		return done
+	}
+
+	// closed returns true once the session has closed the connection.
+	closed := func() bool {
+		_, err := serverConn.Write([]byte{0})
+		return err != nil
+	}
+
+	It("should end when the caller disconnects", func() {
+		done := run(&nodes.Node{ID: 1}, "welcome")
+		Consistently(done).ShouldNot(BeClosed())
+
+		clientConn.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("should end and close the connection when the caller is hung up on", func() {
+		node := &nodes.Node{ID: 1}
+		done := run(node, "welcome")
+		Consistently(done).ShouldNot(BeClosed())
+
+		node.Hangup()
+		Eventually(done).Should(BeClosed())
+		Expect(closed()).To(BeTrue())
+	})
+
+	It("should end when the caller reaches a logoff view", func() {
+		done := run(&nodes.Node{ID: 1}, "logoff")
+		Eventually(done).Should(BeClosed())
+		Expect(closed()).To(BeTrue())
+	})
+
+	It("should ignore a change of view sent from a view the caller has left", func() {
+		s := session.NewSession(serverConn, &nodes.Node{ID: 1}, "welcome")
+		s.HandleEvent(views.ChangeViewEvent{ViewID: "goodbye", From: "main"})
+		Expect(s.View()).To(Equal("welcome"))
+
+		s.HandleEvent(views.ChangeViewEvent{ViewID: "main", From: "welcome"})
+		Expect(s.View()).To(Equal("main"))
+	})
+
+	It("should save the time online and close the connection on teardown", func() {
+		Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+		user, err := app.Store.FindUserByUsername("bob")
+		Expect(err).NotTo(HaveOccurred())
+		node := &nodes.Node{ID: 1}
+		node.SetUser(user)
+
+		s := session.NewSession(serverConn, node, "welcome")
+		now := time.Now()
+		s.ChargeTime(now.Add(-90 * time.Second))
+		s.Teardown()
+
+		Expect(closed()).To(BeTrue())
+		user, err = app.Store.FindUserByUsername("bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(user.TimeUsedToday(now)).To(BeNumerically(">=", 90*time.Second))
+	})
+
+	It("should pass on more GMCP messages than there's room for before the session started", func() {
+		conn := &gmcpConn{queued: 20}
+		node := &nodes.Node{ID: 1, Conn: conn}
+
+		done := make(chan struct{})
+		go func() {
+			session.RunSession(serverConn, node, "welcome")
+			close(done)
+		}()
+
+		// Each message asks for everything to be sent again, after the first Room.Info
+		Eventually(conn.roomInfos, 2*time.Second).Should(Equal(21))
+
+		clientConn.Close()
+		Eventually(done, 2*time.Second).Should(BeClosed())
+	})
+})
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type Ban struct {
+	gorm.Model
+	CIDR   string `gorm:"column:cidr;uniqueIndex"`
+	Reason string
+}
+
+// Network returns the parsed network the ban applies to.
+func (b *Ban) Network() (*net.IPNet, error) {
+	_, network, err := net.ParseCIDR(b.CIDR)
+	return network, err
+}
+
+// AddBan bans an IP address or CIDR range. Single addresses are stored as a /32 (or /128 for IPv6).
+func (s *Store) AddBan(cidr, reason string) (*Ban, error) {
+	normalized, err := NormalizeCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	ban := Ban{
+		CIDR:   normalized,
+		Reason: reason,
+	}
+	if err := s.DB.Create(&ban).Error; err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func (s *Store) RemoveBan(cidr string) error {
+	normalized, err := NormalizeCIDR(cidr)
+	if err != nil {
+		return err
+	}
+
+	result := s.DB.Unscoped().Where("cidr = ?", normalized).Delete(&Ban{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("ban not found")
+	}
+	return nil
+}
+
+func (s *Store) ListBans() ([]Ban, error) {
+	var bans []Ban
+	result := s.DB.Order("id").Find(&bans)
+	return bans, result.Error
+}
+
+// IsBanned checks whether any ban covers the IP address. Rather than loading every ban, it looks up each of the
+// networks the address belongs to, in the canonical form bans are stored in, so it's cheap enough for every
+// connection.
+func (s *Store) IsBanned(ip net.IP) (bool, error) {
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+
+	networks := make([]string, 0, bits+1)
+	for ones := 0; ones <= bits; ones++ {
+		mask := net.CIDRMask(ones, bits)
+		networks = append(networks, (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String())
+	}
+
+	var count int64
+	result := s.DB.Model(&Ban{}).Where("cidr IN ?", networks).Count(&count)
+	return count > 0, result.Error
+}
+
+// NormalizeCIDR turns an IP address or CIDR range into its canonical CIDR form.
+func NormalizeCIDR(cidr string) (string, error) {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return "", errors.New("invalid IP address")
+		}
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+	return network.String(), nil
+}
@@ -0,0 +1,78 @@
+package store_test
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/store"
+)
+
+var _ = Describe("Bans", func() {
+	var db *store.Store
+
+	BeforeEach(func() {
+		var err error
+		db, err = store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("normalizes addresses and ranges", func() {
+		ban, err := db.AddBan("10.0.0.1", "scanner")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ban.CIDR).To(Equal("10.0.0.1/32"))
+
+		ban, err = db.AddBan("192.168.1.77/24", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ban.CIDR).To(Equal("192.168.1.0/24"))
+
+		ban, err = db.AddBan("2001:db8::1", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ban.CIDR).To(Equal("2001:db8::1/128"))
+	})
+
+	It("rejects invalid addresses", func() {
+		_, err := db.AddBan("not-an-ip", "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("lists and removes bans", func() {
+		_, err := db.AddBan("10.0.0.1", "scanner")
+		Expect(err).NotTo(HaveOccurred())
+
+		bans, err := db.ListBans()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bans).To(HaveLen(1))
+		Expect(bans[0].Reason).To(Equal("scanner"))
+
+		Expect(db.RemoveBan("10.0.0.1/32")).To(Succeed())
+		Expect(db.RemoveBan("10.0.0.1")).To(MatchError("ban not found"))
+
+		bans, err = db.ListBans()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bans).To(BeEmpty())
+	})
+	It("checks whether an address is covered by a ban", func() {
+		for _, cidr := range []string{"10.0.0.1", "192.168.1.0/24", "2001:db8::/32"} {
+			_, err := db.AddBan(cidr, "")
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		for addr, want := range map[string]bool{
+			"10.0.0.1":      true,
+			"10.0.0.2":      false,
+			"192.168.1.200": true,
+			"192.168.2.1":   false,
+			"2001:db8::42":  true,
+			"2001:db9::1":   false,
+		} {
+			banned, err := db.IsBanned(net.ParseIP(addr))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(banned).To(Equal(want), addr)
+		}
+
+		Expect(db.RemoveBan("192.168.1.0/24")).To(Succeed())
+		Expect(db.IsBanned(net.ParseIP("192.168.1.200"))).To(BeFalse())
+	})
+})
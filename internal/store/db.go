@@ -32,7 +32,7 @@ func New(filepath string, quiet bool) (*Store, error) {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	err = db.AutoMigrate(&User{})
+	err = db.AutoMigrate(&User{}, &Group{}, &AuthorizedKey{}, &LoginFailure{}, &Ban{})
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	gossh "golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+)
+
+type AuthorizedKey struct {
+	gorm.Model
+	UserID      uint   `gorm:"index"`
+	Fingerprint string `gorm:"uniqueIndex"` // SHA256 fingerprint, a key can only belong to one user
+	PublicKey   string // In authorized_keys format
+	Comment     string
+}
+
+// AddUserKey adds a public key (in authorized_keys format) to the user's list of keys.
+func (s *Store) AddUserKey(username, authorizedKey string) (*AuthorizedKey, error) {
+	user, err := s.FindUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	pub, comment, _, _, err := gossh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, err
+	}
+
+	key := AuthorizedKey{
+		UserID:      user.ID,
+		Fingerprint: gossh.FingerprintSHA256(pub),
+		PublicKey:   strings.TrimSpace(string(gossh.MarshalAuthorizedKey(pub))),
+		Comment:     comment,
+	}
+
+	if err := s.DB.Create(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *Store) ListUserKeys(username string) ([]AuthorizedKey, error) {
+	user, err := s.FindUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []AuthorizedKey
+	result := s.DB.Where("user_id = ?", user.ID).Order("id").Find(&keys)
+	return keys, result.Error
+}
+
+// RemoveUserKey removes one of the user's keys, identified by either its ID or fingerprint.
+func (s *Store) RemoveUserKey(username, idOrFingerprint string) error {
+	user, err := s.FindUserByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	query := s.DB.Unscoped().Where("user_id = ?", user.ID)
+	if id, err := strconv.ParseUint(idOrFingerprint, 10, 64); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("fingerprint = ?", idOrFingerprint)
+	}
+
+	result := query.Delete(&AuthorizedKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("key not found")
+	}
+	return nil
+}
+
+// AuthenticateKey finds the user if the given public key is one of their authorized keys, and their account isn't
+// locked.
+func (s *Store) AuthenticateKey(username string, pub gossh.PublicKey) (*User, error) {
+	user, err := s.FindUserByUsername(username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	var key AuthorizedKey
+	result := s.DB.Where("user_id = ? AND fingerprint = ?", user.ID, gossh.FingerprintSHA256(pub)).First(&key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, errors.New("key not authorized")
+		}
+		return nil, result.Error
+	}
+
+	if user.Locked {
+		return nil, ErrAccountLocked
+	}
+
+	return user, nil
+}
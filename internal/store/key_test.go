@@ -0,0 +1,101 @@
+package store_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	gossh "golang.org/x/crypto/ssh"
+
+	"euphio/internal/store"
+)
+
+func generateKey() gossh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	key, err := gossh.NewPublicKey(pub)
+	Expect(err).NotTo(HaveOccurred())
+	return key
+}
+
+func authorizedKey(key gossh.PublicKey, comment string) string {
+	return strings.TrimSpace(string(gossh.MarshalAuthorizedKey(key))) + " " + comment
+}
+
+var _ = Describe("Authorized Keys", func() {
+	var (
+		db  *store.Store
+		key gossh.PublicKey
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, err = store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.CreateUser("keyuser", "password123")).To(Succeed())
+		Expect(db.CreateUser("otheruser", "password123")).To(Succeed())
+		key = generateKey()
+	})
+
+	Describe("AddUserKey", func() {
+		It("adds a key with its comment and fingerprint", func() {
+			added, err := db.AddUserKey("keyuser", authorizedKey(key, "me@laptop"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(added.Comment).To(Equal("me@laptop"))
+			Expect(added.Fingerprint).To(Equal(gossh.FingerprintSHA256(key)))
+
+			keys, err := db.ListUserKeys("keyuser")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(HaveLen(1))
+		})
+
+		It("rejects invalid keys", func() {
+			_, err := db.AddUserKey("keyuser", "ssh-ed25519 notakey")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects a key that already belongs to someone", func() {
+			_, err := db.AddUserKey("keyuser", authorizedKey(key, ""))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = db.AddUserKey("otheruser", authorizedKey(key, ""))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("AuthenticateKey", func() {
+		BeforeEach(func() {
+			_, err := db.AddUserKey("keyuser", authorizedKey(key, ""))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("authenticates with an authorized key", func() {
+			user, err := db.AuthenticateKey("keyuser", key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Username).To(Equal("keyuser"))
+		})
+
+		It("fails for another user's key", func() {
+			_, err := db.AuthenticateKey("otheruser", key)
+			Expect(err).To(MatchError("key not authorized"))
+		})
+
+		It("fails with an unknown key", func() {
+			_, err := db.AuthenticateKey("keyuser", generateKey())
+			Expect(err).To(MatchError("key not authorized"))
+		})
+
+		It("fails once the key is removed", func() {
+			Expect(db.RemoveUserKey("keyuser", gossh.FingerprintSHA256(key))).To(Succeed())
+			_, err := db.AuthenticateKey("keyuser", key)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails for a locked account", func() {
+			Expect(db.SetLocked("keyuser", true)).To(Succeed())
+			_, err := db.AuthenticateKey("keyuser", key)
+			Expect(err).To(MatchError(store.ErrAccountLocked))
+		})
+	})
+})
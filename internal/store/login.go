@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of keys failed logins are tracked against.
+const (
+	LoginFailureUser = "user"
+	LoginFailureIP   = "ip"
+)
+
+// LoginFailure tracks failed logins against a single key, either a username or a remote IP.
+type LoginFailure struct {
+	ID          uint   `gorm:"primarykey"`
+	Kind        string `gorm:"uniqueIndex:idx_login_failures_kind_key"`
+	Key         string `gorm:"uniqueIndex:idx_login_failures_kind_key"`
+	Count       int
+	LastFailed  time.Time
+	LockedUntil time.Time
+}
+
+// Locked returns true if logins for this key are locked out at the given time.
+func (f *LoginFailure) Locked(now time.Time) bool {
+	return now.Before(f.LockedUntil)
+}
+
+// FindLoginFailure returns the failures tracked for the key. If there are none an empty record is returned.
+func (s *Store) FindLoginFailure(kind, key string) (*LoginFailure, error) {
+	failure := LoginFailure{Kind: kind, Key: key}
+	result := s.DB.Where("kind = ? AND key = ?", kind, key).First(&failure)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, result.Error
+	}
+	return &failure, nil
+}
+
+// RecordLoginFailure increments the failure count for the key. If the last failure happened longer ago than the
+// window, the count starts over. The count is incremented in the database, so failures recorded at the same time
+// by other sessions aren't lost.
+func (s *Store) RecordLoginFailure(kind, key string, window time.Duration) (*LoginFailure, error) {
+	var failure LoginFailure
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// Writing first takes the database's write lock for the rest of the transaction
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&LoginFailure{Kind: kind, Key: key}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("kind = ? AND key = ?", kind, key).First(&failure).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+
+		count := gorm.Expr("count + 1")
+		if now.Sub(failure.LastFailed) > window && !failure.Locked(now) {
+			count = gorm.Expr("1")
+		}
+		if err := tx.Model(&failure).Updates(map[string]interface{}{"count": count, "last_failed": now}).Error; err != nil {
+			return err
+		}
+		return tx.First(&failure, failure.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &failure, nil
+}
+
+// LockLogin locks out logins for the key until the given time.
+func (s *Store) LockLogin(kind, key string, until time.Time) error {
+	return s.DB.Model(&LoginFailure{}).
+		Where("kind = ? AND key = ?", kind, key).
+		Update("locked_until", until).Error
+}
+
+// ClearLoginFailures forgets all failures (and any lockout) for the key.
+func (s *Store) ClearLoginFailures(kind, key string) error {
+	return s.DB.Where("kind = ? AND key = ?", kind, key).Delete(&LoginFailure{}).Error
+}
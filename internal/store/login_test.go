@@ -0,0 +1,87 @@
+package store_test
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/store"
+)
+
+var _ = Describe("Login Failures", func() {
+	var db *store.Store
+
+	BeforeEach(func() {
+		var err error
+		db, err = store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("returns an empty record when nothing has failed", func() {
+		failure, err := db.FindLoginFailure(store.LoginFailureIP, "10.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Count).To(Equal(0))
+		Expect(failure.Locked(time.Now())).To(BeFalse())
+	})
+
+	It("counts failures per key", func() {
+		for range 3 {
+			_, err := db.RecordLoginFailure(store.LoginFailureIP, "10.0.0.1", time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		_, err := db.RecordLoginFailure(store.LoginFailureUser, "10.0.0.1", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		failure, err := db.FindLoginFailure(store.LoginFailureIP, "10.0.0.1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Count).To(Equal(3))
+	})
+
+	It("counts failures recorded at the same time", func() {
+		var err error
+		db, err = store.New(filepath.Join(GinkgoT().TempDir(), "data.sqlite3"), true)
+		Expect(err).NotTo(HaveOccurred())
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				defer GinkgoRecover()
+				_, err := db.RecordLoginFailure(store.LoginFailureUser, "bob", time.Hour)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		}
+		wg.Wait()
+
+		failure, err := db.FindLoginFailure(store.LoginFailureUser, "bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Count).To(Equal(10))
+	})
+
+	It("starts the count over once the window has passed", func() {
+		_, err := db.RecordLoginFailure(store.LoginFailureUser, "bob", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		failure, err := db.RecordLoginFailure(store.LoginFailureUser, "bob", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Count).To(Equal(1))
+	})
+
+	It("locks and clears logins", func() {
+		_, err := db.RecordLoginFailure(store.LoginFailureUser, "bob", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.LockLogin(store.LoginFailureUser, "bob", time.Now().Add(time.Minute))).To(Succeed())
+
+		failure, err := db.FindLoginFailure(store.LoginFailureUser, "bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Locked(time.Now())).To(BeTrue())
+
+		Expect(db.ClearLoginFailures(store.LoginFailureUser, "bob")).To(Succeed())
+		failure, err = db.FindLoginFailure(store.LoginFailureUser, "bob")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(failure.Count).To(Equal(0))
+		Expect(failure.Locked(time.Now())).To(BeFalse())
+	})
+})
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrTimeBankShort = errors.New("not enough time in the time bank")
+
+// TimeUsedToday returns how long the user has been online on now's day.
+func (u *User) TimeUsedToday(now time.Time) time.Duration {
+	if !sameDay(u.TimeUsedOn, now) {
+		return 0
+	}
+	return time.Duration(u.TimeUsed) * time.Second
+}
+
+// UseTime adds to the user's time online on now's day, starting afresh on a new day. A negative duration gives time
+// back. Only whole seconds are counted.
+func (u *User) UseTime(d time.Duration, now time.Time) {
+	if !sameDay(u.TimeUsedOn, now) {
+		u.TimeUsed = 0
+		y, m, day := now.Date()
+		u.TimeUsedOn = time.Date(y, m, day, 0, 0, 0, 0, now.Location())
+	}
+	u.TimeUsed += int(d / time.Second)
+}
+
+// TimeLeft returns how long the user has left today under a daily limit. Time withdrawn from the time bank can take
+// it past the limit.
+func (u *User) TimeLeft(limit time.Duration, now time.Time) time.Duration {
+	return limit - u.TimeUsedToday(now)
+}
+
+// AddTimeUsed adds to the user's time online on now's day. Sessions keep their own copy of the user up to date with
+// UseTime and save what they've added with this, so sessions for the same user on other nodes aren't overwritten. They
+// then pick up what the others have saved with LoadTime.
+func (s *Store) AddTimeUsed(username string, d time.Duration, now time.Time) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+			return err
+		}
+		user.UseTime(d, now)
+		return saveTime(tx, &user)
+	})
+}
+
+// LoadTime refreshes the user's time online and time bank from their record, which sessions for the same user on other
+// nodes add to.
+func (s *Store) LoadTime(user *User) error {
+	var stored User
+	if err := s.DB.Select("time_used", "time_used_on", "time_bank").Where("username = ?", user.Username).First(&stored).Error; err != nil {
+		return err
+	}
+	user.TimeUsed = stored.TimeUsed
+	user.TimeUsedOn = stored.TimeUsedOn
+	user.TimeBank = stored.TimeBank
+	return nil
+}
+
+// TransferTimeBank moves minutes of the user's time today into their time bank, or out of the bank into today if
+// minutes is negative. Returns ErrTimeBankShort if the bank doesn't hold enough. Checking the user has the time to
+// deposit, and room for it, is up to the caller.
+func (s *Store) TransferTimeBank(username string, minutes int, now time.Time) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+			return err
+		}
+		if user.TimeBank+minutes < 0 {
+			return ErrTimeBankShort
+		}
+		user.TimeBank += minutes
+		user.UseTime(time.Duration(minutes)*time.Minute, now)
+		return saveTime(tx, &user)
+	})
+}
+
+func saveTime(tx *gorm.DB, user *User) error {
+	return tx.Model(user).Updates(map[string]interface{}{
+		"time_used":    user.TimeUsed,
+		"time_used_on": user.TimeUsedOn,
+		"time_bank":    user.TimeBank,
+	}).Error
+}
+
+// sameDay checks whether t falls on the same day as now, in now's time zone.
+func sameDay(t, now time.Time) bool {
+	y1, m1, d1 := t.In(now.Location()).Date()
+	y2, m2, d2 := now.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
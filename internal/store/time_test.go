@@ -0,0 +1,81 @@
+package store_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/store"
+)
+
+var _ = Describe("Time Limits", func() {
+	var (
+		db  *store.Store
+		now time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, err = store.New(":memory:", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db.CreateUser("caller", "password")).To(Succeed())
+		now = time.Date(2024, 3, 1, 18, 0, 0, 0, time.Local)
+	})
+
+	find := func() *store.User {
+		user, err := db.FindUserByUsername("caller")
+		Expect(err).NotTo(HaveOccurred())
+		return user
+	}
+
+	It("adds up time used in a day", func() {
+		Expect(db.AddTimeUsed("caller", 10*time.Minute, now)).To(Succeed())
+		Expect(db.AddTimeUsed("caller", 5*time.Minute, now.Add(time.Hour))).To(Succeed())
+
+		user := find()
+		Expect(user.TimeUsedToday(now)).To(Equal(15 * time.Minute))
+		Expect(user.TimeLeft(time.Hour, now)).To(Equal(45 * time.Minute))
+	})
+
+	It("starts afresh each day", func() {
+		Expect(db.AddTimeUsed("caller", 10*time.Minute, now)).To(Succeed())
+		tomorrow := now.Add(8 * time.Hour)
+		Expect(find().TimeUsedToday(tomorrow)).To(BeZero())
+
+		Expect(db.AddTimeUsed("caller", time.Minute, tomorrow)).To(Succeed())
+		Expect(find().TimeUsedToday(tomorrow)).To(Equal(time.Minute))
+	})
+
+	It("deposits and withdraws time from the time bank", func() {
+		Expect(db.TransferTimeBank("caller", 20, now)).To(Succeed())
+		user := find()
+		Expect(user.TimeBank).To(Equal(20))
+		Expect(user.TimeLeft(time.Hour, now)).To(Equal(40 * time.Minute))
+
+		// Withdrawing on another day takes the caller past their limit
+		tomorrow := now.Add(24 * time.Hour)
+		Expect(db.TransferTimeBank("caller", -15, tomorrow)).To(Succeed())
+		user = find()
+		Expect(user.TimeBank).To(Equal(5))
+		Expect(user.TimeLeft(time.Hour, tomorrow)).To(Equal(75 * time.Minute))
+	})
+
+	It("refuses to withdraw more than the bank holds", func() {
+		Expect(db.TransferTimeBank("caller", 5, now)).To(Succeed())
+		Expect(db.TransferTimeBank("caller", -6, now)).To(MatchError(store.ErrTimeBankShort))
+		Expect(find().TimeBank).To(Equal(5))
+	})
+
+	It("loads time saved by other sessions into a copy of the user", func() {
+		user := find()
+		user.UseTime(5*time.Minute, now)
+		Expect(db.AddTimeUsed("caller", 5*time.Minute, now)).To(Succeed())
+		Expect(db.AddTimeUsed("caller", 10*time.Minute, now)).To(Succeed())
+		Expect(db.TransferTimeBank("caller", 5, now)).To(Succeed())
+
+		Expect(db.LoadTime(user)).To(Succeed())
+		Expect(user.TimeUsedToday(now)).To(Equal(20 * time.Minute))
+		Expect(user.TimeBank).To(Equal(5))
+	})
+})
@@ -2,38 +2,109 @@ package store
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// DefaultSecurityLevel is the security level given to newly created users.
+const DefaultSecurityLevel = 10
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrAccountLocked   = errors.New("account locked")
+	ErrUserExists      = errors.New("username already taken")
+)
+
 type User struct {
 	gorm.Model
-	Username     string `gorm:"uniqueIndex"` // Add an index for fast lookups
-	PasswordHash string
+	Username      string `gorm:"uniqueIndex"` // Add an index for fast lookups
+	PasswordHash  string
+	SecurityLevel int       `gorm:"default:10"`
+	Flags         string    // Access flags, a letter each (e.g. "ACZ")
+	Groups        []Group   `gorm:"many2many:user_groups"`
+	Validated     bool      // Set once the sysop has validated the account
+	Locked        bool      // Locked accounts can't log in
+	AuthFactor    int       `gorm:"default:1"` // Factors required to log in: 1 for a password or key, 2 for a second factor too
+	TimeUsed      int       // Seconds online on TimeUsedOn, less any time withdrawn from the time bank
+	TimeUsedOn    time.Time // The day TimeUsed is for
+	TimeBank      int       // Minutes saved in the time bank
+	Profile
+}
 
-	// Future-proofing: GORM Association example
-	// Posts []Post
+// Profile is what the user tells us about themselves.
+type Profile struct {
+	RealName string
+	Location string
+	Email    string
+	Birthday time.Time // Zero if not given
+}
+
+type Group struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex"`
+}
+
+// GroupNames returns the names of the groups the user belongs to.
+func (u *User) GroupNames() []string {
+	names := make([]string, len(u.Groups))
+	for i, group := range u.Groups {
+		names[i] = group.Name
+	}
+	return names
+}
+
+// Status returns a short description of the account status.
+func (u *User) Status() string {
+	switch {
+	case u.DeletedAt.Valid:
+		return "deleted"
+	case u.Locked:
+		return "locked"
+	case u.Validated:
+		return "validated"
+	default:
+		return "unvalidated"
+	}
 }
 
 func (s *Store) CreateUser(username, password string) error {
+	return s.CreateUserWithProfile(username, password, Profile{})
+}
+
+// CreateUserWithProfile creates a user with their profile in one go, returning ErrUserExists if the username is
+// taken, including by a deleted user.
+func (s *Store) CreateUserWithProfile(username, password string, profile Profile) error {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
 	if err != nil {
 		return err
 	}
 
 	user := User{
-		Username:     username,
-		PasswordHash: string(bytes),
+		Username:      username,
+		PasswordHash:  string(bytes),
+		SecurityLevel: DefaultSecurityLevel,
+		Profile:       profile,
 	}
 
-	result := s.DB.Create(&user)
-	return result.Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&User{}).Where("username = ?", username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserExists
+		}
+		return tx.Create(&user).Error
+	})
 }
 
 func (s *Store) FindUserByUsername(username string) (*User, error) {
 	var user User
-	result := s.DB.Where("username = ?", username).First(&user)
+	result := s.DB.Preload("Groups").Where("username = ?", username).First(&user)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -47,9 +118,19 @@ func (s *Store) RenameUser(oldName, newName string) error {
 }
 
 func (s *Store) RemoveUser(username string) error {
-	return s.DB.Unscoped().
-		Where("username = ?", username).
-		Delete(&User{}).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&AuthorizedKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Association("Groups").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&user).Error
+	})
 }
 
 func (s *Store) UpdatePassword(username, newPassword string) error {
@@ -66,19 +147,110 @@ func (s *Store) UpdatePassword(username, newPassword string) error {
 func (s *Store) Authenticate(username, password string) (*User, error) {
 	var user User
 
-	result := s.DB.Where("username = ?", username).First(&user)
+	result := s.DB.Preload("Groups").Where("username = ?", username).First(&user)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		return nil, result.Error
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid password")
+		return nil, ErrInvalidPassword
+	}
+
+	if user.Locked {
+		return nil, ErrAccountLocked
 	}
 
 	return &user, nil
 }
+
+// DeleteUser marks a user as deleted without removing their record. Deleted users can't log in and their username
+// stays reserved.
+func (s *Store) DeleteUser(username string) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Delete(&User{})
+	})
+}
+
+func (s *Store) SetSecurityLevel(username string, level int) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Update("security_level", level)
+	})
+}
+
+func (s *Store) SetFlags(username, flags string) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Update("flags", strings.ToUpper(flags))
+	})
+}
+
+// SetAuthFactor sets the authentication factors the user is required to log in with (see User.AuthFactor).
+func (s *Store) SetAuthFactor(username string, factor int) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Update("auth_factor", factor)
+	})
+}
+
+func (s *Store) SetLocked(username string, locked bool) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Update("locked", locked)
+	})
+}
+
+func (s *Store) SetValidated(username string, validated bool) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Update("validated", validated)
+	})
+}
+
+// AddUserToGroup adds the user to the group, creating the group if it doesn't exist yet.
+func (s *Store) AddUserToGroup(username, groupName string) error {
+	user, err := s.FindUserByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	var group Group
+	if err := s.DB.Where(Group{Name: groupName}).FirstOrCreate(&group).Error; err != nil {
+		return err
+	}
+
+	return s.DB.Model(user).Association("Groups").Append(&group)
+}
+
+func (s *Store) RemoveUserFromGroup(username, groupName string) error {
+	user, err := s.FindUserByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	var group Group
+	if err := s.DB.Where("name = ?", groupName).First(&group).Error; err != nil {
+		return err
+	}
+
+	return s.DB.Model(user).Association("Groups").Delete(&group)
+}
+
+// UpdateProfile replaces the user's profile.
+func (s *Store) UpdateProfile(username string, profile Profile) error {
+	return s.updateUser(username, func(tx *gorm.DB) *gorm.DB {
+		return tx.Select("real_name", "location", "email", "birthday").Updates(User{Profile: profile})
+	})
+}
+
+// updateUser applies an update to a single user, returning gorm.ErrRecordNotFound if there's no such user.
+func (s *Store) updateUser(username string, update func(tx *gorm.DB) *gorm.DB) error {
+	result := update(s.DB.Model(&User{}).Where("username = ?", username))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
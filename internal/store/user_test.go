@@ -1,6 +1,8 @@
 package store_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -32,11 +34,39 @@ var _ = Describe("User Model", func() {
 			It("returns an error", func() {
 				_ = db.CreateUser("dupe", "pass")
 				err := db.CreateUser("dupe", "pass")
-				Expect(err).To(HaveOccurred())
+				Expect(err).To(MatchError(store.ErrUserExists))
+			})
+
+			It("returns an error for a deleted user's name too", func() {
+				Expect(db.CreateUser("gone", "pass")).To(Succeed())
+				Expect(db.DeleteUser("gone")).To(Succeed())
+				Expect(db.CreateUser("gone", "pass")).To(MatchError(store.ErrUserExists))
 			})
 		})
 	})
 
+	Describe("CreateUserWithProfile", func() {
+		It("creates the user with their profile", func() {
+			Expect(db.CreateUserWithProfile("pat", "password123", store.Profile{RealName: "Pat", Location: "Leeds"})).To(Succeed())
+
+			user, err := db.Authenticate("pat", "password123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.RealName).To(Equal("Pat"))
+			Expect(user.Location).To(Equal("Leeds"))
+			Expect(user.SecurityLevel).To(Equal(store.DefaultSecurityLevel))
+		})
+
+		It("leaves an existing user alone", func() {
+			Expect(db.CreateUserWithProfile("pat", "password123", store.Profile{RealName: "Pat"})).To(Succeed())
+			Expect(db.CreateUserWithProfile("pat", "other", store.Profile{RealName: "Someone else"})).
+				To(MatchError(store.ErrUserExists))
+
+			user, err := db.Authenticate("pat", "password123")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.RealName).To(Equal("Pat"))
+		})
+	})
+
 	Describe("Authenticate", func() {
 		BeforeEach(func() {
 			_ = db.CreateUser("validuser", "secretpass")
@@ -58,4 +88,88 @@ var _ = Describe("User Model", func() {
 			Expect(err).To(MatchError("user not found"))
 		})
 	})
+
+	Describe("UpdateProfile", func() {
+		BeforeEach(func() {
+			_ = db.CreateUser("profiled", "secretpass")
+		})
+
+		It("replaces the profile, including with blanks", func() {
+			birthday := time.Date(1990, 4, 1, 0, 0, 0, 0, time.UTC)
+			Expect(db.UpdateProfile("profiled", store.Profile{RealName: "Pat", Email: "pat@example.com", Birthday: birthday})).To(Succeed())
+			Expect(db.UpdateProfile("profiled", store.Profile{RealName: "Pat Smith", Birthday: birthday})).To(Succeed())
+
+			user, err := db.FindUserByUsername("profiled")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.RealName).To(Equal("Pat Smith"))
+			Expect(user.Email).To(BeEmpty())
+			Expect(user.Birthday.Equal(birthday)).To(BeTrue())
+		})
+
+		It("returns an error for an unknown user", func() {
+			Expect(db.UpdateProfile("nobody", store.Profile{RealName: "Nobody"})).To(HaveOccurred())
+		})
+	})
+
+	Describe("Access", func() {
+		BeforeEach(func() {
+			_ = db.CreateUser("member", "secretpass")
+		})
+
+		It("gives new users the default security level", func() {
+			user, err := db.FindUserByUsername("member")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.SecurityLevel).To(Equal(store.DefaultSecurityLevel))
+			Expect(user.AuthFactor).To(Equal(1))
+			Expect(user.Status()).To(Equal("unvalidated"))
+		})
+
+		It("sets the authentication factors required", func() {
+			Expect(db.SetAuthFactor("member", 2)).To(Succeed())
+			user, err := db.FindUserByUsername("member")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.AuthFactor).To(Equal(2))
+		})
+
+		It("sets the security level and flags", func() {
+			Expect(db.SetSecurityLevel("member", 50)).To(Succeed())
+			Expect(db.SetFlags("member", "az")).To(Succeed())
+
+			user, err := db.FindUserByUsername("member")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.SecurityLevel).To(Equal(50))
+			Expect(user.Flags).To(Equal("AZ"))
+		})
+
+		It("returns an error when updating an unknown user", func() {
+			Expect(db.SetSecurityLevel("nobody", 50)).To(HaveOccurred())
+		})
+
+		It("adds and removes groups", func() {
+			Expect(db.AddUserToGroup("member", "users")).To(Succeed())
+			Expect(db.AddUserToGroup("member", "demo")).To(Succeed())
+
+			user, err := db.FindUserByUsername("member")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.GroupNames()).To(ConsistOf("users", "demo"))
+
+			Expect(db.RemoveUserFromGroup("member", "demo")).To(Succeed())
+			user, err = db.Authenticate("member", "secretpass")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.GroupNames()).To(ConsistOf("users"))
+		})
+
+		It("refuses to authenticate locked users", func() {
+			Expect(db.SetLocked("member", true)).To(Succeed())
+			_, err := db.Authenticate("member", "secretpass")
+			Expect(err).To(MatchError(store.ErrAccountLocked))
+		})
+
+		It("refuses to authenticate deleted users but keeps the username reserved", func() {
+			Expect(db.DeleteUser("member")).To(Succeed())
+			_, err := db.Authenticate("member", "secretpass")
+			Expect(err).To(MatchError("user not found"))
+			Expect(db.CreateUser("member", "secretpass")).NotTo(Succeed())
+		})
+	})
 })
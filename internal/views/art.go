@@ -0,0 +1,27 @@
+package views
+
+import (
+	"io"
+
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/nodes"
+)
+
+// ArtView is the plainest view: its art (drawn by the manager, as for every view), and actions for the keys pressed.
+// It's the type of views that don't give one and don't name a module.
+type ArtView struct {
+	cfg config.View
+}
+
+func NewArtView(env Env) *ArtView {
+	return &ArtView{cfg: env.Config}
+}
+
+func (v *ArtView) Render(w io.Writer, node *nodes.Node) error {
+	return nil
+}
+
+func (v *ArtView) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	return actionFor(v.cfg, keyNames(input), node), nil
+}
@@ -0,0 +1,13 @@
+package views
+
+// Unexported helpers, for the views_test package.
+var (
+	NextView = nextView
+	Allowed  = allowed
+	ViewType = viewType
+)
+
+// ValidateField checks an answer for a form field with the options given, see formField.validate.
+func ValidateField(opts map[string]interface{}, input string, values map[string]string) (string, string) {
+	return newFormField(opts, 0).validate(input, values)
+}
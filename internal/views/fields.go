@@ -0,0 +1,221 @@
+package views
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"euphio/internal/app"
+)
+
+// formField is a field of a form, read from the view's options. See FormView for what can be set.
+type formField struct {
+	name     string
+	kind     string // text, masked, numeric, date or select
+	label    string
+	mci      string
+	width    int
+	required bool
+
+	minLength int
+	maxLength int
+	regex     *regexp.Regexp
+	invalid   string // Message for values that don't match regex
+	broken    bool   // The regex wouldn't compile, so nothing can be checked against it
+	unique    string // What the value has to be unique among, only "username" for now
+	matches   string // Name of a field the value has to match, e.g. to confirm a password
+
+	min, max *int // For numeric fields
+
+	format  string // For date fields, e.g. YYYY-MM-DD
+	choices []string
+}
+
+// dateTokens turns a date format for callers into a layout for time.Parse.
+var dateTokens = strings.NewReplacer("YYYY", "2006", "MM", "01", "DD", "02")
+
+// Dates are kept in this layout, whatever the field's format
+const dateLayout = "2006-01-02"
+
+func newFormField(opts map[string]interface{}, index int) formField {
+	f := formField{
+		name:      optionString(opts, "name", fmt.Sprintf("field%d", index+1)),
+		kind:      optionString(opts, "type", "text"),
+		label:     optionString(opts, "label", ""),
+		mci:       optionString(opts, "mci", fmt.Sprintf("ET%d", index+1)),
+		width:     optionInt(opts, "width", 0),
+		required:  optionBool(opts, "required", false),
+		minLength: optionInt(opts, "minLength", 0),
+		maxLength: optionInt(opts, "maxLength", 0),
+		invalid:   optionString(opts, "invalid", ""),
+		unique:    optionString(opts, "unique", ""),
+		matches:   optionString(opts, "matches", ""),
+		format:    optionString(opts, "format", "YYYY-MM-DD"),
+	}
+
+	if expr := optionString(opts, "regex", ""); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			app.Logger.Error("Invalid form field regex", "field", f.name, "regex", expr, "err", err)
+			f.broken = true
+		}
+		f.regex = re
+	}
+	if _, ok := opts["min"]; ok {
+		n := optionInt(opts, "min", 0)
+		f.min = &n
+	}
+	if _, ok := opts["max"]; ok {
+		n := optionInt(opts, "max", 0)
+		f.max = &n
+	}
+	if choices, ok := opts["choices"].([]interface{}); ok {
+		for _, choice := range choices {
+			f.choices = append(f.choices, fmt.Sprint(choice))
+		}
+	}
+
+	switch f.kind {
+	case "text", "masked", "numeric", "date":
+	case "select":
+		if len(f.choices) == 0 {
+			app.Logger.Error("Select form field has no choices", "field", f.name)
+			f.kind = "text"
+		}
+	default:
+		app.Logger.Warn("Unknown form field type, taking it as text", "field", f.name, "type", f.kind)
+		f.kind = "text"
+	}
+	return f
+}
+
+// title names the field in messages, from its label.
+func (f formField) title() string {
+	if title := strings.TrimSpace(strings.TrimRight(f.label, ": ")); title != "" {
+		return title
+	}
+	return f.name
+}
+
+// displayWidth returns how much room the field takes on screen.
+func (f formField) displayWidth() int {
+	switch {
+	case f.width > 0:
+		return f.width
+	case f.kind == "select":
+		width := 0
+		for _, choice := range f.choices {
+			width = max(width, utf8.RuneCountInString(choice))
+		}
+		return width
+	case f.kind == "date":
+		return len(f.format)
+	case f.maxLength > 0:
+		return f.maxLength
+	}
+	return 20
+}
+
+// display returns how a value is shown to the caller.
+func (f formField) display(value string) string {
+	switch f.kind {
+	case "masked":
+		return strings.Repeat("*", utf8.RuneCountInString(value))
+	case "date":
+		if t, err := time.Parse(dateLayout, value); err == nil {
+			return t.Format(dateTokens.Replace(f.format))
+		}
+	}
+	return value
+}
+
+// accepts returns true if the field takes a typed character.
+func (f formField) accepts(r rune) bool {
+	if f.kind == "numeric" {
+		return r >= '0' && r <= '9' || r == '-'
+	}
+	return true
+}
+
+// validate checks what the caller entered, returning the value to keep, or a message for the caller if it won't do.
+// values holds the fields entered so far.
+func (f formField) validate(input string, values map[string]string) (string, string) {
+	// Rather than take anything, refuse answers to a field whose checks are broken until the config is fixed
+	if f.broken {
+		return "", fmt.Sprintf("%s can't be checked right now, sorry.", f.title())
+	}
+	if f.kind != "masked" {
+		input = strings.TrimSpace(input)
+	}
+	if input == "" {
+		if f.required {
+			return "", fmt.Sprintf("%s is required.", f.title())
+		}
+		return "", ""
+	}
+
+	length := utf8.RuneCountInString(input)
+	switch {
+	case f.minLength > 0 && length < f.minLength:
+		return "", fmt.Sprintf("%s must be at least %d characters.", f.title(), f.minLength)
+	case f.maxLength > 0 && length > f.maxLength:
+		return "", fmt.Sprintf("%s must be at most %d characters.", f.title(), f.maxLength)
+	case f.regex != nil && !f.regex.MatchString(input):
+		if f.invalid != "" {
+			return "", f.invalid
+		}
+		return "", fmt.Sprintf("%s isn't valid.", f.title())
+	}
+
+	value := input
+	switch f.kind {
+	case "numeric":
+		n, err := strconv.Atoi(input)
+		if err != nil {
+			return "", fmt.Sprintf("%s must be a number.", f.title())
+		}
+		switch {
+		case f.min != nil && f.max != nil && (n < *f.min || n > *f.max):
+			return "", fmt.Sprintf("%s must be from %d to %d.", f.title(), *f.min, *f.max)
+		case f.min != nil && n < *f.min:
+			return "", fmt.Sprintf("%s must be at least %d.", f.title(), *f.min)
+		case f.max != nil && n > *f.max:
+			return "", fmt.Sprintf("%s must be at most %d.", f.title(), *f.max)
+		}
+		value = strconv.Itoa(n)
+
+	case "date":
+		t, err := time.Parse(dateTokens.Replace(f.format), input)
+		if err != nil {
+			return "", fmt.Sprintf("%s must be a date like %s.", f.title(), f.format)
+		}
+		value = t.Format(dateLayout)
+
+	case "select":
+		if !slices.Contains(f.choices, input) {
+			return "", fmt.Sprintf("%s must be one of %s.", f.title(), strings.Join(f.choices, ", "))
+		}
+	}
+
+	if f.matches != "" && values[f.matches] != value {
+		return "", fmt.Sprintf("%s doesn't match.", f.title())
+	}
+
+	switch f.unique {
+	case "":
+	case "username":
+		if reservedUsername(value) {
+			return "", "That username is reserved."
+		}
+		if _, err := app.Store.FindUserByUsername(value); err == nil {
+			return "", "That username is already taken."
+		}
+	default:
+		app.Logger.Warn("Unknown form field uniqueness check", "field", f.name, "unique", f.unique)
+	}
+	return value, ""
+}
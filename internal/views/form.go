@@ -0,0 +1,330 @@
+package views
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"euphio/internal/ansi"
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
+	"euphio/internal/nodes"
+)
+
+// FormView asks the caller for each of its fields in turn, checking each answer as it's entered, then hands them to
+// its handler (see FormHandler) and moves on to the next view. Shift-Tab or the up arrow go back a field. Escape and
+// function keys can match an action, e.g. to leave the form.
+//
+// If the art has the first field's MCI code, fields are drawn where the art has their codes (%ET1 for the first field,
+// %ET2 for the second, and so on) with the art providing the labels. Otherwise each field is asked for on a line of
+// its own, after its label.
+//
+// Supported options:
+//
+//	handler: what to do with the answers: newUser, profile or password (see formHandlers)
+//	message: the MCI code where messages for the caller go, for fields drawn in the art (default: the line after the
+//	         last field)
+//	fields:  the fields, each with:
+//	  name:      the answer's name, which the handler looks for
+//	  type:      text, masked, numeric, date or select (default text)
+//	  label:     shown before the field, when it isn't drawn in the art
+//	  mci:       the MCI code where the field goes (default ET<n>)
+//	  width:     room for the field on screen (default maxLength, or 20)
+//	  required:  whether it has to be answered (default false)
+//	  minLength: shortest answer
+//	  maxLength: longest answer, which also limits what can be typed
+//	  regex:     a regular expression answers must match, with a message for those that don't in `invalid`
+//	  unique:    "username" for answers that can't be the name of an existing user, or a login view's newKeyword
+//	  matches:   the name of an earlier field the answer must match, e.g. to confirm a password
+//	  min, max:  the range for numeric fields
+//	  format:    how dates are typed, using YYYY, MM and DD (default YYYY-MM-DD)
+//	  choices:   for select fields, which are changed with the arrow keys or space, or by typing the first letter
+type FormView struct {
+	cfg     config.View
+	handler FormHandler
+	fields  []formField
+
+	values  map[string]string
+	current int
+	editor  *lineedit.Editor
+	codes   ansi.MCICodes
+	choice  int // Selected choice for select fields
+	message int // Length of the message on screen, for fields drawn in the art
+}
+
+func NewFormView(env Env) *FormView {
+	v := &FormView{
+		cfg:    env.Config,
+		values: map[string]string{},
+	}
+
+	name := optionString(env.Config.Options, "handler", "")
+	v.handler = formHandlers[name]
+	if v.handler == nil {
+		app.Logger.Error("Unknown form handler", "handler", name)
+	}
+
+	fields, _ := env.Config.Options["fields"].([]interface{})
+	for i, raw := range fields {
+		opts, ok := raw.(map[string]interface{})
+		if !ok {
+			app.Logger.Warn("Form field isn't a map", "field", raw)
+			continue
+		}
+		v.fields = append(v.fields, newFormField(opts, i))
+	}
+	return v
+}
+
+// Enter starts the form with the handler's answers, if it has any.
+func (v *FormView) Enter(w io.Writer, node *nodes.Node) error {
+	if v.handler != nil {
+		for name, value := range v.handler.Load(node) {
+			v.values[name] = value
+		}
+	}
+	return nil
+}
+
+func (v *FormView) SetMCI(codes ansi.MCICodes) {
+	v.codes = codes
+}
+
+// inArt returns true if the fields are drawn in the art.
+func (v *FormView) inArt() bool {
+	if len(v.fields) == 0 {
+		return false
+	}
+	_, ok := v.codes[v.fields[0].mci]
+	return ok
+}
+
+func (v *FormView) Render(w io.Writer, node *nodes.Node) error {
+	if len(v.fields) == 0 {
+		app.Logger.Warn("Form has no fields", "handler", optionString(v.cfg.Options, "handler", ""))
+		return nil
+	}
+	v.message = 0
+	if v.inArt() {
+		for i, f := range v.fields {
+			if pos, ok := v.codes[f.mci]; ok && i != v.current {
+				io.WriteString(w, pos.MoveTo())
+				io.WriteString(w, pad(f.display(v.values[f.name]), f.displayWidth()))
+			}
+		}
+	} else {
+		io.WriteString(w, "\r\n")
+	}
+	v.beginField(w)
+	return nil
+}
+
+// beginField starts editing the current field, showing its answer so far.
+func (v *FormView) beginField(w io.Writer) {
+	f := v.fields[v.current]
+	value := v.values[f.name]
+	if f.kind == "masked" {
+		// Passwords are typed afresh
+		value = ""
+	}
+
+	if pos, ok := v.codes[f.mci]; ok && v.inArt() {
+		io.WriteString(w, pos.MoveTo()+strings.Repeat(" ", f.displayWidth())+pos.MoveTo())
+	} else {
+		io.WriteString(w, f.label)
+	}
+
+	if f.kind == "select" {
+		v.editor = nil
+		v.choice = max(0, indexOf(f.choices, value))
+		v.drawChoice(w)
+		return
+	}
+
+	maxLength := f.maxLength
+	if maxLength == 0 {
+		maxLength = 255
+	}
+	v.editor = lineedit.New(lineedit.Options{
+		MaxLength: maxLength,
+		Masked:    f.kind == "masked",
+		Inline:    v.inArt(),
+	})
+	v.editor.SetLine(w, f.display(value))
+}
+
+// drawChoice shows the selected choice of a select field, leaving the cursor at its start.
+func (v *FormView) drawChoice(w io.Writer) {
+	f := v.fields[v.current]
+	text := pad(f.choices[v.choice], f.displayWidth())
+	io.WriteString(w, text+strings.Repeat("\b", utf8.RuneCountInString(text)))
+}
+
+func (v *FormView) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	if len(v.fields) == 0 {
+		return actionFor(v.cfg, keyNames(input), node), nil
+	}
+
+	for _, k := range input {
+		f := v.fields[v.current]
+
+		switch {
+		case k.Code == keys.Escape || k.Code >= keys.F1 && k.Code <= keys.F12:
+			if target := matchAction(v.cfg, []string{k.String()}, node); target != "" {
+				return target, nil
+			}
+			continue
+		case k.Code == keys.BackTab || k.Code == keys.Up:
+			if v.current > 0 {
+				v.moveTo(w, v.current-1)
+			}
+			continue
+		case f.kind == "select":
+			if k.Code != keys.Enter {
+				v.changeChoice(w, k)
+				continue
+			}
+			if !v.inArt() {
+				io.WriteString(w, "\r\n")
+			}
+			if target, done := v.answer(w, node, f.choices[v.choice]); done {
+				return target, nil
+			}
+			continue
+		case k.Code == keys.Rune && !f.accepts(k.Rune):
+			continue
+		}
+
+		line, done, _ := v.editor.Feed(w, []keys.Key{k})
+		if !done {
+			continue
+		}
+		if target, done := v.answer(w, node, line); done {
+			return target, nil
+		}
+	}
+	return "", nil
+}
+
+// changeChoice moves through a select field's choices.
+func (v *FormView) changeChoice(w io.Writer, k keys.Key) {
+	choices := v.fields[v.current].choices
+	switch {
+	case k.Code == keys.Left:
+		v.choice = (v.choice + len(choices) - 1) % len(choices)
+	case k.Code == keys.Right || k == keys.Char(' '):
+		v.choice = (v.choice + 1) % len(choices)
+	case k.Code == keys.Rune:
+		// Jump to the next choice starting with the letter typed
+		for i := 1; i <= len(choices); i++ {
+			next := (v.choice + i) % len(choices)
+			if first, _ := utf8.DecodeRuneInString(choices[next]); unicode.ToLower(first) == unicode.ToLower(k.Rune) {
+				v.choice = next
+				break
+			}
+		}
+	default:
+		return
+	}
+	v.drawChoice(w)
+}
+
+// answer checks an answer for the current field, moving on to the next field if it'll do, or submitting the form
+// after the last. Returns where to go, and true once the form is done with.
+func (v *FormView) answer(w io.Writer, node *nodes.Node, input string) (string, bool) {
+	f := v.fields[v.current]
+	value, msg := f.validate(input, v.values)
+	if msg != "" {
+		v.showMessage(w, msg)
+		v.beginField(w)
+		return "", false
+	}
+	v.values[f.name] = value
+	v.showMessage(w, "")
+
+	if v.current+1 < len(v.fields) {
+		v.current++
+		v.beginField(w)
+		return "", false
+	}
+	return v.submit(w, node)
+}
+
+// submit hands the answers to the handler, then moves on to the next view. If the handler won't take them, the caller
+// starts again from the first field.
+func (v *FormView) submit(w io.Writer, node *nodes.Node) (string, bool) {
+	msg := "This form isn't working right now, sorry."
+	if v.handler != nil {
+		var err error
+		msg, err = v.handler.Submit(node, v.values)
+		if err != nil {
+			app.Logger.Error("Form handler failed", "handler", optionString(v.cfg.Options, "handler", ""), "node", node.ID, "err", err)
+			msg = "Something went wrong, please try again."
+		}
+	}
+	if msg != "" {
+		v.showMessage(w, msg)
+		v.moveTo(w, 0)
+		return "", false
+	}
+
+	if next := nextView(v.cfg, node); next != nil {
+		return next.View, true
+	}
+	return Back, true
+}
+
+// moveTo goes to another field, leaving the current one as it was.
+func (v *FormView) moveTo(w io.Writer, i int) {
+	f := v.fields[v.current]
+	if pos, ok := v.codes[f.mci]; ok && v.inArt() {
+		io.WriteString(w, pos.MoveTo()+pad(f.display(v.values[f.name]), f.displayWidth()))
+	} else {
+		io.WriteString(w, "\r\n")
+	}
+	v.current = i
+	v.beginField(w)
+}
+
+// showMessage tells the caller what's wrong with their answer, or clears the last message if msg is empty.
+func (v *FormView) showMessage(w io.Writer, msg string) {
+	if !v.inArt() {
+		if msg != "" {
+			io.WriteString(w, msg+"\r\n")
+		}
+		return
+	}
+
+	pos, ok := v.codes[optionString(v.cfg.Options, "message", "")]
+	if !ok {
+		// The line after the last field
+		for _, f := range v.fields {
+			if p, ok := v.codes[f.mci]; ok && p.Row >= pos.Row {
+				pos = ansi.Position{Row: p.Row + 1, Col: 1}
+			}
+		}
+	}
+	if msg == "" && v.message == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s%s", pos.MoveTo(), pad(msg, v.message))
+	v.message = utf8.RuneCountInString(msg)
+}
+
+// pad pads s with spaces to width.
+func pad(s string, width int) string {
+	return s + strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0))
+}
+
+func indexOf(choices []string, value string) int {
+	for i, choice := range choices {
+		if choice == value {
+			return i
+		}
+	}
+	return -1
+}
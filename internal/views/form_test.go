@@ -0,0 +1,169 @@
+package views_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+	"euphio/internal/views"
+)
+
+var _ = Describe("Form fields", func() {
+	BeforeEach(func() {
+		app.SetConfig(&config.Config{Views: map[string]config.View{"login": {Type: "login"}}})
+		Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+	})
+
+	DescribeTable("validate",
+		func(options, input, value, msg string) {
+			var opts map[string]interface{}
+			Expect(yaml.Unmarshal([]byte(options), &opts)).To(Succeed())
+			got, gotMsg := views.ValidateField(opts, input, map[string]string{"password": "secret1"})
+			Expect(gotMsg).To(Equal(msg))
+			Expect(got).To(Equal(value))
+		},
+		Entry("trims text", "{name: city}", "  Leeds ", "Leeds", ""),
+		Entry("keeps spaces in masked fields", "{type: masked}", " pw ", " pw ", ""),
+		Entry("allows blank optional fields", "{name: city}", " ", "", ""),
+		Entry("requires an answer", "{label: 'City: ', required: true}", "", "", "City is required."),
+		Entry("names fields without a label", "{name: city, required: true}", "", "", "city is required."),
+		Entry("checks the shortest length", "{label: 'Name: ', minLength: 3}", "ab", "", "Name must be at least 3 characters."),
+		Entry("checks the longest length in characters", "{label: 'Name: ', maxLength: 3}", "ééé", "ééé", ""),
+		Entry("rejects answers that are too long", "{label: 'Name: ', maxLength: 3}", "abcd", "", "Name must be at most 3 characters."),
+		Entry("checks the regex", "{label: 'Code: ', regex: '^[A-Z]+$'}", "abc", "", "Code isn't valid."),
+		Entry("refuses everything when the regex is broken", "{label: 'Code: ', regex: '^[A-Z'}", "ABC", "", "Code can't be checked right now, sorry."),
+		Entry("gives the message for the regex", "{regex: '^[A-Z]+$', invalid: Capitals only.}", "abc", "", "Capitals only."),
+		Entry("takes numbers", "{type: numeric}", "007", "7", ""),
+		Entry("rejects what isn't a number", "{label: 'Age: ', type: numeric}", "-", "", "Age must be a number."),
+		Entry("checks a number's range", "{label: 'Age: ', type: numeric, min: 13, max: 120}", "12", "", "Age must be from 13 to 120."),
+		Entry("checks a number's minimum", "{label: 'Age: ', type: numeric, min: 13}", "12", "", "Age must be at least 13."),
+		Entry("checks a number's maximum", "{label: 'Age: ', type: numeric, max: 120}", "121", "", "Age must be at most 120."),
+		Entry("keeps dates in one layout", "{type: date, format: DD/MM/YYYY}", "01/04/1990", "1990-04-01", ""),
+		Entry("rejects dates in another format", "{label: 'Birthday: ', type: date, format: DD/MM/YYYY}", "1990-04-01", "", "Birthday must be a date like DD/MM/YYYY."),
+		Entry("takes a choice", "{type: select, choices: [Yes, No]}", "No", "No", ""),
+		Entry("rejects anything else for a select", "{label: 'OK? ', type: select, choices: [Yes, No]}", "Maybe", "", "OK? must be one of Yes, No."),
+		Entry("checks a match", "{label: 'Again: ', type: masked, matches: password}", "secret1", "secret1", ""),
+		Entry("rejects a mismatch", "{label: 'Again: ', type: masked, matches: password}", "secret2", "", "Again doesn't match."),
+		Entry("takes a new username", "{unique: username}", "alice", "alice", ""),
+		Entry("rejects a username that's taken", "{unique: username}", "bob", "", "That username is already taken."),
+		Entry("rejects the login's new user keyword", "{unique: username, regex: '^[A-Za-z]+$'}", "new", "", "That username is reserved."),
+	)
+})
+
+var _ = Describe("FormView", func() {
+	var (
+		view *views.FormView
+		node *nodes.Node
+		out  bytes.Buffer
+	)
+
+	newView := func(text string) {
+		view = views.NewFormView(views.Env{Config: viewConfig(text)})
+		Expect(view.Enter(&out, node)).To(Succeed())
+		Expect(view.Render(&out, node)).To(Succeed())
+	}
+
+	input := func(s string) string {
+		next, err := view.HandleInput(&out, typed(s), node)
+		Expect(err).NotTo(HaveOccurred())
+		return next
+	}
+
+	BeforeEach(func() {
+		app.SetConfig(&config.Config{Views: map[string]config.View{
+			"login": viewConfig("{type: login, options: {newKeyword: APPLY}}"),
+		}})
+		node = &nodes.Node{ID: 1}
+		out.Reset()
+	})
+
+	Describe("newUser", func() {
+		BeforeEach(func() {
+			newView(`
+type: form
+options:
+	handler: newUser
+	fields:
+	- {name: username, label: "Username: ", required: true}
+	- {name: password, type: masked, label: "Password: ", required: true}
+	- {name: realName, label: "Real name: "}
+next: main
+`)
+		})
+
+		It("creates the account with its profile and logs the caller in", func() {
+			Expect(input("alice\rsecret1\rAlice Smith\r")).To(Equal("main"))
+			Expect(node.User.Username).To(Equal("alice"))
+
+			user, err := app.Store.Authenticate("alice", "secret1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.RealName).To(Equal("Alice Smith"))
+		})
+
+		It("refuses a username that's taken, starting again", func() {
+			Expect(app.Store.CreateUser("alice", "other")).To(Succeed())
+			Expect(input("alice\rsecret1\rAlice Smith\r")).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("That username is already taken.\r\n"))
+			Expect(node.User).To(BeNil())
+
+			// The answers are kept, apart from the password
+			Expect(input("\x7f\x7fcia\rsecret1\r\r")).To(Equal("main"))
+			Expect(node.User.Username).To(Equal("alicia"))
+			Expect(node.User.RealName).To(Equal("Alice Smith"))
+		})
+
+		It("refuses the login's new user keyword", func() {
+			Expect(input("apply\rsecret1\r\r")).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("That username is reserved.\r\n"))
+			_, err := app.Store.FindUserByUsername("apply")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("password", func() {
+		BeforeEach(func() {
+			Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+			user, err := app.Store.FindUserByUsername("bob")
+			Expect(err).NotTo(HaveOccurred())
+			node.User = user
+			newView(`
+type: form
+options:
+	handler: password
+	fields:
+	- {name: current, type: masked, label: "Current password: "}
+	- {name: password, type: masked, label: "New password: "}
+next: main
+`)
+		})
+
+		It("changes the password once the current one is right", func() {
+			Expect(input("secret1\rsecret2\r")).To(Equal("main"))
+			_, err := app.Store.Authenticate("bob", "secret2")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("counts a wrong current password as a failed login", func() {
+			Expect(input("wrong\rsecret2\r")).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("Your current password isn't right.\r\n"))
+			failure, err := app.Store.FindLoginFailure(store.LoginFailureUser, "bob")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(failure.Count).To(Equal(1))
+		})
+
+		It("refuses attempts during the back-off from a failed one", func() {
+			app.Config().Security.LoginBackoff = 60000
+			input("wrong\rsecret2\r")
+			Expect(input("secret1\rsecret2\r")).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("Please wait a moment before trying again.\r\n"))
+			_, err := app.Store.Authenticate("bob", "secret1")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
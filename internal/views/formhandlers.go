@@ -0,0 +1,162 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/auth"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+)
+
+// FormHandler does something with a form's answers, e.g. create an account. Answers are keyed by field name.
+type FormHandler interface {
+	// Load returns the answers to start the form with, e.g. the caller's current profile. It may return nil.
+	Load(node *nodes.Node) map[string]string
+	// Submit is given the answers once they're all in and valid. It returns a message for the caller if they can't be
+	// used, so they can change them, or an error if something went wrong.
+	Submit(node *nodes.Node, values map[string]string) (string, error)
+}
+
+// Form handlers, selected with the form's `handler` option
+var formHandlers = map[string]FormHandler{
+	"newUser":  newUserForm{},
+	"profile":  profileForm{},
+	"password": passwordForm{},
+}
+
+// newUserForm creates an account from the username and password fields, and any profile fields (see profileForm),
+// then logs the caller in. Usernames a login view takes as a request to apply (see reservedUsername) are refused.
+type newUserForm struct{}
+
+func (newUserForm) Load(node *nodes.Node) map[string]string {
+	return nil
+}
+
+func (newUserForm) Submit(node *nodes.Node, values map[string]string) (string, error) {
+	username, password := values["username"], values["password"]
+	if username == "" || password == "" {
+		return "", errors.New("new user forms need username and password fields")
+	}
+	if reservedUsername(username) {
+		return "That username is reserved.", nil
+	}
+
+	profile, err := profileFrom(store.Profile{}, values)
+	if err != nil {
+		return "", err
+	}
+	if err := app.Store.CreateUserWithProfile(username, password, profile); err != nil {
+		if errors.Is(err, store.ErrUserExists) {
+			return "That username is already taken.", nil
+		}
+		return "", fmt.Errorf("creating user: %w", err)
+	}
+	user, err := app.Store.FindUserByUsername(username)
+	if err != nil {
+		return "", fmt.Errorf("loading new user: %w", err)
+	}
+
+	app.Logger.Info("New user created", "node", node.ID, "user", username)
+	node.SetUser(user)
+	app.Logger.Info("User logged in", "node", node.ID, "user", username)
+	return "", nil
+}
+
+// profileForm updates the caller's profile from the realName, location, email and birthday fields. Profile details
+// without a field are left as they are.
+type profileForm struct{}
+
+func (profileForm) Load(node *nodes.Node) map[string]string {
+	if node.User == nil {
+		return nil
+	}
+	profile := node.User.Profile
+	values := map[string]string{
+		"realName": profile.RealName,
+		"location": profile.Location,
+		"email":    profile.Email,
+	}
+	if !profile.Birthday.IsZero() {
+		values["birthday"] = profile.Birthday.Format(dateLayout)
+	}
+	return values
+}
+
+func (profileForm) Submit(node *nodes.Node, values map[string]string) (string, error) {
+	if node.User == nil {
+		return "You need to log in to change your profile.", nil
+	}
+	profile, err := profileFrom(node.User.Profile, values)
+	if err != nil {
+		return "", err
+	}
+	if err := app.Store.UpdateProfile(node.User.Username, profile); err != nil {
+		return "", fmt.Errorf("saving profile: %w", err)
+	}
+	node.User.Profile = profile
+	app.Logger.Info("Profile updated", "node", node.ID, "user", node.User.Username)
+	return "", nil
+}
+
+// passwordForm changes the caller's password, checking the current field first.
+type passwordForm struct{}
+
+func (passwordForm) Load(node *nodes.Node) map[string]string {
+	return nil
+}
+
+func (passwordForm) Submit(node *nodes.Node, values map[string]string) (string, error) {
+	if node.User == nil {
+		return "You need to log in to change your password.", nil
+	}
+	password := values["password"]
+	if password == "" {
+		return "", errors.New("password forms need a password field")
+	}
+	// Checked like a login, so the form can't be used to guess passwords. Running out of time today still means the
+	// password was right.
+	_, err := auth.Login(node.User.Username, values["current"], remoteAddr(node))
+	switch {
+	case err == nil, errors.Is(err, auth.ErrNoTime):
+	case errors.Is(err, store.ErrInvalidPassword):
+		return "Your current password isn't right.", nil
+	case errors.Is(err, auth.ErrTooSoon):
+		return "Please wait a moment before trying again.", nil
+	case errors.Is(err, auth.ErrLockedOut):
+		return "Too many failed attempts, please try again later.", nil
+	default:
+		return "", err
+	}
+	if err := app.Store.UpdatePassword(node.User.Username, password); err != nil {
+		return "", fmt.Errorf("updating password: %w", err)
+	}
+	app.Logger.Info("Password changed", "node", node.ID, "user", node.User.Username)
+	return "", nil
+}
+
+// profileFrom returns the profile with any profile fields in values applied.
+func profileFrom(profile store.Profile, values map[string]string) (store.Profile, error) {
+	if v, ok := values["realName"]; ok {
+		profile.RealName = v
+	}
+	if v, ok := values["location"]; ok {
+		profile.Location = v
+	}
+	if v, ok := values["email"]; ok {
+		profile.Email = v
+	}
+	if v, ok := values["birthday"]; ok {
+		profile.Birthday = time.Time{}
+		if v != "" {
+			t, err := time.Parse(dateLayout, v)
+			if err != nil {
+				return profile, fmt.Errorf("birthday field isn't a date: %w", err)
+			}
+			profile.Birthday = t
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,305 @@
+package views
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"euphio/internal/app"
+	"euphio/internal/auth"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+)
+
+type loginState int
+
+const (
+	stateUsername loginState = iota
+	statePassword
+	stateNewUsername
+	stateNewPassword
+	stateNewConfirm
+)
+
+const maxLoginInput = 64
+
+// LoginView prompts the caller for a username and password and authenticates them against the store. Typing the
+// "new" keyword at the username prompt starts a simple new-user application instead, or goes to the newUserView, e.g.
+// a form with the newUser handler.
+//
+// Supported options:
+//
+//	maxAttempts:    number of failed logins before disconnecting (default 3)
+//	allowNew:       whether the new-user application is available (default true)
+//	newKeyword:     what to type at the username prompt to apply (default "NEW")
+//	newUserView:    the view to apply in, instead of the built-in application
+//	usernamePrompt: text shown when asking for a username
+//	passwordPrompt: text shown when asking for a password
+//	prefillUser:    pre-fill the username sent by the client (e.g. USER from Telnet NEW-ENVIRON) (default true)
+type LoginView struct {
+	id   string
+	cfg  config.View
+	send func(event interface{})
+
+	state    loginState
+	editor   *lineedit.Editor
+	attempts int
+	username string
+	password string
+}
+
+func NewLoginView(env Env) *LoginView {
+	return &LoginView{
+		id:     env.ID,
+		cfg:    env.Config,
+		send:   env.Send,
+		editor: lineedit.New(lineedit.Options{MaxLength: maxLoginInput}),
+	}
+}
+
+func (v *LoginView) Render(w io.Writer, node *nodes.Node) error {
+	// Callers that authenticated at the transport level (e.g. SSH) skip straight through.
+	if node.User != nil {
+		if next := nextView(v.cfg, node); next != nil {
+			go v.send(ChangeViewEvent{ViewID: next.View, From: v.id})
+			return nil
+		}
+	}
+
+	v.prompt(w)
+
+	// The caller can accept the username their client sent, or erase it and type another
+	if optionBool(v.cfg.Options, "prefillUser", true) && node.Conn != nil {
+		user := strings.Map(func(r rune) rune {
+			if r < 0x20 || r == 0x7f {
+				return -1
+			}
+			return r
+		}, node.Conn.GetTerminalInfo().Env["USER"])
+		v.editor.SetLine(w, user)
+	}
+	return nil
+}
+
+func (v *LoginView) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	for len(input) > 0 {
+		line, done, rest := v.editor.Feed(w, input)
+		if !done {
+			break
+		}
+		input = rest
+		if next := v.submit(w, line, node); next != "" {
+			return next, nil
+		}
+		// Passwords are masked as they're typed, so this has to be set before feeding the rest
+		v.editor.SetMasked(v.masked())
+	}
+	return "", nil
+}
+
+// submit processes a completed line for the current state, returning the next view once the caller is logged in.
+func (v *LoginView) submit(w io.Writer, line string, node *nodes.Node) string {
+	switch v.state {
+	case stateUsername:
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if v.allowNew() && strings.EqualFold(line, v.newKeyword()) {
+			if view := optionString(v.cfg.Options, "newUserView", ""); view != "" {
+				return view
+			}
+			io.WriteString(w, "\r\nNew user application\r\n")
+			v.state = stateNewUsername
+			break
+		}
+		v.username = line
+		v.state = statePassword
+
+	case statePassword:
+		user, err := auth.Login(v.username, line, remoteAddr(node))
+		if err != nil {
+			app.Logger.Debug("Login failed", "node", node.ID, "user", v.username, "err", err)
+			v.attempts++
+			v.state = stateUsername
+			switch {
+			case errors.Is(err, auth.ErrLockedOut):
+				io.WriteString(w, "Too many failed logins, please try again later.\r\n")
+				v.disconnect(w, node)
+				return ""
+			case errors.Is(err, auth.ErrNoTime):
+				io.WriteString(w, "Your time is up for today, please call again tomorrow.\r\n")
+				go v.send(DisconnectEvent{})
+				return ""
+			case errors.Is(err, auth.ErrTooSoon):
+				io.WriteString(w, "Please wait a moment before trying again.\r\n\r\n")
+			case errors.Is(err, store.ErrAccountLocked):
+				io.WriteString(w, "This account has been locked.\r\n\r\n")
+			default:
+				io.WriteString(w, "Login incorrect.\r\n\r\n")
+			}
+			if v.attempts >= v.maxAttempts() {
+				v.disconnect(w, node)
+				return ""
+			}
+			break
+		}
+		return v.loggedIn(user, node)
+
+	case stateNewUsername:
+		line = strings.TrimSpace(line)
+		if msg := v.validateUsername(line); msg != "" {
+			fmt.Fprintf(w, "%s\r\n", msg)
+			break
+		}
+		v.username = line
+		v.state = stateNewPassword
+
+	case stateNewPassword:
+		if len(line) < 6 {
+			io.WriteString(w, "Password must be at least 6 characters.\r\n")
+			break
+		}
+		v.password = line
+		v.state = stateNewConfirm
+
+	case stateNewConfirm:
+		if line != v.password {
+			io.WriteString(w, "Passwords do not match.\r\n")
+			v.password = ""
+			v.state = stateNewPassword
+			break
+		}
+		if err := app.Store.CreateUser(v.username, v.password); err != nil {
+			app.Logger.Error("Failed to create user", "node", node.ID, "user", v.username, "err", err)
+			io.WriteString(w, "Unable to create your account, please try again.\r\n")
+			v.state = stateNewUsername
+			break
+		}
+		app.Logger.Info("New user created", "node", node.ID, "user", v.username)
+		v.password = ""
+		user, err := app.Store.FindUserByUsername(v.username)
+		if err != nil {
+			app.Logger.Error("Failed to load new user", "node", node.ID, "user", v.username, "err", err)
+			v.state = stateUsername
+			break
+		}
+		return v.loggedIn(user, node)
+	}
+
+	v.prompt(w)
+	return ""
+}
+
+func (v *LoginView) loggedIn(user *store.User, node *nodes.Node) string {
+	node.SetUser(user)
+	app.Logger.Info("User logged in", "node", node.ID, "user", user.Username)
+
+	next := nextView(v.cfg, node)
+	if next == nil {
+		app.Logger.Warn("Login view has no next view available", "node", node.ID)
+		return ""
+	}
+	return next.View
+}
+
+func (v *LoginView) disconnect(w io.Writer, node *nodes.Node) {
+	app.Logger.Warn("Disconnecting after failed logins", "node", node.ID, "addr", remoteAddr(node))
+	io.WriteString(w, "Goodbye.\r\n")
+	go v.send(DisconnectEvent{})
+}
+
+// validateUsername returns a message for the caller if the username can't be used, or an empty string.
+func (v *LoginView) validateUsername(username string) string {
+	if len(username) < 3 {
+		return "Username must be at least 3 characters."
+	}
+	if strings.EqualFold(username, v.newKeyword()) || reservedUsername(username) {
+		return "That username is reserved."
+	}
+	if _, err := app.Store.FindUserByUsername(username); err == nil {
+		return "That username is already taken."
+	}
+	return ""
+}
+
+func (v *LoginView) prompt(w io.Writer) {
+	switch v.state {
+	case stateUsername:
+		io.WriteString(w, optionString(v.cfg.Options, "usernamePrompt", "Username: "))
+	case statePassword:
+		io.WriteString(w, optionString(v.cfg.Options, "passwordPrompt", "Password: "))
+	case stateNewUsername:
+		io.WriteString(w, "Choose a username: ")
+	case stateNewPassword:
+		io.WriteString(w, "Choose a password: ")
+	case stateNewConfirm:
+		io.WriteString(w, "Confirm password: ")
+	}
+}
+
+func (v *LoginView) masked() bool {
+	return v.state == statePassword || v.state == stateNewPassword || v.state == stateNewConfirm
+}
+
+func (v *LoginView) maxAttempts() int {
+	return optionInt(v.cfg.Options, "maxAttempts", 3)
+}
+
+func (v *LoginView) allowNew() bool {
+	return optionBool(v.cfg.Options, "allowNew", true)
+}
+
+func (v *LoginView) newKeyword() string {
+	return newKeyword(v.cfg)
+}
+
+func newKeyword(cfg config.View) string {
+	return optionString(cfg.Options, "newKeyword", "NEW")
+}
+
+// reservedUsername returns true if a login view in the config takes the username as a request to apply, so the
+// account couldn't be logged in to.
+func reservedUsername(username string) bool {
+	for _, view := range app.Config().Views {
+		if viewType(view) == "login" && strings.EqualFold(username, newKeyword(view)) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteAddr(node *nodes.Node) net.Addr {
+	if node.Conn == nil {
+		return nil
+	}
+	return node.Conn.RemoteAddr()
+}
+
+// Option helpers for reading loosely typed values out of config.View.Options.
+
+func optionString(opts map[string]interface{}, key, def string) string {
+	if v, ok := opts[key].(string); ok {
+		return v
+	}
+	return def
+}
+
+func optionInt(opts map[string]interface{}, key string, def int) int {
+	if v, ok := opts[key].(int); ok {
+		return v
+	}
+	return def
+}
+
+func optionBool(opts map[string]interface{}, key string, def bool) bool {
+	if v, ok := opts[key].(bool); ok {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,129 @@
+package views_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/nodes"
+	"euphio/internal/views"
+)
+
+var _ = Describe("LoginView", func() {
+	var (
+		view *views.LoginView
+		node *nodes.Node
+		sent events
+		out  bytes.Buffer
+	)
+
+	newView := func(text string) {
+		sent = make(events, 10)
+		view = views.NewLoginView(views.Env{Config: viewConfig(text), Send: sent.send})
+		Expect(view.Render(&out, node)).To(Succeed())
+	}
+
+	input := func(s string) string {
+		next, err := view.HandleInput(&out, typed(s), node)
+		Expect(err).NotTo(HaveOccurred())
+		return next
+	}
+
+	BeforeEach(func() {
+		node = &nodes.Node{ID: 1}
+		out.Reset()
+		Expect(app.Store.CreateUser("bob", "secret1")).To(Succeed())
+		newView(`
+type: login
+options:
+	maxAttempts: 2
+next: main
+`)
+	})
+
+	It("logs the caller in and moves on", func() {
+		Expect(input("bob\r")).To(BeEmpty())
+		Expect(input("secret1\r")).To(Equal("main"))
+		Expect(node.User.Username).To(Equal("bob"))
+	})
+
+	It("masks the password", func() {
+		input("bob\rsecret1\r")
+		Expect(out.String()).NotTo(ContainSubstring("secret1"))
+	})
+
+	It("disconnects after too many failed logins", func() {
+		Expect(input("bob\rwrong\r")).To(BeEmpty())
+		Expect(out.String()).To(ContainSubstring("Login incorrect."))
+		Expect(sent).NotTo(Receive())
+
+		Expect(input("bob\rwrong\r")).To(BeEmpty())
+		Eventually(sent).Should(Receive(Equal(views.DisconnectEvent{})))
+		Expect(node.User).To(BeNil())
+	})
+
+	It("refuses attempts during the back-off from a failed login, without waiting", func() {
+		app.Config().Security.LoginBackoff = 60000
+		input("bob\rwrong\r")
+		Expect(input("bob\rsecret1\r")).To(BeEmpty())
+		Expect(out.String()).To(ContainSubstring("Please wait a moment before trying again."))
+		Expect(node.User).To(BeNil())
+	})
+
+	It("refuses callers with no time left today", func() {
+		app.Config().Limits.Levels = []config.LevelLimit{{MinutesPerDay: 30}}
+		Expect(app.Store.AddTimeUsed("bob", 30*time.Minute, time.Now())).To(Succeed())
+
+		Expect(input("bob\rsecret1\r")).To(BeEmpty())
+		Expect(out.String()).To(ContainSubstring("Your time is up for today"))
+		Expect(node.User).To(BeNil())
+		Eventually(sent).Should(Receive(Equal(views.DisconnectEvent{})))
+	})
+
+	It("skips through for callers already logged in", func() {
+		user, err := app.Store.FindUserByUsername("bob")
+		Expect(err).NotTo(HaveOccurred())
+		node.User = user
+		sent = make(events, 10)
+		view = views.NewLoginView(views.Env{ID: "login", Config: viewConfig("{type: login, next: main}"), Send: sent.send})
+		Expect(view.Render(&out, node)).To(Succeed())
+		Eventually(sent).Should(Receive(Equal(views.ChangeViewEvent{ViewID: "main", From: "login"})))
+	})
+
+	Describe("new user application", func() {
+		It("creates the account and logs the caller in", func() {
+			Expect(input("new\ralice\rsecret2\rsecret2\r")).To(Equal("main"))
+			Expect(node.User.Username).To(Equal("alice"))
+			_, err := app.Store.Authenticate("alice", "secret2")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("won't take the keyword, or a name that's taken, as a username", func() {
+			input("NEW\rnew\r")
+			Expect(out.String()).To(ContainSubstring("That username is reserved."))
+			input("bob\r")
+			Expect(out.String()).To(ContainSubstring("That username is already taken."))
+		})
+
+		It("asks again when the passwords don't match", func() {
+			Expect(input("NEW\ralice\rsecret2\rsecret3\r")).To(BeEmpty())
+			Expect(out.String()).To(ContainSubstring("Passwords do not match."))
+			Expect(input("secret2\rsecret2\r")).To(Equal("main"))
+		})
+
+		It("goes to the new user view, if there is one", func() {
+			newView("{type: login, options: {newUserView: signup}}")
+			Expect(input("NEW\r")).To(Equal("signup"))
+		})
+
+		It("isn't offered when disabled", func() {
+			newView("{type: login, options: {allowNew: false}}")
+			input("NEW\r")
+			Expect(out.String()).To(HaveSuffix("Password: "))
+		})
+	})
+})
@@ -0,0 +1,55 @@
+package views
+
+import (
+	"io"
+	"time"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/nodes"
+)
+
+// LogoffView shows its art and hangs up on the caller.
+//
+// Supported options:
+//
+//	delay: milliseconds to wait before hanging up, so the caller can read the art (default 0)
+type LogoffView struct {
+	cfg   config.View
+	send  func(event interface{})
+	timer *time.Timer
+}
+
+func NewLogoffView(env Env) *LogoffView {
+	return &LogoffView{
+		cfg:  env.Config,
+		send: env.Send,
+	}
+}
+
+// Enter starts the countdown to hanging up.
+func (v *LogoffView) Enter(w io.Writer, node *nodes.Node) error {
+	app.Logger.Debug("Logoff view", "node", node.ID)
+	delay := time.Duration(optionInt(v.cfg.Options, "delay", 0)) * time.Millisecond
+	v.timer = time.AfterFunc(delay, func() {
+		v.send(DisconnectEvent{})
+	})
+	return nil
+}
+
+func (v *LogoffView) Render(w io.Writer, node *nodes.Node) error {
+	return nil
+}
+
+// Leave stops the countdown, if the caller was sent somewhere else first.
+func (v *LogoffView) Leave() {
+	if v.timer != nil {
+		v.timer.Stop()
+	}
+}
+
+// HandleInput ignores the caller, who is on their way out.
+func (v *LogoffView) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	return "", nil
+}
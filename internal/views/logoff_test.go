@@ -0,0 +1,53 @@
+package views_test
+
+import (
+	"bytes"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/nodes"
+	"euphio/internal/views"
+)
+
+var _ = Describe("LogoffView", func() {
+	var (
+		sent events
+		out  bytes.Buffer
+		node *nodes.Node
+	)
+
+	newView := func(text string) *views.LogoffView {
+		sent = make(events, 10)
+		view := views.NewLogoffView(views.Env{ID: "logoff", Config: viewConfig(text), Send: sent.send})
+		Expect(view.Enter(&out, node)).To(Succeed())
+		Expect(view.Render(&out, node)).To(Succeed())
+		return view
+	}
+
+	BeforeEach(func() {
+		node = &nodes.Node{ID: 1}
+		out.Reset()
+	})
+
+	It("hangs up on the caller", func() {
+		newView("{type: logoff}")
+		Eventually(sent).Should(Receive(Equal(views.DisconnectEvent{})))
+	})
+
+	It("waits for the delay first, ignoring the caller", func() {
+		view := newView("{type: logoff, options: {delay: 200}}")
+		next, err := view.HandleInput(&out, typed("\r"), node)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(next).To(BeEmpty())
+		Consistently(sent, 100*time.Millisecond).ShouldNot(Receive())
+		Eventually(sent).Should(Receive(Equal(views.DisconnectEvent{})))
+	})
+
+	It("doesn't hang up once the caller has left", func() {
+		view := newView("{type: logoff, options: {delay: 50}}")
+		view.Leave()
+		Consistently(sent, 200*time.Millisecond).ShouldNot(Receive())
+	})
+})
@@ -0,0 +1,222 @@
+package views
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"euphio/internal/ansi"
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+)
+
+// MenuView offers the caller a list of items to choose from. Lightbar menus highlight an item, which the caller moves
+// with the arrow keys and chooses with Enter. Hotkey menus leave the choosing to the items' hotkeys, which work in
+// lightbar menus too. Keys the menu doesn't use can still match an action.
+//
+// The items are drawn where the art has the menu's MCI code, one below the other, or after the art if it doesn't
+// have one. Hotkey menus with art but no code are taken to show their items in the art already, so don't draw them.
+//
+// Supported options:
+//
+//	style:   "lightbar" or "hotkey" (default lightbar)
+//	mci:     the MCI code marking where the items go (default VM1)
+//	focus:   SGR parameters for the highlighted item (default "1;37;44")
+//	unfocus: SGR parameters for the other items (default "0;37")
+//	items:   the items, each with:
+//	  text:    what's shown
+//	  hotkey:  a key that chooses it straight away, e.g. "M" or "Escape"
+//	  view:    the view it goes to, or "back"
+//	  command: a command line for the view's module to run, instead of going to a view. The menu is drawn again
+//	           after, so its output is best seen in menus that don't clear the screen.
+//	  acs:     required for it to be offered
+type MenuView struct {
+	cfg     config.View
+	modules *modules.Registry
+	items   []menuItem
+
+	visible []menuItem // Items the caller has access to
+	focus   int
+	draw    bool          // Whether the items are drawn, see MenuView
+	origin  ansi.Position // Where the items are drawn, if found is set
+	found   bool
+	width   int // Items are padded to the widest, so the highlight is the same width for each
+}
+
+type menuItem struct {
+	text    string
+	hotkey  string
+	view    string
+	command string
+	acs     string
+}
+
+func NewMenuView(env Env) *MenuView {
+	v := &MenuView{
+		cfg:     env.Config,
+		modules: env.Modules,
+	}
+	items, _ := env.Config.Options["items"].([]interface{})
+	for _, raw := range items {
+		opts, ok := raw.(map[string]interface{})
+		if !ok {
+			app.Logger.Warn("Menu item isn't a map", "item", raw)
+			continue
+		}
+		item := menuItem{
+			text:    optionString(opts, "text", ""),
+			view:    optionString(opts, "view", ""),
+			command: optionString(opts, "command", ""),
+			acs:     optionString(opts, "acs", ""),
+		}
+		// Unquoted hotkeys like 1 arrive as numbers
+		if hotkey, ok := opts["hotkey"]; ok {
+			item.hotkey = fmt.Sprint(hotkey)
+		}
+		v.items = append(v.items, item)
+	}
+	return v
+}
+
+func (v *MenuView) lightbar() bool {
+	return optionString(v.cfg.Options, "style", "lightbar") != "hotkey"
+}
+
+// SetMCI finds where the items go in the art.
+func (v *MenuView) SetMCI(codes ansi.MCICodes) {
+	v.origin, v.found = codes[optionString(v.cfg.Options, "mci", "VM1")]
+	v.draw = v.found || v.lightbar() || v.cfg.Ansi == ""
+}
+
+func (v *MenuView) Render(w io.Writer, node *nodes.Node) error {
+	v.visible = v.visible[:0]
+	v.width = 0
+	for _, item := range v.items {
+		if allowed(item.acs, node) {
+			v.visible = append(v.visible, item)
+			v.width = max(v.width, utf8.RuneCountInString(item.text))
+		}
+	}
+	v.focus = min(v.focus, max(len(v.visible)-1, 0))
+
+	if !v.draw {
+		return nil
+	}
+	if !v.found {
+		io.WriteString(w, "\r\n")
+	}
+	for i := range v.visible {
+		if !v.found && i > 0 {
+			io.WriteString(w, "\r\n")
+		}
+		v.drawItem(w, i, true)
+	}
+	return nil
+}
+
+// drawItem draws an item, highlighted if it has the focus. Items after the art are drawn where the cursor is if
+// first is set, otherwise by moving up from the last item, where the cursor is left.
+func (v *MenuView) drawItem(w io.Writer, i int, first bool) {
+	item := v.visible[i]
+	sgr := optionString(v.cfg.Options, "unfocus", "0;37")
+	if i == v.focus && v.lightbar() {
+		sgr = optionString(v.cfg.Options, "focus", "1;37;44")
+	}
+
+	up := 0
+	switch {
+	case v.found:
+		io.WriteString(w, ansi.Position{Row: v.origin.Row + i, Col: v.origin.Col}.MoveTo())
+	case !first:
+		up = len(v.visible) - 1 - i
+		if up > 0 {
+			fmt.Fprintf(w, "\x1b[%dA", up)
+		}
+		io.WriteString(w, "\r")
+	}
+
+	padding := strings.Repeat(" ", v.width-utf8.RuneCountInString(item.text))
+	fmt.Fprintf(w, "\x1b[%sm%s%s%s", sgr, item.text, padding, ansi.ResetSeq)
+
+	if up > 0 {
+		fmt.Fprintf(w, "\x1b[%dB", up)
+	}
+}
+
+func (v *MenuView) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	var names []string
+	for _, k := range input {
+		if i := v.hotkey(k); i >= 0 {
+			v.moveFocus(w, i)
+			return v.choose(w, node, v.visible[i])
+		}
+
+		if v.lightbar() && len(v.visible) > 0 {
+			switch k.Code {
+			case keys.Up, keys.Left:
+				v.moveFocus(w, (v.focus+len(v.visible)-1)%len(v.visible))
+				continue
+			case keys.Down, keys.Right:
+				v.moveFocus(w, (v.focus+1)%len(v.visible))
+				continue
+			case keys.Home:
+				v.moveFocus(w, 0)
+				continue
+			case keys.End:
+				v.moveFocus(w, len(v.visible)-1)
+				continue
+			case keys.Enter:
+				return v.choose(w, node, v.visible[v.focus])
+			}
+		}
+		names = append(names, k.String())
+	}
+	return actionFor(v.cfg, names, node), nil
+}
+
+// hotkey returns the index of the visible item with the key as its hotkey, or -1 if there isn't one.
+func (v *MenuView) hotkey(k keys.Key) int {
+	for i, item := range v.visible {
+		if item.hotkey != "" && strings.EqualFold(item.hotkey, k.String()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// moveFocus highlights another item, redrawing the two that changed.
+func (v *MenuView) moveFocus(w io.Writer, i int) {
+	if i == v.focus {
+		return
+	}
+	prev := v.focus
+	v.focus = i
+	if v.draw && v.lightbar() {
+		v.drawItem(w, prev, false)
+		v.drawItem(w, i, false)
+	}
+}
+
+// choose goes where an item leads, or has the module run its command.
+func (v *MenuView) choose(w io.Writer, node *nodes.Node, item menuItem) (string, error) {
+	if item.command == "" {
+		return item.view, nil
+	}
+
+	handler, ok := v.modules.Get(v.cfg.Module).(modules.CommandHandler)
+	if !ok {
+		app.Logger.Warn("Menu item command needs a module that takes commands", "module", v.cfg.Module, "command", item.command)
+		return "", nil
+	}
+	io.WriteString(w, "\r\n")
+	cmd, args := parseCommand(item.command)
+	handled, err := handler.HandleCommand(w, node, cmd, args)
+	if !handled {
+		app.Logger.Warn("Menu item command not handled", "module", v.cfg.Module, "command", item.command)
+	}
+	return redrawIf(handled), err
+}
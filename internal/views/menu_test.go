@@ -0,0 +1,147 @@
+package views_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/ansi"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+	"euphio/internal/views"
+)
+
+var _ = Describe("MenuView", func() {
+	var (
+		view   *views.MenuView
+		module *echoModule
+		node   *nodes.Node
+		out    bytes.Buffer
+	)
+
+	// newView creates a menu from its config, drawn without art
+	newView := func(text string) {
+		registry := modules.NewRegistry()
+		registry.Register(module)
+		view = views.NewMenuView(views.Env{Config: viewConfig(text), Modules: registry})
+		view.SetMCI(nil)
+		out.Reset()
+		Expect(view.Render(&out, node)).To(Succeed())
+	}
+
+	input := func(s string) string {
+		next, err := view.HandleInput(&out, typed(s), node)
+		Expect(err).NotTo(HaveOccurred())
+		return next
+	}
+
+	BeforeEach(func() {
+		module = &echoModule{}
+		node = &nodes.Node{ID: 1, User: &store.User{SecurityLevel: 20}}
+	})
+
+	Describe("lightbar", func() {
+		BeforeEach(func() {
+			newView(`
+type: menu
+module: echo
+options:
+	items:
+	- {text: Messages, hotkey: M, view: messages}
+	- {text: Sysop, hotkey: S, view: sysop, acs: SL90}
+	- {text: Say hi, hotkey: 1, command: say hi}
+	- {text: Log off, hotkey: G, view: goodbye}
+actions:
+	Escape: back
+`)
+		})
+
+		It("draws the items the caller has access to, highlighting the first", func() {
+			Expect(out.String()).To(Equal("\r\n\x1b[1;37;44mMessages" + ansi.ResetSeq + "\r\n" +
+				"\x1b[0;37mSay hi  " + ansi.ResetSeq + "\r\n" +
+				"\x1b[0;37mLog off " + ansi.ResetSeq))
+		})
+
+		It("moves the highlight with the arrow keys, wrapping around, and chooses with Enter", func() {
+			Expect(input("\x1b[B\x1b[B")).To(BeEmpty())
+			Expect(input("\r")).To(Equal("goodbye"))
+
+			Expect(input("\x1b[B\r")).To(Equal("messages"))
+			Expect(input("\x1b[A\r")).To(Equal("goodbye"))
+		})
+
+		It("redraws only the items whose highlight changed", func() {
+			out.Reset()
+			input("\x1b[B")
+			Expect(out.String()).To(Equal("\x1b[2A\r\x1b[0;37mMessages" + ansi.ResetSeq + "\x1b[2B" +
+				"\x1b[1A\r\x1b[1;37;44mSay hi  " + ansi.ResetSeq + "\x1b[1B"))
+		})
+
+		It("chooses items by hotkey, ignoring case", func() {
+			Expect(input("g")).To(Equal("goodbye"))
+			Expect(input("M")).To(Equal("messages"))
+		})
+
+		It("doesn't offer items the caller has no access to", func() {
+			Expect(out.String()).NotTo(ContainSubstring("Sysop"))
+			Expect(input("S")).To(BeEmpty())
+
+			node.User.SecurityLevel = 90
+			Expect(view.Render(&out, node)).To(Succeed())
+			Expect(input("S")).To(Equal("sysop"))
+		})
+
+		It("runs an item's command with the view's module, then draws the menu again", func() {
+			Expect(input("1")).To(Equal(views.Redraw))
+			Expect(module.said).To(Equal([]string{"hi"}))
+		})
+
+		It("matches keys it doesn't use to actions", func() {
+			Expect(input("\x1b")).To(Equal(views.Back))
+		})
+	})
+
+	Describe("hotkey", func() {
+		BeforeEach(func() {
+			newView(`
+type: menu
+options:
+	style: hotkey
+	items:
+	- {text: Messages, hotkey: M, view: messages}
+	- {text: Log off, hotkey: G, view: goodbye}
+actions:
+	Down: files
+`)
+		})
+
+		It("draws the items without a highlight", func() {
+			Expect(out.String()).To(Equal("\r\n\x1b[0;37mMessages" + ansi.ResetSeq + "\r\n\x1b[0;37mLog off " + ansi.ResetSeq))
+		})
+
+		It("leaves the arrow keys and Enter to actions", func() {
+			Expect(input("\r")).To(BeEmpty())
+			Expect(input("\x1b[B")).To(Equal("files"))
+			Expect(input("G")).To(Equal("goodbye"))
+		})
+
+		It("doesn't draw items over art that shows them already", func() {
+			view = views.NewMenuView(views.Env{Config: viewConfig("{type: menu, ansi: mainmenu, options: {style: hotkey, items: [{text: Log off, hotkey: G, view: goodbye}]}}")})
+			view.SetMCI(ansi.MCICodes{})
+			out.Reset()
+			Expect(view.Render(&out, node)).To(Succeed())
+			Expect(out.String()).To(BeEmpty())
+			Expect(input("G")).To(Equal("goodbye"))
+		})
+	})
+
+	It("draws the items where the art has the menu's MCI code", func() {
+		view = views.NewMenuView(views.Env{Config: viewConfig("{type: menu, ansi: mainmenu, options: {items: [{text: One}, {text: Two}]}}")})
+		view.SetMCI(ansi.MCICodes{"VM1": {Row: 5, Col: 10}})
+		out.Reset()
+		Expect(view.Render(&out, node)).To(Succeed())
+		Expect(out.String()).To(Equal("\x1b[5;10H\x1b[1;37;44mOne" + ansi.ResetSeq + "\x1b[6;10H\x1b[0;37mTwo" + ansi.ResetSeq))
+	})
+})
@@ -0,0 +1,151 @@
+package views
+
+import (
+	"io"
+	"strings"
+
+	"euphio/internal/ansi"
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+)
+
+// ModuleView hands input to the module named in its config. Modules that take commands are given whole lines from a
+// line editor, split into the command and its arguments, and the view is drawn again after each one. Modules that
+// handle keys are given each one, and draw their own output so aren't redrawn. Lines and keys the module doesn't
+// handle can still match an action.
+//
+// If the art has an %ET1 MCI code, the command line is placed there.
+type ModuleView struct {
+	cfg     config.View
+	module  modules.Module
+	history *lineedit.History
+	field   *ansi.Position // Where the command line goes, if the art says
+
+	editor       *lineedit.Editor // Line discipline for modules that take commands
+	editorMasked bool
+}
+
+func NewModuleView(env Env) *ModuleView {
+	mod := env.Modules.Get(env.Config.Module)
+	if mod == nil {
+		app.Logger.Warn("View Manager: Module not found", "module", env.Config.Module)
+	}
+	return &ModuleView{
+		cfg:     env.Config,
+		module:  mod,
+		history: env.History,
+	}
+}
+
+// SetMCI finds where the command line goes in the art.
+func (v *ModuleView) SetMCI(codes ansi.MCICodes) {
+	v.field = nil
+	if pos, ok := codes["ET1"]; ok {
+		v.field = &pos
+	}
+}
+
+// Render sets up the line editor for modules that take commands. Capable clients can edit unmasked lines locally.
+func (v *ModuleView) Render(w io.Writer, node *nodes.Node) error {
+	if _, keyed := v.module.(modules.KeyHandler); !keyed {
+		if cmdHandler, ok := v.module.(modules.CommandHandler); ok && v.editor == nil {
+			v.newEditor(cmdHandler)
+		}
+	}
+	node.SetLineEditing(v.editor != nil && !v.editorMasked)
+	if v.editor != nil && v.field != nil {
+		io.WriteString(w, v.field.MoveTo())
+	}
+	return nil
+}
+
+func (v *ModuleView) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	names := keyNames(input)
+
+	switch handler := v.module.(type) {
+	case modules.KeyHandler:
+		names = names[:0]
+		for _, k := range input {
+			handled, err := handler.HandleKey(w, node, k)
+			if err != nil {
+				return "", err
+			}
+			if !handled {
+				names = append(names, k.String())
+			}
+		}
+		if len(names) == 0 {
+			return "", nil
+		}
+
+	case modules.CommandHandler:
+		if v.editor == nil {
+			v.newEditor(handler)
+		}
+		v.editor.SetClientEcho(node.LineEditing())
+
+		handledAny := false
+		for {
+			line, done, rest := v.editor.Feed(w, input)
+			if !done {
+				return redrawIf(handledAny), nil
+			}
+			input = rest
+
+			cmd, args := parseCommand(line)
+			app.Logger.Debug("View Manager: Delegating to module", "module", v.cfg.Module, "cmd", cmd)
+			handled, err := handler.HandleCommand(w, node, cmd, args)
+			if err != nil {
+				return redrawIf(handled || handledAny), err
+			}
+			if !handled {
+				// It can still match an action, otherwise carry on with anything typed after it
+				if target := actionFor(v.cfg, []string{strings.TrimSpace(line)}, node); target != "" {
+					return target, nil
+				}
+			} else {
+				handledAny = true
+			}
+			if len(input) == 0 {
+				return redrawIf(handledAny), nil
+			}
+		}
+
+	case nil:
+	default:
+		app.Logger.Debug("View Manager: Module does not handle input", "module", v.cfg.Module)
+	}
+
+	return actionFor(v.cfg, names, node), nil
+}
+
+// newEditor sets up the line discipline for a command module, using its options if it has any.
+func (v *ModuleView) newEditor(cmdHandler modules.CommandHandler) {
+	opts := lineedit.Options{MaxLength: 255}
+	if le, ok := cmdHandler.(modules.LineEditor); ok {
+		opts = le.LineOptions()
+	}
+	if opts.History == nil {
+		opts.History = v.history
+	}
+	v.editor = lineedit.New(opts)
+	v.editorMasked = opts.Masked
+}
+
+// redrawIf returns Redraw if the view needs drawing again, or an empty string to stay put as it is.
+func redrawIf(redraw bool) string {
+	if redraw {
+		return Redraw
+	}
+	return ""
+}
+
+// parseCommand splits a command line into the command, in lower case, and its arguments.
+func parseCommand(line string) (cmd, args string) {
+	cmd, args, _ = strings.Cut(strings.TrimSpace(line), " ")
+	return strings.ToLower(cmd), strings.TrimSpace(args)
+}
@@ -0,0 +1,80 @@
+package views_test
+
+import (
+	"bytes"
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+	"euphio/internal/views"
+)
+
+// echoModule handles the "say" command, and nothing else.
+type echoModule struct {
+	said []string
+}
+
+func (m *echoModule) Name() string { return "echo" }
+
+func (m *echoModule) HandleCommand(w io.Writer, node *nodes.Node, cmd string, args string) (bool, error) {
+	if cmd != "say" {
+		return false, nil
+	}
+	m.said = append(m.said, args)
+	return true, nil
+}
+
+var _ = Describe("ModuleView", func() {
+	var (
+		view   *views.ModuleView
+		module *echoModule
+		node   *nodes.Node
+		out    bytes.Buffer
+	)
+
+	input := func(s string) string {
+		next, err := view.HandleInput(&out, typed(s), node)
+		Expect(err).NotTo(HaveOccurred())
+		return next
+	}
+
+	BeforeEach(func() {
+		node = &nodes.Node{ID: 1}
+		out.Reset()
+		module = &echoModule{}
+		registry := modules.NewRegistry()
+		registry.Register(module)
+		view = views.NewModuleView(views.Env{Config: viewConfig(`
+type: module
+module: echo
+actions:
+	quit: {view: goodbye}
+`), Modules: registry})
+		Expect(view.Render(&out, node)).To(Succeed())
+	})
+
+	It("passes commands to the module and draws the view again", func() {
+		Expect(input("say hello\r")).To(Equal(views.Redraw))
+		Expect(module.said).To(Equal([]string{"hello"}))
+	})
+
+	It("matches commands the module doesn't handle to actions", func() {
+		Expect(input("quit\r")).To(Equal("goodbye"))
+	})
+
+	It("stays put for a command nobody handles", func() {
+		Expect(input("dance\r")).To(BeEmpty())
+	})
+
+	It("keeps what was typed after a command the module doesn't handle", func() {
+		Expect(input("dance\rsay hi\r")).To(Equal(views.Redraw))
+		Expect(module.said).To(Equal([]string{"hi"}))
+
+		Expect(input("say one\rdance\rsay two")).To(Equal(views.Redraw))
+		Expect(input("\r")).To(Equal(views.Redraw))
+		Expect(module.said).To(Equal([]string{"hi", "one", "two"}))
+	})
+})
@@ -0,0 +1,52 @@
+package views_test
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/store"
+)
+
+func TestViews(t *testing.T) {
+	RegisterFailHandler(Fail)
+
+	app.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	RunSpecs(t, "Views Suite")
+}
+
+var _ = BeforeEach(func() {
+	app.SetConfig(&config.Config{})
+	db, err := store.New(":memory:", true)
+	Expect(err).NotTo(HaveOccurred())
+	app.Store = db
+})
+
+// viewConfig reads a view's config from YAML, as it's written in views.yml.
+func viewConfig(text string) config.View {
+	var cfg config.View
+	Expect(yaml.Unmarshal([]byte(strings.ReplaceAll(text, "\t", "  ")), &cfg)).To(Succeed())
+	return cfg
+}
+
+// typed returns the keys for what the caller types, e.g. "bob\r" or "\x1b[A" for the up arrow.
+func typed(s string) []keys.Key {
+	d := keys.NewDecoder()
+	return append(d.Feed([]byte(s)), d.Flush()...)
+}
+
+// events collects what a view sends to the session.
+type events chan interface{}
+
+func (e events) send(event interface{}) {
+	e <- event
+}
@@ -0,0 +1,86 @@
+package views
+
+import (
+	"io"
+
+	"euphio/internal/ansi"
+	"euphio/internal/config"
+	"euphio/internal/lineedit"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+)
+
+// Env is what a view type's factory is given to create a view.
+type Env struct {
+	ID      string // The view's name in the config
+	Config  config.View
+	Send    func(event interface{}) // Passes an event back to the session
+	Modules *modules.Registry
+	History *lineedit.History // Commands typed this session
+}
+
+// Factory creates a view of a particular type, for a caller entering it.
+type Factory func(env Env) View
+
+// Enterer is an optional interface for views that set something up when the caller enters them. It's called once,
+// before the view is first rendered.
+type Enterer interface {
+	View
+	Enter(w io.Writer, node *nodes.Node) error
+}
+
+// Leaver is an optional interface for views that need to clean up when the caller leaves them, including when the
+// session ends.
+type Leaver interface {
+	View
+	Leave()
+}
+
+// MCIUser is an optional interface for views that draw into their art, at the places marked with MCI codes (see
+// ansi.StripMCI). It's given where they are each time the art is drawn, before Render. Views without art get none.
+type MCIUser interface {
+	View
+	SetMCI(codes ansi.MCICodes)
+}
+
+// TypeRegistry holds the available view types, selected with `type` in the view config.
+type TypeRegistry struct {
+	types map[string]Factory
+}
+
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		types: make(map[string]Factory),
+	}
+}
+
+func (r *TypeRegistry) Register(name string, factory Factory) {
+	r.types[name] = factory
+}
+
+func (r *TypeRegistry) Get(name string) Factory {
+	return r.types[name]
+}
+
+// RegisterBuiltins registers the view types that come with euphio. There's no script type, as euphio doesn't embed a
+// scripting language: anything the built-in types can't do is written in Go, as a module shown with a module view, or
+// as a view type of its own registered with Register.
+func (r *TypeRegistry) RegisterBuiltins() {
+	r.Register("art", func(env Env) View { return NewArtView(env) })
+	r.Register("module", func(env Env) View { return NewModuleView(env) })
+	r.Register("menu", func(env Env) View { return NewMenuView(env) })
+	r.Register("form", func(env Env) View { return NewFormView(env) })
+	r.Register("login", func(env Env) View { return NewLoginView(env) })
+	r.Register("logoff", func(env Env) View { return NewLogoffView(env) })
+}
+
+// viewType returns the type of a view. Views without one are module views if they name a module, and art otherwise.
+func viewType(cfg config.View) string {
+	switch {
+	case cfg.Type != "":
+		return cfg.Type
+	case cfg.Module != "":
+		return "module"
+	}
+	return "art"
+}
@@ -0,0 +1,158 @@
+package views_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+	"euphio/internal/views"
+)
+
+// recorder is a view type that logs what the manager asks of it, and goes wherever the caller types.
+type recorder struct {
+	id  string
+	log *[]string
+}
+
+func (v *recorder) Enter(w io.Writer, node *nodes.Node) error {
+	*v.log = append(*v.log, "enter "+v.id)
+	return nil
+}
+
+func (v *recorder) Leave() {
+	*v.log = append(*v.log, "leave "+v.id)
+}
+
+func (v *recorder) Render(w io.Writer, node *nodes.Node) error {
+	*v.log = append(*v.log, "render "+v.id)
+	return nil
+}
+
+func (v *recorder) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) {
+	return keys.Text(input), nil
+}
+
+var _ = Describe("TypeRegistry", func() {
+	It("returns the factory registered for a type, or nil", func() {
+		types := views.NewTypeRegistry()
+		Expect(types.Get("recorder")).To(BeNil())
+
+		types.Register("recorder", func(env views.Env) views.View { return &recorder{id: env.ID} })
+		Expect(types.Get("recorder")(views.Env{ID: "main"})).To(Equal(&recorder{id: "main"}))
+	})
+
+	It("registers the built-in types", func() {
+		types := views.NewTypeRegistry()
+		types.RegisterBuiltins()
+		for _, name := range []string{"art", "module", "menu", "form", "login", "logoff"} {
+			Expect(types.Get(name)).NotTo(BeNil(), name)
+		}
+		Expect(types.Get("script")).To(BeNil())
+	})
+
+	It("works out the type of views without one", func() {
+		Expect(views.ViewType(viewConfig("{type: menu, module: chat}"))).To(Equal("menu"))
+		Expect(views.ViewType(viewConfig("module: chat"))).To(Equal("module"))
+		Expect(views.ViewType(viewConfig("ansi: welcome"))).To(Equal("art"))
+	})
+})
+
+var _ = Describe("Manager", func() {
+	var (
+		manager *views.Manager
+		node    *nodes.Node
+		log     []string
+		out     bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		app.SetConfig(&config.Config{Views: map[string]config.View{
+			"main":    viewConfig("type: recorder"),
+			"other":   viewConfig("type: recorder"),
+			"unknown": viewConfig("{type: nonsense, actions: {x: main}}"),
+		}})
+		log = nil
+		types := views.NewTypeRegistry()
+		types.Register("recorder", func(env views.Env) views.View { return &recorder{id: env.ID, log: &log} })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		manager = views.NewManager(ctx, modules.NewRegistry(), types, "main", make(events))
+		node = &nodes.Node{ID: 1}
+	})
+
+	render := func() {
+		Expect(manager.RenderCurrent(&out, node)).To(Succeed())
+	}
+
+	input := func(s string) bool {
+		redraw, err := manager.HandleInput(&out, typed(s), node)
+		Expect(err).NotTo(HaveOccurred())
+		return redraw
+	}
+
+	It("creates the view's type on entering it, and draws it each time it's rendered", func() {
+		render()
+		render()
+		Expect(log).To(Equal([]string{"enter main", "render main", "render main"}))
+	})
+
+	It("moves on to the view the current one returns, leaving it", func() {
+		render()
+		Expect(input("other")).To(BeTrue())
+		Expect(manager.Current()).To(Equal("other"))
+		render()
+		Expect(log).To(Equal([]string{"enter main", "render main", "leave main", "enter other", "render other"}))
+	})
+
+	It("goes back to the previous view", func() {
+		render()
+		input("other")
+		render()
+		Expect(input("BACK")).To(BeTrue())
+		Expect(manager.Current()).To(Equal("main"))
+		render()
+		Expect(log).To(HaveExactElements("enter main", "render main", "leave main", "enter other", "render other",
+			"leave other", "enter main", "render main"))
+	})
+
+	It("draws the view again, without entering it again, for a redraw", func() {
+		render()
+		Expect(input("redraw")).To(BeTrue())
+		Expect(manager.Current()).To(Equal("main"))
+		render()
+		Expect(log).To(Equal([]string{"enter main", "render main", "render main"}))
+	})
+
+	It("stays put when the view returns nothing", func() {
+		render()
+		Expect(input("\r")).To(BeFalse())
+		Expect(manager.Current()).To(Equal("main"))
+	})
+
+	It("leaves the current view when the session closes", func() {
+		render()
+		manager.Close()
+		Expect(log).To(HaveExactElements("enter main", "render main", "leave main"))
+	})
+
+	It("treats views of an unknown type as art", func() {
+		manager.Push("unknown")
+		render()
+		Expect(input("x")).To(BeTrue())
+		Expect(manager.Current()).To(Equal("main"))
+	})
+
+	It("fails for a view that isn't in the config", func() {
+		manager.Push("missing")
+		Expect(manager.RenderCurrent(&out, node)).To(MatchError("view not found: missing"))
+	})
+})
@@ -0,0 +1,76 @@
+package views
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"euphio/internal/acs"
+	"euphio/internal/config"
+)
+
+// Validate checks the views in the config for mistakes that would otherwise only show up when a caller reaches them,
+// such as an ACS or form field regex that doesn't parse. Views are checked in name order, and the first mistake found
+// is returned.
+func Validate(cfg *config.Config) error {
+	for _, id := range slices.Sorted(maps.Keys(cfg.Views)) {
+		if reserved(id) {
+			return fmt.Errorf("view %s: the name is reserved, for going back or drawing the view again", id)
+		}
+		if err := validateView(cfg.Views[id]); err != nil {
+			return fmt.Errorf("view %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func validateView(view config.View) error {
+	if err := validateACS(view.ACS); err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(view.Actions)) {
+		if err := validateACS(view.Actions[key].ACS); err != nil {
+			return fmt.Errorf("action %s: %w", key, err)
+		}
+	}
+	for i, next := range view.Next {
+		if err := validateACS(next.ACS); err != nil {
+			return fmt.Errorf("next %d: %w", i+1, err)
+		}
+	}
+	switch viewType(view) {
+	case "menu":
+		items, _ := view.Options["items"].([]interface{})
+		for i, raw := range items {
+			opts, _ := raw.(map[string]interface{})
+			if err := validateACS(optionString(opts, "acs", "")); err != nil {
+				return fmt.Errorf("item %d: %w", i+1, err)
+			}
+		}
+	case "form":
+		fields, _ := view.Options["fields"].([]interface{})
+		for i, raw := range fields {
+			opts, _ := raw.(map[string]interface{})
+			if _, err := regexp.Compile(optionString(opts, "regex", "")); err != nil {
+				return fmt.Errorf("field %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reserved returns true for view names that views and actions use as special targets (see Back and Redraw).
+func reserved(id string) bool {
+	return strings.EqualFold(id, Back) || strings.EqualFold(id, Redraw)
+}
+
+// validateACS checks an ACS parses. An empty ACS always passes, so is fine.
+func validateACS(expr string) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	_, err := acs.Parse(expr)
+	return err
+}
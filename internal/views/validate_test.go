@@ -0,0 +1,51 @@
+package views_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/config"
+	"euphio/internal/views"
+)
+
+var _ = Describe("Validate", func() {
+	validate := func(text string) error {
+		return views.Validate(&config.Config{Views: map[string]config.View{"main": viewConfig(text)}})
+	}
+
+	It("accepts valid ACS, and views without any", func() {
+		Expect(validate(`
+acs: LI & SL20
+type: menu
+options:
+	items:
+	- {text: Sysop, view: sysop, acs: SL90}
+	- {text: Chat, view: chat}
+actions:
+	S: {view: sysop, acs: 'SL90 | GM[sysops]'}
+	G: goodbye
+next:
+- {view: twoFactor, acs: AR2}
+- welcome
+`)).To(Succeed())
+		Expect(views.Validate(&config.Config{})).To(Succeed())
+	})
+
+	It("names where an invalid ACS is", func() {
+		Expect(validate("acs: SL")).To(MatchError(HavePrefix("view main: ")))
+		Expect(validate("actions: {S: {view: sysop, acs: ZZ9}}")).To(MatchError(HavePrefix("view main: action S: ")))
+		Expect(validate("next: [{view: welcome, acs: LI}, {view: demo, acs: 'GM[demo'}]")).
+			To(MatchError(HavePrefix("view main: next 2: ")))
+		Expect(validate("{type: menu, options: {items: [{text: Chat, acs: LI}, {text: Sysop, acs: 'SL('}]}}")).
+			To(MatchError(HavePrefix("view main: item 2: ")))
+		Expect(validate("{type: form, options: {fields: [{name: username, regex: '^[a-z]+$'}, {name: code, regex: '[A-Z'}]}}")).
+			To(MatchError(HavePrefix("view main: field 2: error parsing regexp")))
+	})
+
+	It("refuses view names kept for special targets", func() {
+		for _, id := range []string{"back", "Redraw"} {
+			cfg := &config.Config{Views: map[string]config.View{id: {}}}
+			Expect(views.Validate(cfg)).To(MatchError(HavePrefix("view " + id + ": the name is reserved")))
+		}
+	})
+})
@@ -1,41 +1,66 @@
 package views
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
+	"euphio/internal/acs"
 	"euphio/internal/ansi"
 	"euphio/internal/app"
 	"euphio/internal/config"
+	"euphio/internal/keys"
+	"euphio/internal/lineedit"
 	"euphio/internal/modules"
 	"euphio/internal/nodes"
 	"euphio/internal/prompts"
 )
 
-// View represents a screen or state in the BBS.
+// View represents a screen or state in the BBS. Each view type (see TypeRegistry) has its own implementation, created
+// when the caller enters a view of that type. The manager draws the view's art first, and its prompt after.
 type View interface {
 	Render(w io.Writer, node *nodes.Node) error
-	HandleInput(input string, node *nodes.Node) (string, error) // Returns next view ID or empty
+	HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (string, error) // Returns next view ID or empty
 }
 
-// Manager handles the navigation stack and current view.
+// Targets with a special meaning, for View.HandleInput to return and actions to go to. They're matched ignoring case,
+// and can't be used as view names.
+const (
+	Back   = "back"   // Return to the previous view
+	Redraw = "redraw" // Stay on the view, drawing it again
+)
+
+// Manager handles the navigation stack and current view. Views are looked up in app.Config() as they're entered, so
+// changes made by reloading the config reach callers already online.
 type Manager struct {
-	config        map[string]config.View
 	registry      *modules.Registry
+	types         *TypeRegistry
 	stack         []string
 	current       string
+	ctx           context.Context  // Cancelled when the session ends
 	events        chan interface{} // Channel to send events back to the session
+	nextTimer     *time.Timer      // Moves on to the next view after its delay
 	currentPrompt prompts.Prompt
+	currentView   View              // Implementation of the current view's type, created on entering it
+	history       *lineedit.History // Commands typed this session
 }
 
-func NewManager(viewConfig map[string]config.View, registry *modules.Registry, initialView string, events chan interface{}) *Manager {
+// Commands kept for recall with the up arrow
+const historySize = 50
+
+func NewManager(ctx context.Context, registry *modules.Registry, types *TypeRegistry, initialView string, events chan interface{}) *Manager {
 	return &Manager{
-		config:   viewConfig,
+		ctx:      ctx,
 		registry: registry,
+		types:    types,
 		stack:    []string{},
 		current:  initialView,
 		events:   events,
+		history:  lineedit.NewHistory(historySize),
 	}
 }
 
@@ -49,7 +74,7 @@ func (m *Manager) Push(viewID string) {
 		m.stack = append(m.stack, m.current)
 	}
 	m.current = viewID
-	m.currentPrompt = nil // Reset prompt on view change
+	m.leave()
 }
 
 func (m *Manager) Pop() string {
@@ -61,18 +86,59 @@ func (m *Manager) Pop() string {
 	m.stack = m.stack[:len(m.stack)-1]
 	app.Logger.Debug("View Manager: Pop", "view", prev, "from", m.current)
 	m.current = prev
-	m.currentPrompt = nil // Reset prompt on view change
+	m.leave()
 	return prev
 }
 
+// leave resets the state belonging to the view being left.
+func (m *Manager) leave() {
+	m.stopNext()
+	if leaver, ok := m.currentView.(Leaver); ok {
+		leaver.Leave()
+	}
+	m.currentPrompt = nil
+	m.currentView = nil
+}
+
+// Close stops anything still pending for the current view, once the session has ended.
+func (m *Manager) Close() {
+	m.leave()
+}
+
+func (m *Manager) stopNext() {
+	if m.nextTimer != nil {
+		m.nextTimer.Stop()
+		m.nextTimer = nil
+	}
+}
+
+// send passes an event back to the session, giving up if the session has ended.
+func (m *Manager) send(event interface{}) {
+	select {
+	case m.events <- event:
+	case <-m.ctx.Done():
+	}
+}
+
 // RenderCurrent renders the current view to the writer.
 func (m *Manager) RenderCurrent(w io.Writer, node *nodes.Node) error {
 	app.Logger.Debug("View Manager: RenderCurrent", "view", m.current, "stack", m.stack)
-	viewConfig, ok := m.config[m.current]
+	viewConfig, ok := app.Config().Views[m.current]
 	if !ok {
 		return fmt.Errorf("view not found: %s", m.current)
 	}
 
+	// Guard the view itself, sending the caller back to where they came from if they aren't allowed in
+	if !allowed(viewConfig.ACS, node) {
+		app.Logger.Info("View Manager: Access denied", "view", m.current, "node", node.ID)
+		denied := m.current
+		if m.Pop() == "" {
+			return fmt.Errorf("access denied: %s", denied)
+		}
+		w.Write([]byte("\r\nAccess denied.\r\n"))
+		return m.RenderCurrent(w, node)
+	}
+
 	// Handle screen clearing
 	if viewConfig.ClearScreen {
 		w.Write([]byte(ansi.ClearScreen))
@@ -85,27 +151,38 @@ func (m *Manager) RenderCurrent(w io.Writer, node *nodes.Node) error {
 		w.Write([]byte(ansi.ShowCursor))
 	}
 
-	// For now, we only support a simple "art" view type implicitly
-	// In the future, we can use viewConfig.Type to instantiate different View implementations.
-
-	isUTF8 := false
-	if node.Conn != nil {
-		isUTF8 = node.Conn.IsUTF8()
+	// Create the implementation of the view's type on entering it
+	if m.currentView == nil {
+		if err := m.enter(w, viewConfig, node); err != nil {
+			return err
+		}
 	}
 
+	isUTF8 := node.IsUTF8()
+
+	var codes ansi.MCICodes
 	if viewConfig.Ansi != "" {
 		// Load and display art using the new ansi.RenderArt utility
-		if err := ansi.RenderArt(w, viewConfig.Ansi, isUTF8); err != nil {
+		var err error
+		if codes, err = ansi.RenderArt(w, viewConfig.Ansi, isUTF8, ansi.CallerVars(node)); err != nil {
 			return err
 		}
 	}
+	if err := drawLabels(w, viewConfig, codes, node); err != nil {
+		return err
+	}
+	if user, ok := m.currentView.(MCIUser); ok {
+		user.SetMCI(codes)
+	}
+
+	if err := m.currentView.Render(w, node); err != nil {
+		return err
+	}
 
 	// Handle Prompt
 	if viewConfig.Prompt != "" {
-		if promptCfg, ok := app.Config.Prompts[viewConfig.Prompt]; ok {
-			// Instantiate the prompt
-			// For now, we only have BasicPrompt. Later we can use promptCfg.Type
-			m.currentPrompt = prompts.NewBasic(promptCfg)
+		if promptCfg, ok := app.Config().Prompts[viewConfig.Prompt]; ok {
+			m.currentPrompt = prompts.New(promptCfg)
 			if err := m.currentPrompt.Render(w, node); err != nil {
 				return err
 			}
@@ -115,15 +192,14 @@ func (m *Manager) RenderCurrent(w io.Writer, node *nodes.Node) error {
 	// Handle automatic transition ("next")
 	// Only trigger if Delay is greater than 0.
 	// If Delay is 0 (default), it implies "wait for key press" which is handled in HandleInput.
-	if viewConfig.Next != nil && viewConfig.Next.Delay > 0 {
-		app.Logger.Debug("View Manager: Auto-next configured", "next", viewConfig.Next.View, "delay", viewConfig.Next.Delay)
-		go func(nextView string, delay int) {
-			time.Sleep(time.Duration(delay) * time.Millisecond)
-			// Send event to session loop instead of modifying state directly
-			if m.events != nil {
-				m.events <- ChangeViewEvent{ViewID: nextView}
-			}
-		}(viewConfig.Next.View, viewConfig.Next.Delay)
+	if next := nextView(viewConfig, node); next != nil && next.Delay > 0 {
+		app.Logger.Debug("View Manager: Auto-next configured", "next", next.View, "delay", next.Delay)
+		// Send event to session loop instead of modifying state directly. Rendering again restarts the delay.
+		m.stopNext()
+		event := ChangeViewEvent{ViewID: next.View, From: m.current}
+		m.nextTimer = time.AfterFunc(time.Duration(next.Delay)*time.Millisecond, func() {
+			m.send(event)
+		})
 	}
 
 	return nil
@@ -131,84 +207,181 @@ func (m *Manager) RenderCurrent(w io.Writer, node *nodes.Node) error {
 
 // HandleInput processes input for the current view.
 // Returns true if the input was handled (consumed), false otherwise.
-func (m *Manager) HandleInput(w io.Writer, input string, node *nodes.Node) (bool, error) {
+func (m *Manager) HandleInput(w io.Writer, input []keys.Key, node *nodes.Node) (bool, error) {
 	app.Logger.Debug("View Manager: HandleInput", "input", input, "current", m.current)
-	viewConfig, ok := m.config[m.current]
+	viewConfig, ok := app.Config().Views[m.current]
 	if !ok {
 		return false, fmt.Errorf("view not found: %s", m.current)
 	}
 
-	// 0. Check if there is an active prompt
+	// A prompt takes input first, moving on to the next view once it's done. Prompts draw their own output as input
+	// arrives, so the view is only drawn again if it moves on.
 	if m.currentPrompt != nil {
-		handled, done, err := m.currentPrompt.HandleInput(input, node)
+		handled, done, err := m.currentPrompt.HandleInput(w, input, node)
 		if err != nil {
-			return handled, err
+			return false, err
 		}
 		if handled {
-			if done {
-				// Prompt is done, move to next view if configured
-				if viewConfig.Next != nil {
-					app.Logger.Debug("View Manager: Prompt done, moving next", "next", viewConfig.Next.View)
-					m.Push(viewConfig.Next.View)
-					return true, nil
-				}
-				// If no next view, we just stay here
+			if !done {
+				return false, nil
 			}
-			return true, nil
+			m.currentPrompt = nil
+			if next := nextView(viewConfig, node); next != nil {
+				app.Logger.Debug("View Manager: Prompt done, moving next", "next", next.View)
+				return m.moveTo(next.View), nil
+			}
+			// If no next view, we just stay here
+			return false, nil
 		}
 	}
 
-	// 1. Check if the view uses a module
-	if viewConfig.Module != "" {
-		if mod := m.registry.Get(viewConfig.Module); mod != nil {
-			// Check if the module implements CommandHandler
-			if cmdHandler, ok := mod.(modules.CommandHandler); ok {
-				app.Logger.Debug("View Manager: Delegating to module", "module", viewConfig.Module)
-				handled, err := cmdHandler.HandleCommand(w, node, input, "") // TODO: Parse args properly
-				if err != nil {
-					return handled, err
-				}
-				if handled {
-					return true, nil
-				}
-			} else {
-				app.Logger.Debug("View Manager: Module does not handle commands", "module", viewConfig.Module)
-			}
-		} else {
-			app.Logger.Warn("View Manager: Module not found", "module", viewConfig.Module)
+	// Otherwise it's up to the view. Views draw their own output as input arrives, so they're only drawn again if they
+	// move on or ask for it.
+	if m.currentView == nil {
+		return false, nil
+	}
+	target, err := m.currentView.HandleInput(w, input, node)
+	return m.moveTo(target), err
+}
+
+// enter creates the implementation of the current view's type, falling back on art for unknown types.
+func (m *Manager) enter(w io.Writer, viewConfig config.View, node *nodes.Node) error {
+	env := Env{
+		ID:      m.current,
+		Config:  viewConfig,
+		Send:    m.send,
+		Modules: m.registry,
+		History: m.history,
+	}
+	typ := viewType(viewConfig)
+	factory := m.types.Get(typ)
+	if factory == nil {
+		app.Logger.Warn("View Manager: Unknown view type", "view", m.current, "type", typ)
+		factory = func(env Env) View { return NewArtView(env) }
+	}
+
+	// Only module views let the caller's client edit lines
+	node.SetLineEditing(false)
+
+	m.currentView = factory(env)
+	if enterer, ok := m.currentView.(Enterer); ok {
+		return enterer.Enter(w, node)
+	}
+	return nil
+}
+
+// moveTo goes to the target returned by a view or action, returning true if the current view needs rendering.
+func (m *Manager) moveTo(target string) bool {
+	switch {
+	case target == "":
+		return false
+	case strings.EqualFold(target, Redraw):
+	case strings.EqualFold(target, Back):
+		m.Pop()
+	default:
+		app.Logger.Debug("View Manager: Moving on", "next", target, "from", m.current)
+		m.Push(target)
+	}
+	return true
+}
+
+// matchAction returns where the first of the keys or lines named with a matching action takes the caller, if they
+// have access to it, or an empty string.
+func matchAction(viewConfig config.View, names []string, node *nodes.Node) string {
+	for _, name := range names {
+		if action, ok := viewConfig.Actions[name]; ok && allowed(action.ACS, node) {
+			app.Logger.Debug("View Manager: Action matched", "input", name, "next", action.View)
+			return action.View
 		}
 	}
+	return ""
+}
 
-	// 2. Check for explicit action mapping
-	if nextView, ok := viewConfig.Actions[input]; ok {
-		app.Logger.Debug("View Manager: Action matched", "input", input, "next", nextView)
-		if nextView == "back" || nextView == "BACK" {
-			m.Pop()
-		} else {
-			m.Push(nextView)
+// drawLabels draws the view's text labels where the art has their MCI codes. Labels are templates, like art, and can
+// have pipe colour codes.
+func drawLabels(w io.Writer, viewConfig config.View, codes ansi.MCICodes, node *nodes.Node) error {
+	for _, code := range slices.Sorted(maps.Keys(viewConfig.Labels)) {
+		pos, ok := codes[code]
+		if !ok {
+			app.Logger.Debug("View Manager: Label not in art", "code", code)
+			continue
+		}
+		text, err := ansi.RenderTemplate([]byte(viewConfig.Labels[code]), ansi.CallerVars(node))
+		if err != nil {
+			return fmt.Errorf("failed to render label %s: %w", code, err)
 		}
-		return true, nil
+		io.WriteString(w, pos.MoveTo())
+		io.WriteString(w, ansi.PipeColors(string(text)))
+		io.WriteString(w, ansi.ResetSeq)
+	}
+	return nil
+}
+
+// keyNames names the keys pressed, for matching actions (see keys.Key.String).
+func keyNames(input []keys.Key) []string {
+	names := make([]string, len(input))
+	for i, k := range input {
+		names[i] = k.String()
+	}
+	return names
+}
+
+// actionFor returns where the keys or line named take the caller: the first matching action they have access to, or
+// the next view if one follows any key press. Returns an empty string to stay put.
+func actionFor(viewConfig config.View, names []string, node *nodes.Node) string {
+	if target := matchAction(viewConfig, names, node); target != "" {
+		return target
 	}
 
-	// 3. Check for "Press any key" behavior (Next without delay)
-	// Only if NO prompt is active (prompts handle their own input)
-	if m.currentPrompt == nil && viewConfig.Next != nil && viewConfig.Next.Delay == 0 {
-		// If there's a next view configured without a delay (or explicit 0),
-		// treat any input as a trigger to move next.
-		app.Logger.Debug("View Manager: Next triggered by input", "next", viewConfig.Next.View)
-		m.Push(viewConfig.Next.View)
-		return true, nil
+	// "Press any key" behaviour, for a next view without a delay
+	if next := nextView(viewConfig, node); next != nil && next.Delay == 0 && len(names) > 0 {
+		app.Logger.Debug("View Manager: Next triggered by input", "next", next.View)
+		return next.View
 	}
 
 	app.Logger.Debug("View Manager: No action matched")
-	return false, nil
+	return ""
+}
+
+// nextView returns the first of the view's next branches that the node has access to, or nil if there are none.
+func nextView(viewConfig config.View, node *nodes.Node) *config.NextView {
+	for i, next := range viewConfig.Next {
+		if allowed(next.ACS, node) {
+			return &viewConfig.Next[i]
+		}
+	}
+	return nil
+}
+
+// allowed checks an ACS expression against the node. Invalid expressions are logged and deny access.
+func allowed(expr string, node *nodes.Node) bool {
+	ok, err := acs.Check(expr, acs.ContextFor(node))
+	if err != nil {
+		app.Logger.Error("Invalid ACS", "acs", expr, "err", err)
+		return false
+	}
+	return ok
 }
 
 // Events
+
+// ChangeViewEvent moves the caller on to another view. From is the view that sent it, and the event is ignored if the
+// caller has left it since, e.g. when a delayed next fires just as they go elsewhere.
 type ChangeViewEvent struct {
 	ViewID string
+	From   string
 }
 
+// InputEvent is raw input from the caller, which the session decodes into keys.
 type InputEvent struct {
 	Input string
 }
+
+// DisconnectEvent asks the session to hang up on the caller.
+type DisconnectEvent struct{}
+
+// GMCPEvent is a GMCP message from the caller's client. Data is the raw JSON, and may be empty.
+type GMCPEvent struct {
+	Package string
+	Data    []byte
+}
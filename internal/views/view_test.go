@@ -0,0 +1,113 @@
+package views_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"euphio/internal/ansi"
+	"euphio/internal/app"
+	"euphio/internal/config"
+	"euphio/internal/modules"
+	"euphio/internal/nodes"
+	"euphio/internal/store"
+	"euphio/internal/views"
+)
+
+var _ = Describe("Access", func() {
+	var node *nodes.Node
+
+	BeforeEach(func() {
+		node = &nodes.Node{ID: 1, User: &store.User{SecurityLevel: 20, AuthFactor: 1}}
+	})
+
+	Describe("allowed", func() {
+		It("allows anyone through an empty ACS", func() {
+			Expect(views.Allowed("", &nodes.Node{ID: 1})).To(BeTrue())
+		})
+
+		It("checks the ACS against the node", func() {
+			Expect(views.Allowed("LI & SL20", node)).To(BeTrue())
+			Expect(views.Allowed("SL90", node)).To(BeFalse())
+			Expect(views.Allowed("AR2", node)).To(BeFalse())
+		})
+
+		It("denies access for an ACS that doesn't parse", func() {
+			Expect(views.Allowed("SL(", node)).To(BeFalse())
+		})
+	})
+
+	Describe("nextView", func() {
+		view := viewConfig(`
+next:
+- view: authSshSecondFactor
+	acs: AR2
+- view: sysopWelcome
+	acs: SL90
+- view: authWelcome
+	delay: 500
+`)
+
+		It("takes the first branch the node has access to", func() {
+			next := views.NextView(view, node)
+			Expect(next).NotTo(BeNil())
+			Expect(next.View).To(Equal("authWelcome"))
+			Expect(next.Delay).To(Equal(500))
+
+			node.User.AuthFactor = 2
+			Expect(views.NextView(view, node).View).To(Equal("authSshSecondFactor"))
+		})
+
+		It("returns nil when no branch is open to the node", func() {
+			Expect(views.NextView(viewConfig("next: {view: sysopWelcome, acs: SL90}"), node)).To(BeNil())
+			Expect(views.NextView(config.View{}, node)).To(BeNil())
+		})
+	})
+})
+
+var _ = Describe("Art with MCI codes", func() {
+	var (
+		manager *views.Manager
+		node    *nodes.Node
+		out     bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		art := "\x1b[2J\x1b[H|11Welcome\r\n  %TL1\r\n\x1b[10;5H> %ET1"
+		Expect(os.WriteFile(filepath.Join(dir, "main.asc"), []byte(art), 0o644)).To(Succeed())
+
+		app.SetConfig(&config.Config{
+			Paths: config.PathsConfig{Ansi: dir},
+			Views: map[string]config.View{"main": viewConfig(`
+ansi: main
+module: echo
+labels:
+	TL1: "|14{{ .Custom.Username }}"
+	TL2: not in the art
+`)},
+		})
+		registry := modules.NewRegistry()
+		registry.Register(&echoModule{})
+		types := views.NewTypeRegistry()
+		types.RegisterBuiltins()
+		manager = views.NewManager(context.Background(), registry, types, "main", make(events))
+		node = &nodes.Node{ID: 1, User: &store.User{Username: "bob"}}
+		out.Reset()
+		Expect(manager.RenderCurrent(&out, node)).To(Succeed())
+	})
+
+	It("draws labels where the art has their codes, with pipe colours", func() {
+		Expect(out.String()).To(ContainSubstring("\x1b[1;36mWelcome\r\n      \r\n"))
+		Expect(out.String()).To(ContainSubstring("\x1b[2;3H\x1b[1;33mbob" + ansi.ResetSeq))
+		Expect(out.String()).NotTo(ContainSubstring("not in the art"))
+	})
+
+	It("puts a module's command line where the art has %ET1", func() {
+		Expect(out.String()).To(HaveSuffix("\x1b[10;7H"))
+	})
+})